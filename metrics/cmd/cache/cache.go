@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/cache"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/factory"
+	"github.com/spf13/cobra"
+)
+
+type Factory interface {
+	factory.GenericFactory
+	factory.CacheFactory
+}
+
+type cacheConfig struct {
+	logger   *slog.Logger
+	exporter export.Exporter
+	store    *cache.Store
+}
+
+func NewCacheCmd(f Factory) *cobra.Command {
+	config := new(cacheConfig)
+
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and prune the cache for API responses",
+		Long:  "Inspect and prune the on-disk cache for GitHub and Grafana API responses",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := f.Logger()
+			if err != nil {
+				return fmt.Errorf("error retrieving logger: %w", err)
+			}
+			config.logger = logger
+
+			exporter, err := f.Exporter()
+			if err != nil {
+				return fmt.Errorf("error retrieving exporter: %w", err)
+			}
+			config.exporter = exporter
+
+			store, err := f.Cache()
+			if err != nil {
+				return fmt.Errorf("error retrieving cache: %w. Set env vars or pass --cache-dir", err)
+			}
+			config.store = store
+
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newStatsCmd(config))
+	cmd.AddCommand(newPruneCmd(config))
+
+	return cmd
+}
+
+func newStatsCmd(config *cacheConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show the number and size of cached API responses",
+		Long:  "Show the number and size of cached API responses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.logger.Debug("cmd.runCacheStats", slog.String("dir", config.store.Dir()))
+
+			stats, err := config.store.Stats()
+			if err != nil {
+				return fmt.Errorf("error reading cache stats: %w", err)
+			}
+
+			return config.exporter.Export(stats)
+		},
+	}
+}
+
+func newPruneCmd(config *cacheConfig) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove expired API responses from the cache",
+		Long:  "Remove expired API responses from the cache. Responses which never expire are only removed with --all",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config.logger.Debug("cmd.runCachePrune", slog.String("dir", config.store.Dir()), slog.Bool("all", all))
+
+			result, err := config.store.Prune(all)
+			if err != nil {
+				return fmt.Errorf("error pruning cache: %w", err)
+			}
+
+			return config.exporter.Export(result)
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "remove all cached API responses")
+
+	return cmd
+}
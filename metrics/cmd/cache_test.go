@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/cache"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestCache(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+		config.EnvKey("CACHE", "DIR"):         "",
+	}
+
+	cacheDir := t.TempDir()
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:        "cache__not_configured",
+		Args:        []string{"cache", "stats"},
+		ErrContains: "error retrieving cache: cache not configured",
+		Env:         env,
+	}, {
+		Name:        "cache__ttl__invalid",
+		Args:        []string{"cache", "stats", "--cache-dir", cacheDir, "--cache-ttl", "0s"},
+		ErrContains: "cache TTL must be greater than 0",
+		Env:         env,
+	}, {
+		Name:        "cache__prs__miss",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--cache-dir", cacheDir, "--debug"},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantLog:     `msg="cache: miss"`,
+		Env:         env,
+	}, {
+		Name:        "cache__prs__hit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--cache-dir", cacheDir, "--debug"},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantLog:     `msg="cache: hit"`,
+		Env:         env,
+	}, {
+		Name:        "cache__prs__env",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--debug"},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantLog:     `msg="cache: hit"`,
+		Env: map[string]string{
+			config.EnvKey("GITHUB", "REPO_OWNER"): "",
+			config.EnvKey("GITHUB", "REPO_NAME"):  "",
+			config.EnvKey("CACHE", "DIR"):         cacheDir,
+		},
+	}})
+
+	store, err := cache.NewStore(cacheDir, time.Hour)
+	if err != nil {
+		t.Fatalf("error opening cache: %v", err)
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("error reading cache stats: %v", err)
+	}
+
+	// One entry for each page of pull requests
+	if stats.Entries != 2 {
+		t.Fatalf("cache has %d entries, want 2", stats.Entries)
+	}
+
+	// Files which are not cache entries are never removed by prune.
+	foreign := []string{
+		filepath.Join(cacheDir, "prs.json"),
+		filepath.Join(cacheDir, "sub", "config.json"),
+	}
+	for _, path := range foreign {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`[{"Number": 1}]`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:     "cache__stats",
+		Args:     []string{"cache", "stats", "--cache-dir", cacheDir, "-e", "csv"},
+		WantText: fmt.Sprintf("dir,entries,immutableEntries,expiredEntries,bytes\n%s,2,0,0,%d", cacheDir, stats.Bytes),
+		Env:      env,
+	}, {
+		Name:     "cache__prune",
+		Args:     []string{"cache", "prune", "--cache-dir", cacheDir, "-e", "csv"},
+		WantText: fmt.Sprintf("dir,removed,bytes\n%s,0,0", cacheDir),
+		Env:      env,
+	}, {
+		Name:     "cache__prune__all",
+		Args:     []string{"cache", "prune", "--all", "--cache-dir", cacheDir, "-e", "csv"},
+		WantText: fmt.Sprintf("dir,removed,bytes\n%s,2,%d", cacheDir, stats.Bytes),
+		Env:      env,
+	}})
+
+	for _, path := range foreign {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("cache prune removed file which is not a cache entry: %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestChangeFailures(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "change-failures__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures", "-l", "0"},
+		ErrContains: "limit cannot be smaller than 1",
+		Env:         env,
+	}, {
+		Name:        "change-failures__hotfix_pattern__invalid",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures", "--hotfix-pattern", "hotfix("},
+		ErrContains: `invalid hotfix pattern "hotfix("`,
+		Env:         env,
+	}, {
+		Name:        "change-failures__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures"},
+		WantFixture: test.NewFixture("github", "change-failures", "want__default.json"),
+		Env:         env,
+	}, {
+		Name:        "change-failures__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "change-failures", "want__default.csv"),
+		Env:         env,
+	}, {
+		Name:        "change-failures__summary__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures", "--summary", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "change-failures", "want__summary.csv"),
+		Env:         env,
+	}, {
+		Name:        "change-failures__hotfix_pattern__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "change-failures", "--hotfix-pattern", "4444$", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "change-failures", "want__hotfix_pattern.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
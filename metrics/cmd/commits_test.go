@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestCompare(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "compare__refs__required",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "compare", "--base", "v1.0.0"},
+		ErrContains: "git Ref Base and Head are required",
+		Env:         env,
+	}, {
+		Name:        "compare__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "compare", "--base", "v1.0.0", "--head", "v1.1.0"},
+		WantFixture: test.NewFixture("github", "compare-refs", "want__default.json"),
+		Env:         env,
+	}, {
+		Name:        "compare__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "compare", "--base", "v1.0.0", "--head", "v1.1.0", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "compare-refs", "want__default.csv"),
+		Env:         env,
+	}, {
+		// The total number of commits is reported even if the limit is lower.
+		Name:        "compare__limit__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "compare", "--base", "v1.0.0", "--head", "v1.1.0", "-l", "2", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "compare-refs", "want__limit.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
+
+func TestHistory(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "history__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "history", "--base", "bbb2222bbbbbbbbbbb", "--head", "eee5555eeeeeeeeeee", "-l", "2"},
+		ErrContains: "reached limit of 2 without finding bbb2222bbbbbbbbbbb",
+		Env:         env,
+	}, {
+		Name:        "history__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "history", "--base", "bbb2222bbbbbbbbbbb", "--head", "eee5555eeeeeeeeeee"},
+		WantFixture: test.NewFixture("github", "history", "want__default.json"),
+		Env:         env,
+	}, {
+		// Only the first line of commit messages is exported and merge
+		// commits have more than one parent.
+		Name:        "history__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "history", "--base", "bbb2222bbbbbbbbbbb", "--head", "eee5555eeeeeeeeeee", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "history", "want__default.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
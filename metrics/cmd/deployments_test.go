@@ -35,6 +35,12 @@ func TestDeployments(t *testing.T) {
 		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments"},
 		WantFixture: test.NewFixture("github", "deployments", "want__default.json"),
 		Env:         env,
+	}, {
+		Name:        "deployments__statuses__truncated",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments"},
+		WantFixture: test.NewFixture("github", "deployments", "want__default.json"),
+		WantLog:     "level=WARN msg=\"graphql.StreamDeployments: deployment has more statuses than were fetched, duration and failed attempts may be wrong\" deployment.sha=3abc111ccccccccccc deployment.createdAt=2022-02-01T20:25:05.000Z deployment.env=hello statuses=25 fetched=2",
+		Env:         env,
 	}, {
 		Name:        "deployments__limit",
 		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "-l", "2"},
@@ -81,6 +87,48 @@ func TestDeployments(t *testing.T) {
 		Args:    []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--env", "prod", "--debug"},
 		WantLog: "level=DEBUG msg=cmd.runDeployments github.DeploymentsService=*graphql.API config.repo=hackebrot/turtle config.envs=[prod] config.limit=10",
 		Env:     env,
+	}, {
+		Name:        "deployments__since",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--since", "2022-04-15", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "deployments", "want__since.csv"),
+		Env:         env,
+	}, {
+		// A date includes the entire day, so the deployment on the evening of
+		// that day is part of the window.
+		Name:        "deployments__until",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--until", "2022-05-01", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "deployments", "want__until.csv"),
+		Env:         env,
+	}, {
+		Name:        "deployments__until__before__since",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--since", "now-30d", "--until", "now-90d"},
+		ErrContains: "--until cannot be before --since",
+		Env:         env,
+	}, {
+		Name:        "deployments__prs__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "--prs"},
+		WantFixture: test.NewFixture("github", "deployments", "want__prs.json"),
+		Env:         env,
+	}, {
+		Name:        "deployments__prs__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "--prs", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "deployments", "want__prs.csv"),
+		Env:         env,
+	}, {
+		Name:        "deployments__prs__requires__commits",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--prs"},
+		ErrContains: "--prs requires --commits",
+		Env:         env,
+	}, {
+		Name:        "deployments__concurrency__requires__commits",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--concurrency", "8"},
+		ErrContains: "--concurrency requires --commits",
+		Env:         env,
+	}, {
+		Name:        "deployments__concurrency__invalid",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "--concurrency", "0"},
+		ErrContains: "concurrency cannot be smaller than 1",
+		Env:         env,
 	}}
 
 	test.RunTests(t, NewRootCmd, tests)
@@ -0,0 +1,166 @@
+package dora
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/datetime"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/factory"
+	"github.com/mozilla-services/rapid-release-model/pkg/dora"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type Factory interface {
+	factory.GenericFactory
+	factory.GitHubFactory
+}
+
+type doraOptions struct {
+	Since  string
+	Until  string
+	Period string
+}
+
+type doraConfig struct {
+	logger      *slog.Logger
+	exporter    export.Exporter
+	repo        *github.Repo
+	environment string
+	limit       int
+	commitLimit int
+	concurrency int
+	metrics     *dora.Options
+}
+
+func NewDoraCmd(f Factory) *cobra.Command {
+	opts := new(doraOptions)
+	config := &doraConfig{repo: f.DefaultGitHubRepo()}
+
+	cmd := &cobra.Command{
+		Use:   "dora",
+		Short: "Compute DORA metrics from GitHub Deployments",
+		Long:  "Compute deployment frequency, lead time for changes, change failure rate and time to restore service from GitHub Deployments",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := f.Logger()
+			if err != nil {
+				return fmt.Errorf("error retrieving logger: %w", err)
+			}
+			config.logger = logger
+
+			exporter, err := f.Exporter()
+			if err != nil {
+				return fmt.Errorf("error retrieving exporter: %w", err)
+			}
+			config.exporter = exporter
+
+			if config.repo.Owner == "" || config.repo.Name == "" {
+				return fmt.Errorf("repo.Owner and repo.Name are required. Set env vars or pass flags")
+			}
+			f.ConfigureGitHubRepo(config.repo.Owner, config.repo.Name)
+
+			if config.limit < 1 {
+				return fmt.Errorf("limit cannot be smaller than 1")
+			}
+
+			if config.commitLimit < 1 {
+				return fmt.Errorf("commit-limit cannot be smaller than 1")
+			}
+
+			if config.concurrency < 1 {
+				return fmt.Errorf("concurrency cannot be smaller than 1")
+			}
+
+			now := time.Now()
+			metrics := &dora.Options{Period: dora.Period(opts.Period)}
+
+			if metrics.Since, err = datetime.Parse(opts.Since, now); err != nil {
+				return fmt.Errorf("error parsing --since: %w", err)
+			}
+
+			if metrics.Until, err = datetime.Parse(opts.Until, now); err != nil {
+				return fmt.Errorf("error parsing --until: %w", err)
+			}
+
+			if _, err := metrics.Period.Start(metrics.Since); err != nil {
+				return err
+			}
+			config.metrics = metrics
+
+			if err := f.ConfigureGitHubHTTPClient(); err != nil {
+				return fmt.Errorf("error initializing GitHub HTTP client: %w", err)
+			}
+
+			if err := f.ConfigureGitHubRESTAPI(); err != nil {
+				return fmt.Errorf("error initializing GitHub REST API: %w", err)
+			}
+
+			if err := f.ConfigureGitHubGraphQLAPI(); err != nil {
+				return fmt.Errorf("error initializing GitHub GraphQL API: %w", err)
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			graphqlAPI, err := f.GitHubGraphQLAPI()
+			if err != nil {
+				return fmt.Errorf("error retrieving GitHub GraphQL API: %w", err)
+			}
+
+			restAPI, err := f.GitHubRestAPI()
+			if err != nil {
+				return fmt.Errorf("error retrieving GitHub REST API: %w", err)
+			}
+
+			return runDora(ctx, graphqlAPI, restAPI, config)
+		},
+	}
+
+	cmd.Flags().StringVarP(&config.repo.Owner, "repo-owner", "o", config.repo.Owner, "owner of the GitHub repo")
+	cmd.Flags().StringVarP(&config.repo.Name, "repo-name", "n", config.repo.Name, "name of the GitHub repo")
+	cmd.Flags().StringVar(&config.environment, "env", "production", "deployment environment")
+	cmd.Flags().StringVar(&opts.Since, "since", "now-90d", "start of the time window (e.g. now-90d, 2024-01-01)")
+	cmd.Flags().StringVar(&opts.Until, "until", "now", "end of the time window (e.g. now, 2024-03-31)")
+	cmd.Flags().StringVar(&opts.Period, "period", "week", "period for the metrics breakdown ('week' or 'month')")
+	cmd.Flags().IntVarP(&config.limit, "limit", "l", 100, "maximum number of deployments to fetch")
+	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch per deployment")
+	cmd.Flags().IntVar(&config.concurrency, "concurrency", 4, "maximum number of concurrent commit comparisons")
+
+	return cmd
+}
+
+func runDora(ctx context.Context, d github.DeploymentsService, c github.CommitsComparisonService, config *doraConfig) error {
+	config.logger.Debug("cmd.runDora",
+		slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
+		slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
+		slog.Group("config",
+			slog.String("repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name)),
+			slog.String("env", config.environment),
+			slog.Int("limit", config.limit),
+			slog.Int("commitLimit", config.commitLimit),
+			slog.Int("concurrency", config.concurrency),
+		),
+	)
+
+	opts := &dora.QueryOptions{
+		Env:     config.environment,
+		Limit:   config.limit,
+		Metrics: config.metrics,
+		Commits: &github.CommitsOpts{
+			Limit: config.commitLimit,
+		},
+		Concurrency: config.concurrency,
+	}
+
+	report, err := dora.QueryReport(ctx, config.repo, d, c, config.logger, opts)
+	if err != nil {
+		return fmt.Errorf("error querying DORA metrics: %w", err)
+	}
+
+	return config.exporter.Export(report)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestDora(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "dora__repo_owner__required",
+		Args:        []string{"dora", "-n", repo.Name},
+		ErrContains: "repo.Owner and repo.Name are required. Set env vars or pass flags",
+		Env:         env,
+	}, {
+		Name:        "dora__period__unsupported",
+		Args:        []string{"dora", "-o", repo.Owner, "-n", repo.Name, "--period", "day"},
+		ErrContains: "unsupported period \"day\". Please use 'week' or 'month'",
+		Env:         env,
+	}, {
+		Name:        "dora__since__invalid",
+		Args:        []string{"dora", "-o", repo.Owner, "-n", repo.Name, "--since", "yesterday"},
+		ErrContains: "error parsing --since: invalid time \"yesterday\"",
+		Env:         env,
+	}, {
+		Name:        "dora__json",
+		Args:        []string{"dora", "-o", repo.Owner, "-n", repo.Name, "--env", "stage", "--since", "2022-04-01", "--until", "2022-06-01", "--period", "month"},
+		WantFixture: test.NewFixture("dora", "want__month.json"),
+		Env:         env,
+	}, {
+		Name:        "dora__csv",
+		Args:        []string{"dora", "-o", repo.Owner, "-n", repo.Name, "--env", "stage", "--since", "2022-04-01", "--until", "2022-06-01", "--period", "month", "-e", "csv"},
+		WantFixture: test.NewFixture("dora", "want__month.csv"),
+		Env:         env,
+	}, {
+		// The oldest deployment in the window is compared to the deployment
+		// before the window, so its lead time covers all the commits it
+		// deployed.
+		Name:        "dora__base",
+		Args:        []string{"dora", "-o", repo.Owner, "-n", repo.Name, "--env", "stage", "--since", "2022-04-15", "--until", "2022-05-15", "--period", "month"},
+		WantFixture: test.NewFixture("dora", "want__base.json"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
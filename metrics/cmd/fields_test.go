@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestFields(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "fields__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "tagName,name,publishedAt", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields.csv"),
+		Env:         env,
+	}, {
+		// Fields are the CSV columns for every encoding, so JSON objects are
+		// keyed on the column names rather than on the names of the JSON
+		// output without --fields.
+		Name:        "fields__json__columns",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "tagName,name,publishedAt"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields_columns.json"),
+		Env:         env,
+	}, {
+		Name:        "fields__ndjson",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "tagName,name,publishedAt", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields.ndjson"),
+		Env:         env,
+	}, {
+		// Fields keep their types in JSON output.
+		Name:        "fields__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--prs", "--fields", "tagName", "--fields", "prs"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields.json"),
+		Env:         env,
+	}, {
+		Name:        "fields__help",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "help"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields_help.txt"),
+		Env:         env,
+	}, {
+		Name:        "fields__help__ndjson",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "help", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "releases", "want__fields_help.txt"),
+		Env:         env,
+	}, {
+		Name:        "fields__repos__json",
+		Args:        []string{"github", "--repos-file", "fixtures/github/services.yaml", "prs", "-l", "2", "--fields", "service,number,title"},
+		WantFixture: test.NewFixture("github", "prs", "want__repos_fields.json"),
+		Env:         env,
+	}, {
+		Name:        "fields__repos__csv",
+		Args:        []string{"github", "--repos-file", "fixtures/github/services.csv", "deployments", "-e", "csv", "--fields", "service,repo,commitSHA,state"},
+		WantFixture: test.NewFixture("github", "deployments", "want__repos_fields.csv"),
+		Env:         env,
+	}, {
+		Name:        "fields__unknown",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "tagName,notes"},
+		ErrContains: `unknown field "notes". Available fields: name, tagName, isDraft, isLatest, isPrerelease, description, createdAt, publishedAt`,
+		Env:         env,
+	}, {
+		Name:        "fields__duplicate",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "name,name"},
+		ErrContains: `duplicate field "name"`,
+		Env:         env,
+	}, {
+		Name:        "fields__help__combined",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "help,name"},
+		ErrContains: `"help" cannot be combined with other fields`,
+		Env:         env,
+	}, {
+		Name:        "fields__encoding",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--fields", "name", "-e", "plain"},
+		ErrContains: "fields are only supported for 'json', 'ndjson' and 'csv' encodings",
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
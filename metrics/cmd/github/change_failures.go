@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type changeFailuresConfig struct {
+	*githubConfig
+	limit             int
+	commitLimit       int
+	concurrency       int
+	summary           bool
+	hotfixPatterns    []string
+	changeFailureOpts *github.ChangeFailureOptions
+	environments      *[]string
+	windowOpts        windowOptions
+	window            *github.TimeWindow
+}
+
+func newChangeFailuresCmd(f Factory, c *githubConfig) *cobra.Command {
+	config := &changeFailuresConfig{githubConfig: c}
+
+	cmd := &cobra.Command{
+		Use:   "change-failures",
+		Short: "Classify deployments as change failures",
+		Long:  "Classify deployments as change failures if they failed, ship reverts or hotfixes, or roll back to an older commit",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if config.limit < 1 {
+				return fmt.Errorf("limit cannot be smaller than 1")
+			}
+
+			if config.commitLimit < 1 {
+				return fmt.Errorf("commit-limit cannot be smaller than 1")
+			}
+
+			if config.concurrency < 1 {
+				return fmt.Errorf("concurrency cannot be smaller than 1")
+			}
+
+			opts, err := github.NewChangeFailureOptions(config.hotfixPatterns)
+			if err != nil {
+				return err
+			}
+			config.changeFailureOpts = opts
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return runChangeFailures(ctx, config.graphqlAPI, config.restAPI, config)
+		},
+	}
+	cmd.Flags().IntVarP(&config.limit, "limit", "l", 10, "maximum number of deployments to fetch")
+	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch per deployment")
+	cmd.Flags().IntVar(&config.concurrency, "concurrency", 4, "maximum number of concurrent commit comparisons")
+	cmd.Flags().BoolVar(&config.summary, "summary", false, "only export change failure rates for each environment")
+	cmd.Flags().StringArrayVar(&config.hotfixPatterns, "hotfix-pattern", github.DefaultHotfixPatterns, "multiple use for regular expressions matching commit messages of hotfixes")
+
+	config.environments = cmd.Flags().StringArray("env", nil, "multiple use for deployment environments")
+	config.windowOpts.addFlags(cmd)
+
+	return cmd
+}
+
+func runChangeFailures(ctx context.Context, d github.DeploymentsService, c github.CommitsComparisonService, config *changeFailuresConfig) error {
+	opts := &github.DeploymentWithCommitsOptions{
+		Deployments: &github.DeploymentsOpts{
+			Envs:   config.environments,
+			Limit:  config.limit,
+			Window: config.window,
+		},
+		Commits: &github.CommitsOpts{
+			Limit: config.commitLimit,
+		},
+		Concurrency: config.concurrency,
+	}
+
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runChangeFailures",
+			slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
+			slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", *config.environments),
+				slog.Int("limit", config.limit),
+				slog.Int("commitLimit", config.commitLimit),
+				slog.Int("concurrency", config.concurrency),
+				slog.Any("hotfixPatterns", config.hotfixPatterns),
+				slog.Any("window", config.window),
+			),
+		)
+
+		report, err := github.QueryChangeFailures(ctx, repo, d, c, config.logger, opts, config.changeFailureOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying change failures: %w", err)
+		}
+
+		if config.summary {
+			return report.Summary, nil
+		}
+
+		return report, nil
+	})
+}
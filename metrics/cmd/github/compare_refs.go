@@ -27,13 +27,6 @@ func newCompareRefsCmd(f Factory, c *githubConfig) *cobra.Command {
 				return fmt.Errorf("limit cannot be smaller than 1")
 			}
 
-			// TODO: add CSV encoding support for this command
-			if encoding, err := cmd.Flags().GetString("encoding"); err != nil {
-				return fmt.Errorf("failed to retrieve 'encoding' flag: %w", err)
-			} else if encoding != "json" {
-				return fmt.Errorf("unsupported Export.Encoding. Please use 'json'")
-			}
-
 			if config.base == "" || config.head == "" {
 				return fmt.Errorf("git Ref Base and Head are required")
 			}
@@ -53,16 +46,18 @@ func newCompareRefsCmd(f Factory, c *githubConfig) *cobra.Command {
 }
 
 func runCompareRefs(ctx context.Context, r github.RefComparisonService, config *compareConfig) error {
-	config.logger.Debug(
-		"runCompareRefs",
-		"github.RefComparisonService", fmt.Sprintf("%T", r),
-		"repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name),
-	)
-
-	comparison, err := r.QueryCompareRefs(ctx, config.repo, config.base, config.head, config.limit)
-	if err != nil {
-		return fmt.Errorf("error querying ref comparison: %w", err)
-	}
-
-	return config.exporter.Export(comparison)
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug(
+			"runCompareRefs",
+			"github.RefComparisonService", fmt.Sprintf("%T", r),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+		)
+
+		comparison, err := r.QueryCompareRefs(ctx, repo, config.base, config.head, config.limit)
+		if err != nil {
+			return nil, fmt.Errorf("error querying ref comparison: %w", err)
+		}
+
+		return comparison, nil
+	})
 }
@@ -15,6 +15,7 @@ type deployedCommitsConfig struct {
 	commitLimit int
 	sha         string
 	environment string
+	withPRs     bool
 }
 
 func newDeployedCommitsCmd(f Factory, c *githubConfig) *cobra.Command {
@@ -38,32 +39,21 @@ func newDeployedCommitsCmd(f Factory, c *githubConfig) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
-			return runDeployedCommits(ctx, config.graphqlAPI, config.restAPI, config)
+			return runDeployedCommits(ctx, config.graphqlAPI, config.restAPI, config.graphqlAPI, config)
 		},
 	}
 	cmd.Flags().IntVar(&config.searchLimit, "search-limit", 10, "maximum number of deployments to search")
 	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch for the deployment")
 	cmd.Flags().StringVar(&config.environment, "env", "production", "deployment environment")
 	cmd.Flags().StringVar(&config.sha, "sha", "", "git commit SHA of the deployment")
+	cmd.Flags().BoolVar(&config.withPRs, "prs", false, "include merged pull requests for each deployed commit")
 
 	cmd.MarkFlagRequired("sha")
 
 	return cmd
 }
 
-func runDeployedCommits(ctx context.Context, d github.DeploymentService, c github.CommitsComparisonService, config *deployedCommitsConfig) error {
-	config.logger.Debug("cmd.runDeployedCommits",
-		"github.DeploymentService", fmt.Sprintf("%T", d),
-		"github.CommitsComparisonService", fmt.Sprintf("%T", c),
-		slog.Group("config",
-			slog.String("repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name)),
-			slog.String("env", config.environment),
-			slog.String("sha", config.sha),
-			slog.Int("searchLimit", config.searchLimit),
-			slog.Int("commitLimit", config.commitLimit),
-		),
-	)
-
+func runDeployedCommits(ctx context.Context, d github.DeploymentService, c github.CommitsComparisonService, p github.CommitPullRequestsService, config *deployedCommitsConfig) error {
 	opts := &github.DeployedCommitsOptions{
 		Deployment: &github.DeploymentOpts{
 			Env:         config.environment,
@@ -75,10 +65,32 @@ func runDeployedCommits(ctx context.Context, d github.DeploymentService, c githu
 		},
 	}
 
-	deployment, err := github.QueryDeployedCommits(ctx, config.repo, d, c, config.logger, opts)
-	if err != nil {
-		return fmt.Errorf("error querying deployed commits: %w", err)
-	}
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runDeployedCommits",
+			"github.DeploymentService", fmt.Sprintf("%T", d),
+			"github.CommitsComparisonService", fmt.Sprintf("%T", c),
+			"github.CommitPullRequestsService", fmt.Sprintf("%T", p),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.String("env", config.environment),
+				slog.String("sha", config.sha),
+				slog.Int("searchLimit", config.searchLimit),
+				slog.Int("commitLimit", config.commitLimit),
+				slog.Bool("prs", config.withPRs),
+			),
+		)
+
+		deployment, err := github.QueryDeployedCommits(ctx, repo, d, c, config.logger, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying deployed commits: %w", err)
+		}
+
+		if config.withPRs {
+			if err := github.QueryDeployedPullRequests(ctx, repo, p, config.logger, deployment); err != nil {
+				return nil, err
+			}
+		}
 
-	return config.exporter.Export(deployment)
+		return deployment, nil
+	})
 }
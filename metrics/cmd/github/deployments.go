@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/spf13/cobra"
 )
@@ -12,9 +16,14 @@ import (
 type deploymentsConfig struct {
 	*githubConfig
 	withCommits  bool
+	withPRs      bool
 	limit        int
 	commitLimit  int
+	concurrency  int
 	environments *[]string
+	windowOpts   windowOptions
+	window       *github.TimeWindow
+	syncOpts     syncOptions
 }
 
 func newDeploymentsCmd(f Factory, c *githubConfig) *cobra.Command {
@@ -33,17 +42,39 @@ func newDeploymentsCmd(f Factory, c *githubConfig) *cobra.Command {
 				return fmt.Errorf("--commit-limit requires --commits")
 			}
 
+			if config.withPRs && !config.withCommits {
+				return fmt.Errorf("--prs requires --commits")
+			}
+
 			if config.commitLimit < 1 {
 				return fmt.Errorf("commit-limit cannot be smaller than 1")
 			}
 
-			return nil
+			if cmd.Flags().Changed("concurrency") && !config.withCommits {
+				return fmt.Errorf("--concurrency requires --commits")
+			}
+
+			if config.concurrency < 1 {
+				return fmt.Errorf("concurrency cannot be smaller than 1")
+			}
+
+			if config.syncOpts.enabled() && config.withCommits {
+				return fmt.Errorf("--state-file cannot be combined with --commits")
+			}
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
+			return config.syncOpts.load(config.logger)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
 			if config.withCommits {
-				return runDeploymentsWithCommits(ctx, config.graphqlAPI, config.restAPI, config)
+				return runDeploymentsWithCommits(ctx, config.graphqlAPI, config.restAPI, config.graphqlAPI, config)
 			}
 
 			return runDeployments(ctx, config.graphqlAPI, config)
@@ -51,57 +82,187 @@ func newDeploymentsCmd(f Factory, c *githubConfig) *cobra.Command {
 	}
 	cmd.Flags().IntVarP(&config.limit, "limit", "l", 10, "maximum number of deployments to fetch")
 	cmd.Flags().BoolVar(&config.withCommits, "commits", false, "include deployed commits for each deployment")
+	cmd.Flags().BoolVar(&config.withPRs, "prs", false, "include merged pull requests for each deployed commit")
 	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch per deployment")
+	cmd.Flags().IntVar(&config.concurrency, "concurrency", 4, "maximum number of concurrent commit comparisons")
 
 	config.environments = cmd.Flags().StringArray("env", nil, "multiple use for deployment environments")
+	config.windowOpts.addFlags(cmd)
+	config.syncOpts.addFlags(cmd)
 
 	return cmd
 }
 
-func runDeploymentsWithCommits(ctx context.Context, d github.DeploymentsService, c github.CommitsComparisonService, config *deploymentsConfig) error {
-	config.logger.Debug("cmd.runDeploymentsWithCommits",
-		slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
-		slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
-		slog.Group("config",
-			slog.String("repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name)),
-			slog.Any("envs", *config.environments),
-			slog.Int("limit", config.limit),
-			slog.Int("commitLimit", config.commitLimit),
-		),
-	)
-
+func runDeploymentsWithCommits(ctx context.Context, d github.DeploymentsService, c github.CommitsComparisonService, p github.CommitPullRequestsService, config *deploymentsConfig) error {
 	opts := &github.DeploymentWithCommitsOptions{
 		Deployments: &github.DeploymentsOpts{
-			Envs:  config.environments,
-			Limit: config.limit,
+			Envs:   config.environments,
+			Limit:  config.limit,
+			Window: config.window,
 		},
 		Commits: &github.CommitsOpts{
 			Limit: config.commitLimit,
 		},
+		Concurrency: config.concurrency,
 	}
 
-	deploymentsByEnv, err := github.QueryDeploymentsWithCommits(ctx, config.repo, d, c, config.logger, opts)
-	if err != nil {
-		return fmt.Errorf("error querying deployments with commits: %w", err)
-	}
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runDeploymentsWithCommits",
+			slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
+			slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
+			slog.String("github.CommitPullRequestsService", fmt.Sprintf("%T", p)),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", *config.environments),
+				slog.Int("limit", config.limit),
+				slog.Int("commitLimit", config.commitLimit),
+				slog.Bool("prs", config.withPRs),
+				slog.Int("concurrency", config.concurrency),
+				slog.Any("window", config.window),
+			),
+		)
+
+		deploymentsByEnv, err := github.QueryDeploymentsWithCommits(ctx, repo, d, c, config.logger, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying deployments with commits: %w", err)
+		}
+
+		if config.withPRs {
+			// Sort environments so that commits are looked up in a stable order.
+			envs := make([]string, 0, len(deploymentsByEnv))
+			for env := range deploymentsByEnv {
+				envs = append(envs, env)
+			}
+			sort.Strings(envs)
+
+			var deployments []*github.DeploymentWithCommits
+			for _, env := range envs {
+				deployments = append(deployments, deploymentsByEnv[env]...)
+			}
 
-	return config.exporter.Export(deploymentsByEnv)
+			if err := github.QueryDeployedPullRequests(ctx, repo, p, config.logger, deployments...); err != nil {
+				return nil, err
+			}
+		}
+
+		return deploymentsByEnv, nil
+	})
 }
 
 func runDeployments(ctx context.Context, d github.DeploymentsService, config *deploymentsConfig) error {
-	config.logger.Debug("cmd.runDeployments",
-		"github.DeploymentsService", fmt.Sprintf("%T", d),
-		slog.Group("config",
-			slog.String("repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name)),
-			slog.Any("envs", *config.environments),
-			slog.Int("limit", config.limit),
-		),
-	)
-
-	deployments, err := d.QueryDeployments(ctx, config.repo, config.environments, config.limit)
-	if err != nil {
-		return fmt.Errorf("error querying deployments: %w", err)
+	if sd, ok := d.(github.DeploymentsStreamService); ok && !config.syncOpts.enabled() {
+		if s, ok := config.streamer(); ok {
+			return streamDeployments(ctx, sd, s, config)
+		}
+	}
+
+	return config.sync(ctx, &config.syncOpts, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		keys := deploymentKeys(repo, *config.environments)
+		window := config.syncOpts.window(config.window, keys...)
+		limit := config.syncOpts.limit(config.limit, keys...)
+
+		config.logger.Debug("cmd.runDeployments",
+			"github.DeploymentsService", fmt.Sprintf("%T", d),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", *config.environments),
+				slog.Int("limit", limit),
+				slog.Any("window", window),
+			),
+		)
+
+		deployments, err := d.QueryDeployments(ctx, repo, config.environments, limit, window)
+		if err != nil {
+			return nil, fmt.Errorf("error querying deployments: %w", err)
+		}
+
+		if !config.syncOpts.enabled() {
+			return deployments, nil
+		}
+
+		if len(deployments) > 0 {
+			oldest := deployments[len(deployments)-1].CreatedAt
+			if err := config.syncOpts.checkLimit(limit, len(deployments), oldest, keys...); err != nil {
+				return nil, err
+			}
+		}
+
+		// The window starts after the oldest cursor across environments, so
+		// drop deployments which were exported before for their environment.
+		newDeployments := make([]github.Deployment, 0, len(deployments))
+		for _, deployment := range deployments {
+			if config.syncOpts.isNew(deploymentKey(repo, *config.environments, deployment), deployment.CreatedAt) {
+				newDeployments = append(newDeployments, deployment)
+			}
+		}
+
+		for _, deployment := range newDeployments {
+			config.syncOpts.advance(deploymentKey(repo, *config.environments, deployment), deployment.CreatedAt)
+		}
+
+		config.logger.Debug("sync: new deployments",
+			slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+			slog.Int("fetched", len(deployments)),
+			slog.Int("new", len(newDeployments)),
+		)
+
+		return newDeployments, nil
+	})
+}
+
+// deploymentKeys returns the cursor keys for deployments to the given
+// environments.
+func deploymentKeys(repo *github.Repo, envs []string) []string {
+	if len(envs) == 0 {
+		return []string{repoKey("deployments", repo, allEnvs)}
 	}
 
-	return config.exporter.Export(deployments)
+	keys := make([]string, 0, len(envs))
+	for _, env := range envs {
+		keys = append(keys, repoKey("deployments", repo, env))
+	}
+	return keys
+}
+
+// deploymentKey returns the cursor key for the given deployment, which is
+// the key of the requested environment it was fetched for. The latest
+// environment of a deployment may not be one of the requested environments,
+// so its original environment is tried next and the first requested
+// environment is used if the deployment matches neither.
+func deploymentKey(repo *github.Repo, envs []string, deployment github.Deployment) string {
+	if len(envs) == 0 {
+		return repoKey("deployments", repo, allEnvs)
+	}
+
+	for _, env := range []string{deployment.LatestEnvironment, deployment.OriginalEnvironment} {
+		if slices.Contains(envs, env) {
+			return repoKey("deployments", repo, env)
+		}
+	}
+
+	return repoKey("deployments", repo, envs[0])
+}
+
+// streamDeployments exports deployments page by page as they are fetched.
+func streamDeployments(ctx context.Context, d github.DeploymentsStreamService, s export.Streamer, config *deploymentsConfig) error {
+	return config.stream(ctx, s, func(ctx context.Context, repo *github.Repo, fn func(page interface{}) error) error {
+		config.logger.Debug("cmd.streamDeployments",
+			"github.DeploymentsStreamService", fmt.Sprintf("%T", d),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", *config.environments),
+				slog.Int("limit", config.limit),
+				slog.Any("window", config.window),
+			),
+		)
+
+		err := d.StreamDeployments(ctx, repo, config.environments, config.limit, config.window, func(page []github.Deployment) error {
+			return fn(page)
+		})
+		if err != nil {
+			return fmt.Errorf("error querying deployments: %w", err)
+		}
+
+		return nil
+	})
 }
@@ -1,9 +1,11 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/catalog"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/factory"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
@@ -21,6 +23,8 @@ type githubConfig struct {
 	logger     *slog.Logger
 	exporter   export.Exporter
 	repo       *github.Repo
+	reposFile  string
+	services   []*catalog.Service
 	graphqlAPI *graphql.API
 	restAPI    *rest.API
 }
@@ -51,6 +55,85 @@ func (c *githubConfig) configureAPIs(f Factory) error {
 	return nil
 }
 
+// queryFunc queries data for a single GitHub repo.
+type queryFunc func(ctx context.Context, repo *github.Repo) (interface{}, error)
+
+// export runs the query for the configured repo and exports the result. If a
+// repos file was given, it runs the query for the repo of each service instead
+// and exports the results tagged with service and repo.
+func (c *githubConfig) export(ctx context.Context, query queryFunc) error {
+	return c.run(ctx, query, c.exporter.Export)
+}
+
+// run runs the query like export, but passes the result to exportFn.
+func (c *githubConfig) run(ctx context.Context, query queryFunc, exportFn func(v interface{}) error) error {
+	if c.services == nil {
+		result, err := query(ctx, c.repo)
+		if err != nil {
+			return err
+		}
+
+		// Exporters which key records by repo need to know the repo.
+		if _, ok := c.exporter.(export.SourceKeyedExporter); ok {
+			repo := fmt.Sprintf("%s/%s", c.repo.Owner, c.repo.Name)
+			return exportFn([]*export.RepoResult{{Repo: repo, Data: result}})
+		}
+
+		return exportFn(result)
+	}
+
+	results := make([]*export.RepoResult, 0, len(c.services))
+
+	for _, s := range c.services {
+		repo := fmt.Sprintf("%s/%s", s.Repo.Owner, s.Repo.Name)
+
+		result, err := query(ctx, s.Repo)
+		if err != nil {
+			return fmt.Errorf("error querying %s for service %s: %w", repo, s.Name, err)
+		}
+
+		results = append(results, &export.RepoResult{Service: s.Name, Repo: repo, Data: result})
+	}
+
+	return exportFn(results)
+}
+
+// streamFunc queries data for a single GitHub repo and passes it to fn a page
+// at a time.
+type streamFunc func(ctx context.Context, repo *github.Repo, fn func(page interface{}) error) error
+
+// streamer returns the exporter if it can export records page by page.
+func (c *githubConfig) streamer() (export.Streamer, bool) {
+	s, ok := c.exporter.(export.Streamer)
+	if !ok || !s.CanStream() {
+		return nil, false
+	}
+	return s, true
+}
+
+// stream is like export, but exports each page of results as soon as it is
+// fetched instead of exporting all results at the end.
+func (c *githubConfig) stream(ctx context.Context, s export.Streamer, query streamFunc) error {
+	return s.Stream(func(exportFn func(v interface{}) error) error {
+		if c.services == nil {
+			return query(ctx, c.repo, exportFn)
+		}
+
+		for _, svc := range c.services {
+			repo := fmt.Sprintf("%s/%s", svc.Repo.Owner, svc.Repo.Name)
+
+			err := query(ctx, svc.Repo, func(page interface{}) error {
+				return exportFn([]*export.RepoResult{{Service: svc.Name, Repo: repo, Data: page}})
+			})
+			if err != nil {
+				return fmt.Errorf("error querying %s for service %s: %w", repo, svc.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
 func NewGitHubCmd(f Factory) *cobra.Command {
 	config := &githubConfig{repo: f.DefaultGitHubRepo()}
 
@@ -71,10 +154,28 @@ func NewGitHubCmd(f Factory) *cobra.Command {
 			}
 			config.exporter = exporter
 
-			if config.repo.Owner == "" || config.repo.Name == "" {
-				return fmt.Errorf("repo.Owner and repo.Name are required. Set env vars or pass flags")
+			if config.reposFile != "" {
+				if cmd.Flags().Changed("repo-owner") || cmd.Flags().Changed("repo-name") {
+					return fmt.Errorf("--repos-file cannot be combined with --repo-owner or --repo-name")
+				}
+
+				services, err := catalog.ReadServices(config.reposFile)
+				if err != nil {
+					return fmt.Errorf("error reading repos file: %w", err)
+				}
+				config.services = services
+
+				logger.Debug(
+					"github: read services from repos file",
+					slog.String("reposFile", config.reposFile),
+					slog.Int("services", len(services)),
+				)
+			} else {
+				if config.repo.Owner == "" || config.repo.Name == "" {
+					return fmt.Errorf("repo.Owner and repo.Name are required. Set env vars or pass flags")
+				}
+				f.ConfigureGitHubRepo(config.repo.Owner, config.repo.Name)
 			}
-			f.ConfigureGitHubRepo(config.repo.Owner, config.repo.Name)
 
 			if err := config.configureAPIs(f); err != nil {
 				return fmt.Errorf("error configuring GitHub APIs: %w", err)
@@ -86,6 +187,7 @@ func NewGitHubCmd(f Factory) *cobra.Command {
 
 	cmd.PersistentFlags().StringVarP(&config.repo.Owner, "repo-owner", "o", config.repo.Owner, "owner of the GitHub repo")
 	cmd.PersistentFlags().StringVarP(&config.repo.Name, "repo-name", "n", config.repo.Name, "name of the GitHub repo")
+	cmd.PersistentFlags().StringVar(&config.reposFile, "repos-file", "", "CSV, JSON or YAML file mapping services to GitHub repos to query instead of a single repo")
 
 	cmd.AddCommand(newPullRequestsCmd(f, config))
 	cmd.AddCommand(newReleasesCmd(f, config))
@@ -93,6 +195,10 @@ func NewGitHubCmd(f Factory) *cobra.Command {
 	cmd.AddCommand(newCompareRefsCmd(f, config))
 	cmd.AddCommand(newHistoryCmd(f, config))
 	cmd.AddCommand(newDeployedCommitsCmd(f, config))
+	cmd.AddCommand(newLeadTimeCmd(f, config))
+	cmd.AddCommand(newChangeFailuresCmd(f, config))
+	cmd.AddCommand(newPullRequestCycleTimeCmd(f, config))
+	cmd.AddCommand(newPromotionsCmd(f, config))
 
 	return cmd
 }
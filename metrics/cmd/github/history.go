@@ -27,13 +27,6 @@ func newHistoryCmd(f Factory, c *githubConfig) *cobra.Command {
 				return fmt.Errorf("limit cannot be smaller than 1")
 			}
 
-			// TODO: add CSV encoding support for this command
-			if encoding, err := cmd.Flags().GetString("encoding"); err != nil {
-				return fmt.Errorf("failed to retrieve 'encoding' flag: %w", err)
-			} else if encoding != "json" {
-				return fmt.Errorf("unsupported Export.Encoding. Please use 'json'")
-			}
-
 			if config.base == "" || config.head == "" {
 				return fmt.Errorf("git base and head commits are required")
 			}
@@ -54,16 +47,18 @@ func newHistoryCmd(f Factory, c *githubConfig) *cobra.Command {
 }
 
 func runHistory(ctx context.Context, h github.HistoryService, config *historyConfig) error {
-	config.logger.Debug(
-		"runHistory",
-		"github.HistoryService", fmt.Sprintf("%T", h),
-		"repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name),
-	)
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug(
+			"runHistory",
+			"github.HistoryService", fmt.Sprintf("%T", h),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+		)
 
-	commits, err := h.QueryHistory(ctx, config.repo, config.head, config.base, config.limit)
-	if err != nil {
-		return fmt.Errorf("error querying commit history: %w", err)
-	}
+		commits, err := h.QueryHistory(ctx, repo, config.head, config.base, config.limit)
+		if err != nil {
+			return nil, fmt.Errorf("error querying commit history: %w", err)
+		}
 
-	return config.exporter.Export(commits)
+		return commits, nil
+	})
 }
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type leadTimeConfig struct {
+	*githubConfig
+	limit        int
+	commitLimit  int
+	concurrency  int
+	summary      bool
+	environments *[]string
+}
+
+func newLeadTimeCmd(f Factory, c *githubConfig) *cobra.Command {
+	config := &leadTimeConfig{githubConfig: c}
+
+	cmd := &cobra.Command{
+		Use:   "lead-time",
+		Short: "Retrieve lead time for changes for deployed commits",
+		Long:  "Retrieve the time from authoring each commit to its first successful deployment in each environment",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if config.limit < 1 {
+				return fmt.Errorf("limit cannot be smaller than 1")
+			}
+
+			if config.commitLimit < 1 {
+				return fmt.Errorf("commit-limit cannot be smaller than 1")
+			}
+
+			if config.concurrency < 1 {
+				return fmt.Errorf("concurrency cannot be smaller than 1")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return runLeadTime(ctx, config.graphqlAPI, config.restAPI, config)
+		},
+	}
+	cmd.Flags().IntVarP(&config.limit, "limit", "l", 10, "maximum number of deployments to fetch")
+	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch per deployment")
+	cmd.Flags().IntVar(&config.concurrency, "concurrency", 4, "maximum number of concurrent commit comparisons")
+	cmd.Flags().BoolVar(&config.summary, "summary", false, "only export lead time percentiles for each environment")
+
+	config.environments = cmd.Flags().StringArray("env", nil, "multiple use for deployment environments")
+
+	return cmd
+}
+
+func runLeadTime(ctx context.Context, d github.DeploymentsService, c github.CommitsComparisonService, config *leadTimeConfig) error {
+	opts := &github.DeploymentWithCommitsOptions{
+		Deployments: &github.DeploymentsOpts{
+			Envs:  config.environments,
+			Limit: config.limit,
+		},
+		Commits: &github.CommitsOpts{
+			Limit: config.commitLimit,
+		},
+		Concurrency: config.concurrency,
+	}
+
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runLeadTime",
+			slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
+			slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", *config.environments),
+				slog.Int("limit", config.limit),
+				slog.Int("commitLimit", config.commitLimit),
+				slog.Int("concurrency", config.concurrency),
+			),
+		)
+
+		report, err := github.QueryLeadTimes(ctx, repo, d, c, config.logger, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying lead times: %w", err)
+		}
+
+		if config.summary {
+			return report.Summary, nil
+		}
+
+		return report, nil
+	})
+}
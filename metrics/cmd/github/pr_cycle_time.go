@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type prCycleTimeConfig struct {
+	*githubConfig
+	limit      int
+	summary    bool
+	windowOpts windowOptions
+	window     *github.TimeWindow
+}
+
+func newPullRequestCycleTimeCmd(f Factory, c *githubConfig) *cobra.Command {
+	config := &prCycleTimeConfig{githubConfig: c}
+
+	cmd := &cobra.Command{
+		Use:   "pr-cycle-time",
+		Short: "Retrieve cycle time breakdowns for merged pull requests",
+		Long:  "Retrieve the coding, pickup, review and merge time of merged pull requests along with weekly percentiles",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if config.limit < 1 {
+				return fmt.Errorf("limit cannot be smaller than 1")
+			}
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return runPullRequestCycleTime(ctx, config.graphqlAPI, config)
+		},
+	}
+	cmd.Flags().IntVarP(&config.limit, "limit", "l", 100, "maximum number of merged PRs to fetch")
+	cmd.Flags().BoolVar(&config.summary, "summary", false, "only export cycle time percentiles for each week")
+	config.windowOpts.addFlags(cmd)
+
+	return cmd
+}
+
+func runPullRequestCycleTime(ctx context.Context, p github.PullRequestsService, config *prCycleTimeConfig) error {
+	opts := &github.CycleTimeOptions{
+		Limit:  config.limit,
+		Window: config.window,
+	}
+
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runPullRequestCycleTime",
+			slog.String("github.PullRequestsService", fmt.Sprintf("%T", p)),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Int("limit", config.limit),
+				slog.Any("window", config.window),
+			),
+		)
+
+		report, err := github.QueryCycleTimes(ctx, repo, p, config.logger, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying cycle times: %w", err)
+		}
+
+		if config.summary {
+			return report.Summary, nil
+		}
+
+		return report, nil
+	})
+}
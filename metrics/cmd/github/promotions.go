@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type promotionsConfig struct {
+	*githubConfig
+	limit        int
+	summary      bool
+	environments []string
+	windowOpts   windowOptions
+	window       *github.TimeWindow
+}
+
+func newPromotionsCmd(f Factory, c *githubConfig) *cobra.Command {
+	config := &promotionsConfig{githubConfig: c}
+
+	cmd := &cobra.Command{
+		Use:   "promotions",
+		Short: "Track the promotion of deployed commits across environments",
+		Long:  "Retrieve the first deployment of each commit to each environment and the delay of promoting it from one environment to the next",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if config.limit < 1 {
+				return fmt.Errorf("limit cannot be smaller than 1")
+			}
+
+			if len(config.environments) < 2 {
+				return fmt.Errorf("--env requires at least two environments")
+			}
+
+			seen := make(map[string]bool)
+			for _, env := range config.environments {
+				if seen[env] {
+					return fmt.Errorf("duplicate environment %q", env)
+				}
+				seen[env] = true
+			}
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return runPromotions(ctx, config.graphqlAPI, config)
+		},
+	}
+	cmd.Flags().IntVarP(&config.limit, "limit", "l", 100, "maximum number of deployments to fetch per environment")
+	cmd.Flags().BoolVar(&config.summary, "summary", false, "only export promotion delay percentiles for each environment")
+	cmd.Flags().StringArrayVar(&config.environments, "env", github.DefaultPromotionEnvs, "multiple use for deployment environments in the order of promotion")
+	config.windowOpts.addFlags(cmd)
+
+	return cmd
+}
+
+func runPromotions(ctx context.Context, d github.DeploymentsService, config *promotionsConfig) error {
+	opts := &github.PromotionOptions{
+		Envs:   config.environments,
+		Limit:  config.limit,
+		Window: config.window,
+	}
+
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug("cmd.runPromotions",
+			slog.String("github.DeploymentsService", fmt.Sprintf("%T", d)),
+			slog.Group("config",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.Any("envs", config.environments),
+				slog.Int("limit", config.limit),
+				slog.Any("window", config.window),
+			),
+		)
+
+		report, err := github.QueryPromotions(ctx, repo, d, config.logger, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying promotions: %w", err)
+		}
+
+		if config.summary {
+			return report.Summary, nil
+		}
+
+		return report, nil
+	})
+}
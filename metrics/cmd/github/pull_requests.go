@@ -3,14 +3,27 @@ package github
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/state"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/spf13/cobra"
 )
 
 type prsConfig struct {
 	*githubConfig
-	limit int
+	limit      int
+	states     []string
+	baseRef    string
+	labels     []string
+	author     string
+	orderBy    string
+	opts       *github.PullRequestsOpts
+	windowOpts windowOptions
+	window     *github.TimeWindow
+	syncOpts   syncOptions
 }
 
 func newPullRequestsCmd(f Factory, c *githubConfig) *cobra.Command {
@@ -24,7 +37,58 @@ func newPullRequestsCmd(f Factory, c *githubConfig) *cobra.Command {
 			if config.limit < 1 {
 				return fmt.Errorf("limit cannot be smaller than 1")
 			}
-			return nil
+
+			var states []github.PullRequestState
+			for _, s := range config.states {
+				state, err := github.ParsePullRequestState(s)
+				if err != nil {
+					return err
+				}
+				states = append(states, state)
+			}
+
+			orderBy, err := github.ParsePullRequestOrder(config.orderBy)
+			if err != nil {
+				return err
+			}
+
+			if orderBy == github.PullRequestOrderMerged {
+				for _, state := range states {
+					if state != github.PullRequestStateMerged {
+						return fmt.Errorf("--order-by merged requires --state merged")
+					}
+				}
+			}
+
+			if config.syncOpts.enabled() && orderBy != github.PullRequestOrderUpdated {
+				return fmt.Errorf("--state-file requires --order-by updated")
+			}
+
+			// Synced PRs are exported once they are merged.
+			if config.syncOpts.enabled() {
+				for _, state := range states {
+					if state != github.PullRequestStateMerged {
+						return fmt.Errorf("--state-file requires --state merged")
+					}
+				}
+			}
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
+			config.opts = &github.PullRequestsOpts{
+				Limit:   config.limit,
+				States:  states,
+				BaseRef: config.baseRef,
+				Labels:  config.labels,
+				Author:  config.author,
+				OrderBy: orderBy,
+			}
+
+			return config.syncOpts.load(config.logger)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
@@ -33,21 +97,111 @@ func newPullRequestsCmd(f Factory, c *githubConfig) *cobra.Command {
 	}
 
 	cmd.Flags().IntVarP(&config.limit, "limit", "l", 10, "limit for how many PRs to fetch")
+	cmd.Flags().StringArrayVar(&config.states, "state", []string{"merged"}, "multiple use for PR states (open, closed, merged)")
+	cmd.Flags().StringVar(&config.baseRef, "base", "", "only fetch PRs into this base branch")
+	cmd.Flags().StringArrayVar(&config.labels, "label", nil, "multiple use for labels, PRs with any of the labels are fetched")
+	cmd.Flags().StringVar(&config.author, "author", "", "only fetch PRs opened by this user")
+	cmd.Flags().StringVar(&config.orderBy, "order-by", "updated", "order and filter PRs by the time they were created, updated or merged")
+	config.windowOpts.addFlags(cmd)
+	config.syncOpts.addFlags(cmd)
 
 	return cmd
 }
 
 func runPullRequests(ctx context.Context, p github.PullRequestsService, config *prsConfig) error {
-	config.logger.Debug(
-		"runPullRequests",
-		"github.PullRequestsService", fmt.Sprintf("%T", p),
-		"repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name),
-	)
-
-	pullRequests, err := p.QueryPullRequests(ctx, config.repo, config.limit)
-	if err != nil {
-		return fmt.Errorf("error querying deployments: %w", err)
+	// PRs ordered by merge time are sorted after fetching all of them, so
+	// they cannot be exported page by page.
+	if sp, ok := p.(github.PullRequestsStreamService); ok && !config.syncOpts.enabled() && config.opts.OrderBy != github.PullRequestOrderMerged {
+		if s, ok := config.streamer(); ok {
+			return streamPullRequests(ctx, sp, s, config)
+		}
 	}
 
-	return config.exporter.Export(pullRequests)
+	return config.sync(ctx, &config.syncOpts, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		key := repoKey("prs", repo)
+		window := config.syncOpts.window(config.window, key)
+
+		config.logger.Debug(
+			"runPullRequests",
+			"github.PullRequestsService", fmt.Sprintf("%T", p),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+			slog.Any("states", config.opts.States),
+			slog.String("base", config.opts.BaseRef),
+			slog.Any("labels", config.opts.Labels),
+			slog.String("author", config.opts.Author),
+			slog.String("orderBy", string(config.opts.OrderBy)),
+			slog.Any("window", window),
+		)
+
+		opts := *config.opts
+		opts.Window = window
+		opts.Limit = config.syncOpts.limit(opts.Limit, key)
+
+		pullRequests, err := p.QueryPullRequests(ctx, repo, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("error querying pull requests: %w", err)
+		}
+
+		if !config.syncOpts.enabled() {
+			return pullRequests, nil
+		}
+
+		// Pull requests which were updated after they were exported are
+		// fetched again. Every PR merged after the merged cursor was updated
+		// after the updated cursor, so only export the ones merged since.
+		mergedKey := state.Key(key, "merged")
+		newPullRequests := make([]github.PullRequest, 0, len(pullRequests))
+		for _, pr := range pullRequests {
+			if config.syncOpts.isNew(mergedKey, pr.MergedAt) {
+				newPullRequests = append(newPullRequests, pr)
+			}
+		}
+
+		for _, pr := range newPullRequests {
+			config.syncOpts.advance(mergedKey, pr.MergedAt)
+		}
+
+		// Pull requests are ordered by the time they were last updated, so
+		// the first one is the newest.
+		if len(pullRequests) > 0 {
+			config.syncOpts.advance(key, pullRequests[0].UpdatedAt)
+		}
+
+		config.logger.Debug("sync: new pull requests",
+			slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+			slog.Int("fetched", len(pullRequests)),
+			slog.Int("new", len(newPullRequests)),
+		)
+
+		return newPullRequests, nil
+	})
+}
+
+// streamPullRequests exports pull requests page by page as they are fetched.
+func streamPullRequests(ctx context.Context, p github.PullRequestsStreamService, s export.Streamer, config *prsConfig) error {
+	return config.stream(ctx, s, func(ctx context.Context, repo *github.Repo, fn func(page interface{}) error) error {
+		config.logger.Debug(
+			"streamPullRequests",
+			"github.PullRequestsStreamService", fmt.Sprintf("%T", p),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+			slog.Any("states", config.opts.States),
+			slog.String("base", config.opts.BaseRef),
+			slog.Any("labels", config.opts.Labels),
+			slog.String("author", config.opts.Author),
+			slog.String("orderBy", string(config.opts.OrderBy)),
+			slog.Any("window", config.window),
+		)
+
+		opts := *config.opts
+		opts.Window = config.window
+
+		err := p.StreamPullRequests(ctx, repo, &opts, func(page []github.PullRequest) error {
+			return fn(page)
+		})
+		if err != nil {
+			return fmt.Errorf("error querying pull requests: %w", err)
+		}
+
+		return nil
+	})
 }
@@ -3,15 +3,21 @@ package github
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/spf13/cobra"
 )
 
 type releasesConfig struct {
 	*githubConfig
-	limit   int
-	withPRs bool
+	limit       int
+	withPRs     bool
+	commitLimit int
+	windowOpts  windowOptions
+	window      *github.TimeWindow
 }
 
 func newReleasesCmd(f Factory, c *githubConfig) *cobra.Command {
@@ -25,42 +31,90 @@ func newReleasesCmd(f Factory, c *githubConfig) *cobra.Command {
 			if config.limit < 1 {
 				return fmt.Errorf("limit cannot be smaller than 1")
 			}
+
+			if cmd.Flags().Changed("commit-limit") && !config.withPRs {
+				return fmt.Errorf("--commit-limit requires --prs")
+			}
+
+			if config.commitLimit < 1 {
+				return fmt.Errorf("commit-limit cannot be smaller than 1")
+			}
+
+			window, err := config.windowOpts.window(time.Now())
+			if err != nil {
+				return err
+			}
+			config.window = window
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			return runReleases(ctx, config.graphqlAPI, config)
+			return runReleases(ctx, config.graphqlAPI, config.graphqlAPI, config)
 		},
 	}
 	cmd.Flags().IntVarP(&config.limit, "limit", "l", 10, "limit for how many Releases to fetch")
-	cmd.Flags().BoolVar(&config.withPRs, "prs", false, "parse PR numbers from auto-generated release notes")
+	cmd.Flags().BoolVar(&config.withPRs, "prs", false, "include PRs associated with the commits since the previous release")
+	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to look up PRs for per release")
+	config.windowOpts.addFlags(cmd)
 
 	return cmd
 }
 
-func runReleases(ctx context.Context, r github.ReleasesService, config *releasesConfig) error {
-	config.logger.Debug(
-		"runReleases",
-		"github.ReleasesService", fmt.Sprintf("%T", r),
-		"repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name),
-	)
-
-	releases, err := r.QueryReleases(ctx, config.repo, config.limit)
-	if err != nil {
-		return fmt.Errorf("error querying releases: %w", err)
+func runReleases(ctx context.Context, r github.ReleasesService, p github.AssociatedPullRequestsService, config *releasesConfig) error {
+	if sr, ok := r.(github.ReleasesStreamService); ok && !config.withPRs {
+		if s, ok := config.streamer(); ok {
+			return streamReleases(ctx, sr, s, config)
+		}
 	}
 
-	if config.withPRs {
-		var releasesWithPRs []github.ReleaseWithPRs
+	return config.export(ctx, func(ctx context.Context, repo *github.Repo) (interface{}, error) {
+		config.logger.Debug(
+			"runReleases",
+			"github.ReleasesService", fmt.Sprintf("%T", r),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+			slog.Any("window", config.window),
+		)
 
-		for _, release := range releases {
-			r := release // Create a copy to avoid referencing the same loop variable memory.
-			releasesWithPRs = append(releasesWithPRs, *github.NewReleaseWithPRs(&r))
+		if config.withPRs {
+			releasesWithPRs, err := github.QueryReleasesWithPRs(ctx, repo, r, p, config.logger, &github.ReleasesWithPRsOptions{
+				Limit:       config.limit,
+				Window:      config.window,
+				CommitLimit: config.commitLimit,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error querying releases with PRs: %w", err)
+			}
+
+			return releasesWithPRs, nil
 		}
 
-		return config.exporter.Export(releasesWithPRs)
-	}
+		releases, err := r.QueryReleases(ctx, repo, config.limit, config.window)
+		if err != nil {
+			return nil, fmt.Errorf("error querying releases: %w", err)
+		}
 
-	return config.exporter.Export(releases)
+		return releases, nil
+	})
+}
+
+// streamReleases exports releases page by page as they are fetched.
+func streamReleases(ctx context.Context, r github.ReleasesStreamService, s export.Streamer, config *releasesConfig) error {
+	return config.stream(ctx, s, func(ctx context.Context, repo *github.Repo, fn func(page interface{}) error) error {
+		config.logger.Debug(
+			"streamReleases",
+			"github.ReleasesStreamService", fmt.Sprintf("%T", r),
+			"repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+			slog.Any("window", config.window),
+		)
+
+		err := r.StreamReleases(ctx, repo, config.limit, config.window, func(page []github.Release) error {
+			return fn(page)
+		})
+		if err != nil {
+			return fmt.Errorf("error querying releases: %w", err)
+		}
 
+		return nil
+	})
 }
@@ -0,0 +1,180 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/state"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+// allEnvs is the cursor key component for deployments which are queried
+// without filtering by environment.
+const allEnvs = "*"
+
+type syncOptions struct {
+	stateFile string
+	state     *state.State
+}
+
+// addFlags adds the --state-file flag to the given command.
+func (o *syncOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.stateFile, "state-file", "", "file to persist the newest item seen, so that subsequent runs only fetch and append newer items")
+}
+
+// enabled reports whether incremental sync was requested.
+func (o *syncOptions) enabled() bool {
+	return o.stateFile != ""
+}
+
+// load reads the state file. It leaves the state unset if incremental sync
+// was not requested.
+func (o *syncOptions) load(logger *slog.Logger) error {
+	if !o.enabled() {
+		return nil
+	}
+
+	s, err := state.Load(o.stateFile)
+	if err != nil {
+		return fmt.Errorf("error loading state: %w", err)
+	}
+	o.state = s
+
+	logger.Debug("sync: loaded state", slog.String("stateFile", o.stateFile), slog.Int("cursors", len(s.Cursors)))
+
+	return nil
+}
+
+// save writes the state file, if incremental sync was requested.
+func (o *syncOptions) save(logger *slog.Logger) error {
+	if o.state == nil {
+		return nil
+	}
+
+	if err := o.state.Save(o.stateFile); err != nil {
+		return fmt.Errorf("error saving state: %w", err)
+	}
+
+	logger.Debug("sync: saved state", slog.String("stateFile", o.stateFile), slog.Int("cursors", len(o.state.Cursors)))
+
+	return nil
+}
+
+// window returns a copy of the given time window which starts right after
+// the oldest of the cursors for the given keys. It returns the window
+// unchanged if any of the keys has no cursor yet, because then all items
+// need to be fetched for that key.
+func (o *syncOptions) window(window *github.TimeWindow, keys ...string) *github.TimeWindow {
+	if o.state == nil || len(keys) == 0 {
+		return window
+	}
+
+	var since time.Time
+
+	for _, key := range keys {
+		cursor, ok := o.state.Cursor(key)
+		if !ok {
+			return window
+		}
+		if since.IsZero() || cursor.Before(since) {
+			since = cursor
+		}
+	}
+
+	// Cursors hold the time of an item which was already exported.
+	since = since.Add(time.Nanosecond)
+
+	w := new(github.TimeWindow)
+	if window != nil {
+		*w = *window
+	}
+
+	if w.Since.IsZero() || w.Since.Before(since) {
+		w.Since = since
+	}
+
+	return w
+}
+
+// limit returns the number of items to fetch for the given keys. Once all
+// keys have a cursor, all items since the oldest cursor are fetched, because
+// items beyond the limit would be skipped for good once the cursor advances
+// past them. The first sync of a key fetches at most limit items.
+func (o *syncOptions) limit(limit int, keys ...string) int {
+	if o.state == nil {
+		return limit
+	}
+
+	for _, key := range keys {
+		if _, ok := o.state.Cursor(key); !ok {
+			return limit
+		}
+	}
+
+	return math.MaxInt
+}
+
+// checkLimit returns an error if count items were fetched up to the limit
+// and the oldest of them is newer than the cursor of any of the keys, which
+// means that items since that cursor may have been skipped. This happens when
+// keys without a cursor are synced along with keys with a cursor.
+func (o *syncOptions) checkLimit(limit int, count int, oldest time.Time, keys ...string) error {
+	if o.state == nil || count < limit {
+		return nil
+	}
+
+	for _, key := range keys {
+		if cursor, ok := o.state.Cursor(key); ok && cursor.Before(oldest) {
+			return fmt.Errorf("reached --limit %d before the cursor for %s. Please raise --limit", limit, key)
+		}
+	}
+
+	return nil
+}
+
+// isNew reports whether t is after the cursor for the given key.
+func (o *syncOptions) isNew(key string, t time.Time) bool {
+	if o.state == nil {
+		return true
+	}
+	cursor, ok := o.state.Cursor(key)
+	return !ok || t.After(cursor)
+}
+
+// advance moves the cursor for the given key to t.
+func (o *syncOptions) advance(key string, t time.Time) {
+	if o.state == nil {
+		return
+	}
+	o.state.Advance(key, t)
+}
+
+// repoKey returns the cursor key for the given kind of item in the repo.
+func repoKey(kind string, repo *github.Repo, parts ...string) string {
+	return state.Key(append([]string{kind, repo.Owner, repo.Name}, parts...)...)
+}
+
+// sync runs the query like export. If incremental sync was requested, it
+// appends the result to the previous export, if the exporter supports it, and
+// saves the state once the result was exported successfully.
+func (c *githubConfig) sync(ctx context.Context, opts *syncOptions, query queryFunc) error {
+	if !opts.enabled() {
+		return c.export(ctx, query)
+	}
+
+	exportFn := c.exporter.Export
+	if a, ok := c.exporter.(export.Appender); ok {
+		exportFn = a.Append
+	}
+
+	if err := c.run(ctx, query, exportFn); err != nil {
+		return err
+	}
+
+	return opts.save(c.logger)
+}
@@ -0,0 +1,52 @@
+package github
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/datetime"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/spf13/cobra"
+)
+
+type windowOptions struct {
+	since string
+	until string
+}
+
+// addFlags adds the --since and --until flags to the given command.
+func (o *windowOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.since, "since", "", "start of the time window (e.g. now-90d, 2024-01-01)")
+	cmd.Flags().StringVar(&o.until, "until", "", "end of the time window (e.g. now-30d, 2024-03-31)")
+}
+
+// window returns the time window for the flag values or nil if neither flag
+// was set. Relative times are resolved against now. A date for --until
+// includes that entire day.
+func (o *windowOptions) window(now time.Time) (*github.TimeWindow, error) {
+	if o.since == "" && o.until == "" {
+		return nil, nil
+	}
+
+	window := new(github.TimeWindow)
+
+	var err error
+
+	if o.since != "" {
+		if window.Since, err = datetime.Parse(o.since, now); err != nil {
+			return nil, fmt.Errorf("error parsing --since: %w", err)
+		}
+	}
+
+	if o.until != "" {
+		if window.Until, err = datetime.ParseEnd(o.until, now); err != nil {
+			return nil, fmt.Errorf("error parsing --until: %w", err)
+		}
+	}
+
+	if !window.Since.IsZero() && !window.Until.IsZero() && window.Until.Before(window.Since) {
+		return nil, fmt.Errorf("--until cannot be before --since")
+	}
+
+	return window, nil
+}
@@ -20,6 +20,30 @@ func TestGitHub(t *testing.T) {
 			ErrContains: "",
 			Env:         env,
 		},
+		{
+			Name:        "github__repos_file__prs",
+			Args:        []string{"github", "--repos-file", "fixtures/github/services.yaml", "prs", "-l", "2"},
+			WantFixture: test.NewFixture("github", "prs", "want__repos.json"),
+			Env:         env,
+		},
+		{
+			Name:        "github__repos_file__deployments__csv",
+			Args:        []string{"github", "--repos-file", "fixtures/github/services.csv", "deployments", "-e", "csv"},
+			WantFixture: test.NewFixture("github", "deployments", "want__repos.csv"),
+			Env:         env,
+		},
+		{
+			Name:        "github__repos_file__repo_owner",
+			Args:        []string{"github", "--repos-file", "fixtures/github/services.csv", "-o", "hackebrot", "prs"},
+			ErrContains: "--repos-file cannot be combined with --repo-owner or --repo-name",
+			Env:         env,
+		},
+		{
+			Name:        "github__repos_file__missing",
+			Args:        []string{"github", "--repos-file", "fixtures/github/nope.csv", "prs"},
+			ErrContains: "error reading repos file: error reading file at fixtures/github/nope.csv",
+			Env:         env,
+		},
 	}
 
 	test.RunTests(t, NewRootCmd, tests)
@@ -3,26 +3,38 @@ package grafana
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/spf13/cobra"
 )
 
 type deploymentsOptions struct {
-	App   string
-	From  string
-	To    string
-	Limit int
+	App            string
+	From           string
+	To             string
+	Limit          int
+	MaxAnnotations int
+	RulesFile      string
 }
 
 type deploymentsConfig struct {
 	*grafanaConfig
-	filter *grafana.AnnotationsFilter
+	filter       *grafana.AnnotationsFilter
+	rules        *grafana.AnnotationRules
+	unparsed     bool
+	withCommits  bool
+	repo         *github.Repo
+	releaseLimit int
+	commitLimit  int
 }
 
 func newDeploymentsCmd(f Factory, c *grafanaConfig) *cobra.Command {
-	opts := new(deploymentsOptions)
-	config := &deploymentsConfig{grafanaConfig: c}
+	opts := &deploymentsOptions{RulesFile: config.ReadFromEnv("GRAFANA", "ANNOTATIONS", "RULES_FILE")}
+	config := &deploymentsConfig{grafanaConfig: c, repo: f.DefaultGitHubRepo()}
 
 	cmd := &cobra.Command{
 		Use:   "deployments",
@@ -56,12 +68,78 @@ func newDeploymentsCmd(f Factory, c *grafanaConfig) *cobra.Command {
 				return fmt.Errorf("limit cannot be smaller than 1")
 			}
 
+			filter.MaxAnnotations = opts.MaxAnnotations
+
+			if filter.MaxAnnotations < 1 {
+				return fmt.Errorf("max-annotations cannot be smaller than 1")
+			}
+
 			config.filter = filter
 
+			config.rules = grafana.DefaultAnnotationRules()
+			if opts.RulesFile != "" {
+				rules, err := grafana.LoadAnnotationRules(opts.RulesFile)
+				if err != nil {
+					return fmt.Errorf("error loading rules: %w", err)
+				}
+				config.rules = rules
+			} else if err := config.rules.Compile(); err != nil {
+				return err
+			}
+
+			if !config.withCommits {
+				for _, name := range []string{"repo-owner", "repo-name", "release-limit", "commit-limit"} {
+					if cmd.Flags().Changed(name) {
+						return fmt.Errorf("--%s requires --commits", name)
+					}
+				}
+				return nil
+			}
+
+			if config.releaseLimit < 1 {
+				return fmt.Errorf("release-limit cannot be smaller than 1")
+			}
+
+			if config.commitLimit < 1 {
+				return fmt.Errorf("commit-limit cannot be smaller than 1")
+			}
+
+			if config.repo.Owner == "" || config.repo.Name == "" {
+				return fmt.Errorf("repo.Owner and repo.Name are required. Set env vars or pass flags")
+			}
+			f.ConfigureGitHubRepo(config.repo.Owner, config.repo.Name)
+
+			if err := f.ConfigureGitHubHTTPClient(); err != nil {
+				return fmt.Errorf("error initializing GitHub HTTP client: %w", err)
+			}
+
+			if err := f.ConfigureGitHubRESTAPI(); err != nil {
+				return fmt.Errorf("error initializing GitHub REST API: %w", err)
+			}
+
+			if err := f.ConfigureGitHubGraphQLAPI(); err != nil {
+				return fmt.Errorf("error initializing GitHub GraphQL API: %w", err)
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
+
+			if config.withCommits {
+				graphqlAPI, err := f.GitHubGraphQLAPI()
+				if err != nil {
+					return fmt.Errorf("error retrieving GitHub GraphQL API: %w", err)
+				}
+
+				restAPI, err := f.GitHubRestAPI()
+				if err != nil {
+					return fmt.Errorf("error retrieving GitHub REST API: %w", err)
+				}
+
+				return runDeploymentsWithCommits(ctx, graphqlAPI, restAPI, config)
+			}
+
 			return runDeployments(ctx, config)
 		},
 	}
@@ -69,7 +147,15 @@ func newDeploymentsCmd(f Factory, c *grafanaConfig) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&opts.App, "app-name", "a", "", "name of the Grafana app")
 	cmd.Flags().StringVar(&opts.From, "from", "now-6M", "epoch datetime in milliseconds (e.g. now-6M)")
 	cmd.Flags().StringVar(&opts.To, "to", "now", "epoch datetime in milliseconds (e.g. now)")
-	cmd.Flags().IntVarP(&opts.Limit, "limit", "l", 100, "limit for how many Deployments to fetch")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "l", 100, "maximum number of annotations to fetch per request")
+	cmd.Flags().IntVar(&opts.MaxAnnotations, "max-annotations", 1000, "maximum number of annotations to fetch across requests")
+	cmd.Flags().StringVar(&opts.RulesFile, "rules-file", opts.RulesFile, "JSON or YAML file with rules for reading deployments from annotations")
+	cmd.Flags().BoolVar(&config.unparsed, "unparsed", false, "only export deployments which could not be fully read from their annotations")
+	cmd.Flags().BoolVar(&config.withCommits, "commits", false, "link deployments to GitHub releases or commits and fetch deployed commits")
+	cmd.Flags().StringVarP(&config.repo.Owner, "repo-owner", "o", config.repo.Owner, "owner of the GitHub repo of the app")
+	cmd.Flags().StringVarP(&config.repo.Name, "repo-name", "n", config.repo.Name, "name of the GitHub repo of the app")
+	cmd.Flags().IntVar(&config.releaseLimit, "release-limit", 100, "maximum number of releases to match Docker image tags against")
+	cmd.Flags().IntVar(&config.commitLimit, "commit-limit", 250, "maximum number of commits to fetch per deployment")
 
 	return cmd
 }
@@ -80,9 +166,81 @@ func runDeployments(ctx context.Context, config *deploymentsConfig) error {
 		"client", config.client,
 	)
 
-	deployments, err := grafana.QueryDeployments(ctx, config.client, config.filter)
+	deployments, err := config.queryDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	return config.export(deployments)
+}
+
+func runDeploymentsWithCommits(ctx context.Context, r github.ReleasesService, c github.CommitsComparisonService, config *deploymentsConfig) error {
+	config.logger.Debug("cmd.runDeploymentsWithCommits",
+		slog.String("github.ReleasesService", fmt.Sprintf("%T", r)),
+		slog.String("github.CommitsComparisonService", fmt.Sprintf("%T", c)),
+		slog.Group("config",
+			slog.String("app", config.filter.App),
+			slog.String("repo", fmt.Sprintf("%s/%s", config.repo.Owner, config.repo.Name)),
+			slog.Int("releaseLimit", config.releaseLimit),
+			slog.Int("commitLimit", config.commitLimit),
+		),
+	)
+
+	deployments, err := config.queryDeployments(ctx)
 	if err != nil {
 		return err
 	}
-	return config.exporter.Export(deployments)
+
+	opts := &grafana.LinkOptions{
+		ReleaseLimit: config.releaseLimit,
+		Commits: &github.CommitsOpts{
+			Limit: config.commitLimit,
+		},
+	}
+
+	linked, err := grafana.LinkDeployments(ctx, config.repo, deployments, r, c, config.logger, opts)
+	if err != nil {
+		return fmt.Errorf("error linking deployments: %w", err)
+	}
+
+	return config.export(linked)
+}
+
+// queryDeployments fetches the deployments of the configured app. Only
+// deployments with parse errors are returned if --unparsed was passed.
+func (c *deploymentsConfig) queryDeployments(ctx context.Context) ([]grafana.Deployment, error) {
+	deployments, err := grafana.QueryDeployments(ctx, c.client, c.logger, c.filter, c.rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var unparsed []grafana.Deployment
+	for _, d := range deployments {
+		if len(d.ParseErrors) > 0 {
+			unparsed = append(unparsed, d)
+		}
+	}
+
+	c.logger.Debug(
+		"cmd.queryDeployments: read deployments from annotations",
+		slog.String("app", c.filter.App),
+		slog.Int("count", len(deployments)),
+		slog.Int("unparsed", len(unparsed)),
+	)
+
+	if c.unparsed {
+		return unparsed, nil
+	}
+
+	return deployments, nil
+}
+
+// export exports the deployments of the configured app.
+func (c *deploymentsConfig) export(v interface{}) error {
+	// Exporters which key records by app need to know the app.
+	if _, ok := c.exporter.(export.SourceKeyedExporter); ok {
+		return c.exporter.Export(&export.AppResult{App: c.filter.App, Data: v})
+	}
+
+	return c.exporter.Export(v)
 }
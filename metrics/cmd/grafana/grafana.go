@@ -12,6 +12,7 @@ import (
 
 type Factory interface {
 	factory.GenericFactory
+	factory.GitHubFactory
 	factory.GrafanaFactory
 }
 
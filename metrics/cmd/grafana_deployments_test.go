@@ -9,12 +9,36 @@ import (
 )
 
 func TestGrafanaDeployments(t *testing.T) {
+	rulesDir := t.TempDir()
+
+	// Deployments are tagged by service and their Docker images are tagged
+	// with commit SHAs.
+	rulesFile := writeFile(t, rulesDir, "rules.yaml", `tags:
+  - event_type:deploy
+appTag: service:{app}
+patterns:
+  - source: text
+    regex: 'Docker Image:</b> (?P<image>[\w./-]+):(?P<sha>[0-9a-f]{7,40})<br>'
+  - source: tags
+    regex: '^env:(?P<env>\w+)$'
+fields:
+  imageRepo: image
+  imageTag: sha
+  env: env
+`)
+
+	invalidRulesFile := writeFile(t, rulesDir, "invalid.json", `{"fields": {"image": "repo"}}`)
+	unsupportedRulesFile := writeFile(t, rulesDir, "rules.toml", "")
+
 	env := map[string]string{
-		config.EnvKey("GRAFANA", "TOKEN"):               "",
-		config.EnvKey("GRAFANA", "SERVER_URL"):          "",
-		config.EnvKey("GRAFANA", "ANNOTATIONS", "APP"):  "",
-		config.EnvKey("GRAFANA", "ANNOTATIONS", "FROM"): "",
-		config.EnvKey("GRAFANA", "ANNOTATIONS", "TO"):   "",
+		config.EnvKey("GRAFANA", "TOKEN"):                     "",
+		config.EnvKey("GRAFANA", "SERVER_URL"):                "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "APP"):        "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "FROM"):       "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "TO"):         "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "RULES_FILE"): "",
+		config.EnvKey("GITHUB", "REPO_OWNER"):                 "",
+		config.EnvKey("GITHUB", "REPO_NAME"):                  "",
 	}
 
 	tests := []test.TestCase{
@@ -40,6 +64,115 @@ func TestGrafanaDeployments(t *testing.T) {
 			}},
 			Env: env,
 		},
+		{
+			// Nested Docker images are flattened into columns.
+			Name:        "deployments__csv",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "-e", "csv"},
+			WantFixture: test.NewFixture("grafana", "api", "annotations", "want__defaults.csv"),
+			Env:         env,
+		},
+		{
+			// Full pages are followed by requests for older annotations until
+			// a page is not full. The annotation at the boundary of two pages
+			// is only included once.
+			Name:        "deployments__paging",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "-l", "2"},
+			WantFixture: test.NewFixture("grafana", "api", "annotations", "want__paging.json"),
+			WantReqParams: &test.WantReqParams{Grafana: &test.GrafanaReqParams{
+				Path: "api/annotations",
+				Params: url.Values{
+					"type":  []string{"annotation"},
+					"from":  []string{"now-6M"},
+					"to":    []string{"now"},
+					"limit": []string{"2"},
+					"tags":  []string{"event_type:deployment", "event_status:complete", "app:turtle"},
+				},
+			}},
+			Env: env,
+		},
+		{
+			Name:        "deployments__max_annotations",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "-l", "2", "--max-annotations", "3"},
+			WantFixture: test.NewFixture("grafana", "api", "annotations", "want__max_annotations.json"),
+			WantLog:     `msg="grafana.QueryAnnotations: reached the maximum number of annotations, skipping older annotations" app=turtle maxAnnotations=3 oldest=2022-01-10T12:00:00.000Z`,
+			Env:         env,
+		},
+		{
+			// The last page only holds the annotation at the boundary of the
+			// previous page, so paging cannot continue.
+			Name:    "deployments__paging__stuck",
+			Args:    []string{"grafana", "deployments", "-a", "turtle", "-l", "1"},
+			WantLog: `msg="grafana.QueryAnnotations: more annotations than the limit share a single time, skipping older annotations" app=turtle limit=1 oldest=2022-01-10T12:00:00.000Z`,
+			Env:     env,
+		},
+		{
+			Name:        "deployments__max_annotations__invalid",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--max-annotations", "0"},
+			ErrContains: "max-annotations cannot be smaller than 1",
+			Env:         env,
+		},
+		{
+			Name:        "deployments__repo__requires__commits",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "-o", "hackebrot", "-n", "turtle"},
+			ErrContains: "--repo-owner requires --commits",
+			Env:         env,
+		},
+		{
+			Name:        "deployments__commits__repo__required",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--commits"},
+			ErrContains: "repo.Owner and repo.Name are required. Set env vars or pass flags",
+			Env:         env,
+		},
+		{
+			Name:        "deployments__commits__commit_limit",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--commits", "-o", "hackebrot", "-n", "turtle", "--commit-limit", "0"},
+			ErrContains: "commit-limit cannot be smaller than 1",
+			Env:         env,
+		},
+		{
+			// Deployments are linked to releases by tag (with or without a "v"
+			// prefix) or to commits by SHA. The canary deployment and the
+			// deployment of an unknown tag are not linked.
+			Name:        "deployments__commits",
+			Args:        []string{"grafana", "deployments", "-a", "link", "--commits", "-o", "hackebrot", "-n", "turtle"},
+			WantFixture: test.NewFixture("grafana", "api", "annotations", "want__commits.json"),
+			Env:         env,
+		},
+		{
+			Name: "deployments__rules_file",
+			Args: []string{"grafana", "deployments", "-a", "turtle", "--rules-file", rulesFile},
+			WantReqParams: &test.WantReqParams{Grafana: &test.GrafanaReqParams{
+				Path: "api/annotations",
+				Params: url.Values{
+					"type":  []string{"annotation"},
+					"from":  []string{"now-6M"},
+					"to":    []string{"now"},
+					"limit": []string{"100"},
+					"tags":  []string{"event_type:deploy", "service:turtle"},
+				},
+			}},
+			Env: env,
+		},
+		{
+			// Neither deployment is of a commit SHA, so neither matches the
+			// rules.
+			Name:        "deployments__rules_file__unparsed",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--rules-file", rulesFile, "--unparsed"},
+			WantFixture: test.NewFixture("grafana", "api", "annotations", "want__unparsed.json"),
+			Env:         env,
+		},
+		{
+			Name:        "deployments__rules_file__invalid",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--rules-file", invalidRulesFile},
+			ErrContains: `unknown field "image"`,
+			Env:         env,
+		},
+		{
+			Name:        "deployments__rules_file__unsupported",
+			Args:        []string{"grafana", "deployments", "-a", "turtle", "--rules-file", unsupportedRulesFile},
+			ErrContains: `unsupported file extension ".toml"`,
+			Env:         env,
+		},
 		{
 			Name: "deployments__env__from_to",
 			Args: []string{"grafana", "deployments", "-a", "turtle"},
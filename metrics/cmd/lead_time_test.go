@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestLeadTime(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "lead-time__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "lead-time", "-l", "0"},
+		ErrContains: "limit cannot be smaller than 1",
+		Env:         env,
+	}, {
+		Name:        "lead-time__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "lead-time"},
+		WantFixture: test.NewFixture("github", "lead-time", "want__default.json"),
+		Env:         env,
+	}, {
+		Name:        "lead-time__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "lead-time", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "lead-time", "want__default.csv"),
+		Env:         env,
+	}, {
+		Name:        "lead-time__summary__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "lead-time", "--summary", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "lead-time", "want__summary.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestNDJSON(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		// Deployments span two pages, which are exported one after another.
+		Name:        "ndjson__deployments",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "deployments", "want__default.ndjson"),
+		Env:         env,
+	}, {
+		// Deployments with commits are not streamed, but still exported as
+		// one deployment per line.
+		Name:        "ndjson__deployments__commits",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "deployments", "want__commits.ndjson"),
+		Env:         env,
+	}, {
+		Name:        "ndjson__releases",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "releases", "want__default.ndjson"),
+		Env:         env,
+	}, {
+		Name:        "ndjson__prs__repos",
+		Args:        []string{"github", "--repos-file", "fixtures/github/services.yaml", "prs", "-l", "2", "-e", "ndjson"},
+		WantFixture: test.NewFixture("github", "prs", "want__repos.ndjson"),
+		Env:         env,
+	}, {
+		Name:        "ndjson__prs__fields",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "-e", "ndjson", "--fields", "number,title,mergedAt"},
+		WantFixture: test.NewFixture("github", "prs", "want__fields.ndjson"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
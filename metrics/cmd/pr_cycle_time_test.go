@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestPullRequestCycleTime(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "pr-cycle-time__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time", "-l", "0"},
+		ErrContains: "limit cannot be smaller than 1",
+		Env:         env,
+	}, {
+		Name:        "pr-cycle-time__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time"},
+		WantFixture: test.NewFixture("github", "pr-cycle-time", "want__default.json"),
+		Env:         env,
+	}, {
+		Name:        "pr-cycle-time__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "pr-cycle-time", "want__default.csv"),
+		Env:         env,
+	}, {
+		// The limit applies to the PRs merged last.
+		Name:        "pr-cycle-time__limit__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time", "-l", "2", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "pr-cycle-time", "want__limit.csv"),
+		Env:         env,
+	}, {
+		Name:        "pr-cycle-time__summary__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time", "--summary", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "pr-cycle-time", "want__summary.csv"),
+		Env:         env,
+	}, {
+		Name:        "pr-cycle-time__since__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "pr-cycle-time", "--since", "2023-09-09", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "pr-cycle-time", "want__since.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestPromotions(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	tests := []test.TestCase{{
+		Name:        "promotions__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "-l", "0"},
+		ErrContains: "limit cannot be smaller than 1",
+		Env:         env,
+	}, {
+		Name:        "promotions__env__single",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "--env", "prod"},
+		ErrContains: "--env requires at least two environments",
+		Env:         env,
+	}, {
+		Name:        "promotions__env__duplicate",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "--env", "stage", "--env", "prod", "--env", "stage"},
+		ErrContains: `duplicate environment "stage"`,
+		Env:         env,
+	}, {
+		Name:        "promotions__json",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions"},
+		WantFixture: test.NewFixture("github", "promotions", "want__default.json"),
+		Env:         env,
+	}, {
+		// The commit deployed to stage only is never promoted.
+		Name:        "promotions__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "promotions", "want__default.csv"),
+		Env:         env,
+	}, {
+		// Commits promoted from stage to prod skip the hello environment.
+		Name:        "promotions__envs__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "--env", "hello", "--env", "stage", "--env", "prod", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "promotions", "want__envs.csv"),
+		Env:         env,
+	}, {
+		Name:        "promotions__summary__csv",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "promotions", "--summary", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "promotions", "want__summary.csv"),
+		Env:         env,
+	}}
+
+	test.RunTests(t, NewRootCmd, tests)
+}
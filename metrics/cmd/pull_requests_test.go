@@ -7,6 +7,7 @@ import (
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/shurcooL/githubv4"
 )
 
 func TestPullRequests(t *testing.T) {
@@ -55,6 +56,85 @@ func TestPullRequests(t *testing.T) {
 		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
 		WantFile:    filepath.Join(tempDir, "prs.json"),
 		Env:         env,
+	}, {
+		Name:        "prs__since",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--since", "2023-09-09"},
+		WantFixture: test.NewFixture("github", "prs", "want__since.json"),
+		Env:         env,
+	}, {
+		Name:        "prs__since__invalid",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--since", "last-week"},
+		ErrContains: "error parsing --since: invalid time \"last-week\"",
+		Env:         env,
+	}, {
+		Name: "prs__filters",
+		Args: []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state", "open", "--state", "CLOSED", "--base", "main", "--label", "ci", "--label", "bug", "--order-by", "created"},
+		WantReqParams: &test.WantReqParams{
+			GitHub: &test.GitHubReqParams{
+				Variables: map[string]interface{}{
+					"states":      []githubv4.PullRequestState{githubv4.PullRequestStateOpen, githubv4.PullRequestStateClosed},
+					"labels":      []githubv4.String{"ci", "bug"},
+					"baseRefName": githubv4.String("main"),
+					"orderBy":     githubv4.IssueOrder{Field: githubv4.IssueOrderFieldCreatedAt, Direction: githubv4.OrderDirectionDesc},
+				},
+			},
+		},
+		Env: env,
+	}, {
+		Name: "prs__filters__default",
+		Args: []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs"},
+		WantReqParams: &test.WantReqParams{
+			GitHub: &test.GitHubReqParams{
+				Variables: map[string]interface{}{
+					"states":      []githubv4.PullRequestState{githubv4.PullRequestStateMerged},
+					"labels":      (*[]githubv4.String)(nil),
+					"baseRefName": (*githubv4.String)(nil),
+					"orderBy":     githubv4.IssueOrder{Field: githubv4.IssueOrderFieldUpdatedAt, Direction: githubv4.OrderDirectionDesc},
+				},
+			},
+		},
+		Env: env,
+	}, {
+		Name:        "prs__author",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--author", "HACKEBROT", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "prs", "want__author.csv"),
+		Env:         env,
+	}, {
+		Name:        "prs__order_by__created",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "created", "--until", "2023-10-01", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "prs", "want__order_by_created.csv"),
+		Env:         env,
+	}, {
+		Name:        "prs__order_by__merged",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "merged", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "prs", "want__order_by_merged.csv"),
+		Env:         env,
+	}, {
+		// PRs updated after the first page may still be merged last.
+		Name:        "prs__order_by__merged__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "merged", "-l", "2", "-e", "csv"},
+		WantFixture: test.NewFixture("github", "prs", "want__order_by_merged_limit.csv"),
+		Env:         env,
+	}, {
+		Name:        "prs__order_by__invalid",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "closed"},
+		ErrContains: "invalid pull request order \"closed\", must be one of created, updated, merged",
+		Env:         env,
+	}, {
+		Name:        "prs__order_by__merged__state",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "merged", "--state", "open"},
+		ErrContains: "--order-by merged requires --state merged",
+		Env:         env,
+	}, {
+		Name:        "prs__state__invalid",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state", "draft"},
+		ErrContains: "invalid pull request state \"draft\", must be one of open, closed, merged",
+		Env:         env,
+	}, {
+		Name:        "prs__state_file__order_by",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--order-by", "created", "--state-file", filepath.Join(tempDir, "state.json")},
+		ErrContains: "--state-file requires --order-by updated",
+		Env:         env,
 	}}
 
 	test.RunTests(t, NewRootCmd, tests)
@@ -65,6 +65,21 @@ func TestReleases(t *testing.T) {
 		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--prs", "-e", "csv"},
 		WantFixture: test.NewFixture("github", "releases", "want__prs.csv"),
 		Env:         env,
+	}, {
+		Name:        "releases__prs__commit_limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "-l", "1", "--prs", "--commit-limit", "10"},
+		WantFixture: test.NewFixture("github", "releases", "want__prs_limit.json"),
+		Env:         env,
+	}, {
+		Name:        "releases__commit_limit__requires_prs",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--commit-limit", "10"},
+		ErrContains: "--commit-limit requires --prs",
+		Env:         env,
+	}, {
+		Name:        "releases__since__until",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "releases", "--since", "2019-01-01", "--until", "2020-01-01"},
+		WantFixture: test.NewFixture("github", "releases", "want__window.json"),
+		Env:         env,
 	}}
 
 	test.RunTests(t, NewRootCmd, tests)
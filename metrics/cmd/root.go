@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/cmd/cache"
+	"github.com/mozilla-services/rapid-release-model/metrics/cmd/dora"
 	"github.com/mozilla-services/rapid-release-model/metrics/cmd/github"
 	"github.com/mozilla-services/rapid-release-model/metrics/cmd/grafana"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/factory"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +20,12 @@ type metricsOptions struct {
 	exporter struct {
 		Encoding string
 		Filename string
+		Fields   []string
+	}
+	cache struct {
+		Dir      string
+		TTL      time.Duration
+		Disabled bool
 	}
 	debug bool
 }
@@ -40,20 +50,38 @@ func NewRootCmd(f factory.Factory) *cobra.Command {
 				return fmt.Errorf("error configuring encoder: %w", err)
 			}
 
+			if len(opts.exporter.Fields) > 0 {
+				if err := f.ConfigureFields(opts.exporter.Fields); err != nil {
+					return fmt.Errorf("error configuring fields: %w", err)
+				}
+			}
+
 			if err := f.ConfigureExporter(opts.exporter.Filename); err != nil {
 				return fmt.Errorf("error configuring exporter: %w", err)
 			}
 
+			if opts.cache.Dir != "" && !opts.cache.Disabled {
+				if err := f.ConfigureCache(opts.cache.Dir, opts.cache.TTL); err != nil {
+					return fmt.Errorf("error configuring cache: %w", err)
+				}
+			}
+
 			return nil
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&opts.exporter.Encoding, "encoding", "e", "json", "export encoding")
 	rootCmd.PersistentFlags().StringVarP(&opts.exporter.Filename, "filename", "f", "", "export to file")
+	rootCmd.PersistentFlags().StringSliceVar(&opts.exporter.Fields, "fields", nil, "export only these comma-separated fields in this order, named after the CSV columns for all encodings (use 'help' to list fields)")
 	rootCmd.PersistentFlags().BoolVar(&opts.debug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&opts.cache.Dir, "cache-dir", config.ReadFromEnv("CACHE", "DIR"), "cache API responses in this directory")
+	rootCmd.PersistentFlags().DurationVar(&opts.cache.TTL, "cache-ttl", 24*time.Hour, "time after which cached API responses expire")
+	rootCmd.PersistentFlags().BoolVar(&opts.cache.Disabled, "no-cache", false, "do not read or write cached API responses")
 
 	rootCmd.AddCommand(github.NewGitHubCmd(f))
 	rootCmd.AddCommand(grafana.NewGrafanaCmd(f))
+	rootCmd.AddCommand(dora.NewDoraCmd(f))
+	rootCmd.AddCommand(cache.NewCacheCmd(f))
 
 	return rootCmd
 }
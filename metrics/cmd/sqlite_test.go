@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestSQLite(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"):           "",
+		config.EnvKey("GITHUB", "REPO_NAME"):            "",
+		config.EnvKey("GRAFANA", "TOKEN"):               "",
+		config.EnvKey("GRAFANA", "SERVER_URL"):          "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "APP"):  "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "FROM"): "",
+		config.EnvKey("GRAFANA", "ANNOTATIONS", "TO"):   "",
+	}
+
+	filename := filepath.Join(t.TempDir(), "metrics.db")
+
+	sqlite := func(args ...string) []string {
+		return append(args, "-e", "sqlite", "-f", filename)
+	}
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:        "sqlite__filename__required",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "-e", "sqlite"},
+		ErrContains: "sqlite encoding requires a filename",
+		Env:         env,
+	}, {
+		Name: "sqlite__prs",
+		Args: sqlite("github", "-o", repo.Owner, "-n", repo.Name, "prs"),
+		Env:  env,
+	}, {
+		// Records are upserted, so exporting them again does not add rows.
+		Name: "sqlite__prs__again",
+		Args: sqlite("github", "-o", repo.Owner, "-n", repo.Name, "prs"),
+		Env:  env,
+	}, {
+		Name: "sqlite__prs__repos_file",
+		Args: sqlite("github", "--repos-file", "fixtures/github/services.csv", "prs"),
+		Env:  env,
+	}, {
+		Name: "sqlite__releases__prs",
+		Args: sqlite("github", "-o", repo.Owner, "-n", repo.Name, "releases", "--prs"),
+		Env:  env,
+	}, {
+		Name: "sqlite__deployments",
+		Args: sqlite("github", "-o", repo.Owner, "-n", repo.Name, "deployments"),
+		Env:  env,
+	}, {
+		Name: "sqlite__deployments__commits",
+		Args: sqlite("github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "--prs"),
+		Env:  env,
+	}, {
+		Name: "sqlite__grafana__deployments",
+		Args: sqlite("grafana", "deployments", "-a", "turtle"),
+		Env:  env,
+	}, {
+		Name:        "sqlite__lead_time__unsupported",
+		Args:        sqlite("github", "-o", repo.Owner, "-n", repo.Name, "lead-time"),
+		ErrContains: "unable to export type *github.LeadTimeReport to SQLite",
+		Env:         env,
+	}})
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	counts := []struct {
+		query string
+		want  int
+	}{
+		{query: "SELECT COUNT(*) FROM repos", want: 1},
+		// Merged PRs of releases and deployed commits are stored along with
+		// the exported PRs.
+		{query: "SELECT COUNT(*) FROM pull_requests", want: 9},
+		{query: "SELECT COUNT(*) FROM pull_requests WHERE merged_at IS NOT NULL", want: 9},
+		{query: "SELECT COUNT(*) FROM release_pull_requests", want: 5},
+		{query: "SELECT COUNT(*) FROM pull_request_labels", want: 4},
+		{query: "SELECT COUNT(*) FROM pull_requests WHERE approved_at IS NOT NULL", want: 2},
+		{query: "SELECT COUNT(*) FROM releases", want: 3},
+		{query: "SELECT COUNT(*) FROM deployments", want: 4},
+		{query: "SELECT COUNT(*) FROM deployment_statuses", want: 13},
+		{query: "SELECT COUNT(*) FROM deployments WHERE failed_attempts > 0", want: 2},
+		{query: "SELECT COUNT(*) FROM commit_pull_requests", want: 3},
+		{query: "SELECT COUNT(*) FROM grafana_deployments WHERE app = 'turtle'", want: 2},
+	}
+
+	for _, c := range counts {
+		var got int
+		if err := db.QueryRow(c.query).Scan(&got); err != nil {
+			t.Fatalf("error running %q: %v", c.query, err)
+		}
+		if got != c.want {
+			t.Errorf("%q = %d, want %d", c.query, got, c.want)
+		}
+	}
+
+	var deployedCommits int
+	if err := db.QueryRow("SELECT COUNT(*) FROM deployed_commits").Scan(&deployedCommits); err != nil {
+		t.Fatalf("error counting deployed commits: %v", err)
+	}
+	if deployedCommits == 0 {
+		t.Errorf("no deployed commits in database")
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/state"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/test"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+// writeFile writes the given content to a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// writeState writes a state file with the given cursors to dir and returns
+// its path.
+func writeState(t *testing.T, dir string, cursors map[string]time.Time) string {
+	t.Helper()
+
+	s := state.New()
+	for key, cursor := range cursors {
+		s.Advance(key, cursor)
+	}
+
+	filename := filepath.Join(dir, "state.json")
+	if err := s.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestSyncPullRequests(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	// The first run exports all PRs, the second run finds no newer PRs and
+	// leaves the export unchanged.
+	fullDir := t.TempDir()
+	fullState := filepath.Join(fullDir, "state.json")
+	fullExport := filepath.Join(fullDir, "prs.json")
+
+	// The export already contains PRs up to the cursor.
+	seededDir := t.TempDir()
+	seededState := writeState(t, seededDir, map[string]time.Time{
+		"prs/hackebrot/turtle": time.Date(2023, time.September, 9, 0, 0, 0, 0, time.UTC),
+	})
+	seededExport := writeFile(t, seededDir, "prs.json", "[]\n")
+
+	// Once there is a cursor, all PRs since the cursor are fetched regardless
+	// of the limit.
+	limitDir := t.TempDir()
+	limitState := writeState(t, limitDir, map[string]time.Time{
+		"prs/hackebrot/turtle": time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+	})
+	limitExport := writeFile(t, limitDir, "prs.json", "[]\n")
+
+	// PR 1 was updated after it was exported, so it is fetched again.
+	exportedDir := t.TempDir()
+	exportedState := writeState(t, exportedDir, map[string]time.Time{
+		"prs/hackebrot/turtle":        time.Date(2023, time.September, 9, 0, 0, 0, 0, time.UTC),
+		"prs/hackebrot/turtle/merged": time.Date(2023, time.September, 10, 7, 24, 16, 0, time.UTC),
+	})
+	exportedExport := writeFile(t, exportedDir, "prs.json", "[]\n")
+
+	corruptState := writeFile(t, t.TempDir(), "state.json", "{")
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:        "sync__prs__first",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", fullState, "-f", fullExport},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantFile:    fullExport,
+		Env:         env,
+	}, {
+		Name:        "sync__prs__second",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", fullState, "-f", fullExport, "--debug"},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantFile:    fullExport,
+		WantLog:     `msg="sync: saved state"`,
+		Env:         env,
+	}, {
+		Name:        "sync__prs__cursor",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", seededState, "-f", seededExport},
+		WantFixture: test.NewFixture("github", "prs", "want__since.json"),
+		WantFile:    seededExport,
+		Env:         env,
+	}, {
+		Name:        "sync__prs__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "-l", "2", "--state-file", limitState, "-f", limitExport},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantFile:    limitExport,
+		Env:         env,
+	}, {
+		Name:     "sync__prs__exported",
+		Args:     []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", exportedState, "-f", exportedExport},
+		WantText: "[]",
+		WantFile: exportedExport,
+		Env:      env,
+	}, {
+		Name:        "sync__prs__state",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state", "open", "--state-file", filepath.Join(t.TempDir(), "state.json")},
+		ErrContains: "--state-file requires --state merged",
+		Env:         env,
+	}, {
+		Name:        "sync__prs__corrupt",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", corruptState},
+		ErrContains: "is corrupt",
+		Env:         env,
+	}})
+
+	s, err := state.Load(fullState)
+	if err != nil {
+		t.Fatalf("error loading state: %v", err)
+	}
+
+	want := time.Date(2023, time.September, 10, 7, 24, 20, 0, time.UTC)
+	if cursor, ok := s.Cursor("prs/hackebrot/turtle"); !ok || !cursor.Equal(want) {
+		t.Errorf("state cursor = %v, %v, want %v, true", cursor, ok, want)
+	}
+
+	wantMerged := time.Date(2023, time.December, 10, 7, 24, 16, 0, time.UTC)
+	if cursor, ok := s.Cursor("prs/hackebrot/turtle/merged"); !ok || !cursor.Equal(wantMerged) {
+		t.Errorf("state merged cursor = %v, %v, want %v, true", cursor, ok, wantMerged)
+	}
+
+	// All PRs are updated after the export, e.g. by a comment, so they are
+	// fetched again, but they are not exported again.
+	s.Cursors["prs/hackebrot/turtle"] = time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Save(fullState); err != nil {
+		t.Fatal(err)
+	}
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:        "sync__prs__third",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "prs", "--state-file", fullState, "-f", fullExport},
+		WantFixture: test.NewFixture("github", "prs", "want__default.json"),
+		WantFile:    fullExport,
+		Env:         env,
+	}})
+}
+
+func TestSyncDeployments(t *testing.T) {
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	env := map[string]string{
+		config.EnvKey("GITHUB", "REPO_OWNER"): "",
+		config.EnvKey("GITHUB", "REPO_NAME"):  "",
+	}
+
+	// The export already contains deployments up to the cursor.
+	appendDir := t.TempDir()
+	appendState := writeState(t, appendDir, map[string]time.Time{
+		"deployments/hackebrot/turtle/*": time.Date(2022, time.April, 15, 0, 0, 0, 0, time.UTC),
+	})
+	appendCSV := `description,createdAt,updatedAt,originalEnvironment,latestEnvironment,task,state,abbreviatedCommitSHA,commitSHA,statuses,durationSeconds,failedAttempts,logURL,environmentURL
+Deployment02,2022-04-01T20:25:05Z,2022-04-01T20:25:05Z,stage,stage,deploy,INACTIVE,2abc111,2abc111bbbbbbbbbbb,"[""QUEUED"",""SUCCESS"",""INACTIVE""]",180,0,https://github.com/hackebrot/turtle/actions/runs/2,https://stage.turtle.example.com
+Deployment01,2022-02-01T20:25:05Z,2022-02-01T20:25:05Z,hello,hello,deploy,ACTIVE,3abc111,3abc111ccccccccccc,"[""QUEUED"",""ERROR""]",60,1,https://github.com/hackebrot/turtle/actions/runs/1,
+`
+	appendExport := writeFile(t, appendDir, "deployments.csv", appendCSV)
+
+	// Once there is a cursor, all deployments since the cursor are fetched
+	// regardless of the limit.
+	limitDir := t.TempDir()
+	limitState := writeState(t, limitDir, map[string]time.Time{
+		"deployments/hackebrot/turtle/*": time.Date(2022, time.April, 15, 0, 0, 0, 0, time.UTC),
+	})
+	limitExport := writeFile(t, limitDir, "deployments.csv", appendCSV)
+
+	// Cursors are kept per environment.
+	envState := writeState(t, t.TempDir(), map[string]time.Time{
+		"deployments/hackebrot/turtle/prod":  time.Date(2022, time.April, 1, 20, 25, 5, 0, time.UTC),
+		"deployments/hackebrot/turtle/stage": time.Date(2022, time.May, 1, 20, 20, 5, 0, time.UTC),
+	})
+
+	newEnvState := writeState(t, t.TempDir(), map[string]time.Time{
+		"deployments/hackebrot/turtle/prod": time.Date(2022, time.April, 1, 20, 25, 5, 0, time.UTC),
+	})
+
+	// Cursors are keyed by the requested environment, even if the latest
+	// environment of the deployments differs.
+	renamedState := filepath.Join(t.TempDir(), "state.json")
+
+	mismatchExport := writeFile(t, t.TempDir(), "deployments.csv", "number,title\n")
+
+	test.RunTests(t, NewRootCmd, []test.TestCase{{
+		Name:        "sync__deployments__append",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--state-file", appendState, "-e", "csv", "-f", appendExport},
+		WantFixture: test.NewFixture("github", "deployments", "want__append.csv"),
+		WantFile:    appendExport,
+		Env:         env,
+	}, {
+		Name: "sync__deployments__env",
+		Args: []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--env", "prod", "--env", "stage", "--state-file", envState, "-e", "csv"},
+		WantText: `description,createdAt,updatedAt,originalEnvironment,latestEnvironment,task,state,abbreviatedCommitSHA,commitSHA,statuses,durationSeconds,failedAttempts,logURL,environmentURL
+Deployment03,2022-05-02T20:25:05Z,2022-05-02T20:25:05Z,prod,prod,deploy,ACTIVE,1abc111,1abc111aaaaaaaaaaa,"[""QUEUED"",""IN_PROGRESS"",""SUCCESS""]",300,0,https://github.com/hackebrot/turtle/actions/runs/4,https://turtle.example.com`,
+		Env: env,
+	}, {
+		Name:        "sync__deployments__limit",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "-l", "1", "--state-file", limitState, "-e", "csv", "-f", limitExport},
+		WantFixture: test.NewFixture("github", "deployments", "want__append.csv"),
+		WantFile:    limitExport,
+		Env:         env,
+	}, {
+		// The environment without a cursor limits the number of deployments,
+		// which stops before the cursor of the other environments.
+		Name:        "sync__deployments__limit__new_env",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--env", "prod", "--env", "hello", "-l", "1", "--state-file", newEnvState, "-e", "csv"},
+		ErrContains: "reached --limit 1 before the cursor for deployments/hackebrot/turtle/prod",
+		Env:         env,
+	}, {
+		Name:        "sync__deployments__env__renamed",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--env", "live", "--state-file", renamedState, "-e", "csv"},
+		WantFixture: test.NewFixture("github", "deployments", "want__default.csv"),
+		Env:         env,
+	}, {
+		Name:        "sync__deployments__header__mismatch",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--state-file", filepath.Join(t.TempDir(), "state.json"), "-e", "csv", "-f", mismatchExport},
+		ErrContains: "unable to append to existing CSV",
+		Env:         env,
+	}, {
+		Name:        "sync__deployments__commits",
+		Args:        []string{"github", "-o", repo.Owner, "-n", repo.Name, "deployments", "--commits", "--state-file", appendState},
+		ErrContains: "--state-file cannot be combined with --commits",
+		Env:         env,
+	}})
+
+	s, err := state.Load(appendState)
+	if err != nil {
+		t.Fatalf("error loading state: %v", err)
+	}
+
+	want := time.Date(2022, time.May, 2, 20, 25, 5, 0, time.UTC)
+	if cursor, ok := s.Cursor("deployments/hackebrot/turtle/*"); !ok || !cursor.Equal(want) {
+		t.Errorf("state cursor = %v, %v, want %v, true", cursor, ok, want)
+	}
+
+	s, err = state.Load(renamedState)
+	if err != nil {
+		t.Fatalf("error loading state: %v", err)
+	}
+
+	if cursor, ok := s.Cursor("deployments/hackebrot/turtle/live"); !ok || !cursor.Equal(want) {
+		t.Errorf("state cursor = %v, %v, want %v, true", cursor, ok, want)
+	}
+	if len(s.Cursors) != 1 {
+		t.Errorf("state has cursors %v, want only the requested environment", s.Cursors)
+	}
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"regexp"
+
+	ghrest "github.com/google/go-github/v68/github"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/rest"
+)
+
+// Full Git commit SHA. Comparisons between two SHAs never change.
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Compile-time interface assertions ensure that the cached clients implement
+// the interfaces of the clients they wrap.
+var (
+	_ graphql.Client     = (*GraphQLClient)(nil)
+	_ rest.Client        = (*RESTClient)(nil)
+	_ grafana.HTTPClient = (*GrafanaClient)(nil)
+)
+
+// GraphQLClient is a graphql.Client which caches query results. Entries are
+// keyed on the query type, a hash of its fields and the query variables, so
+// that adding fields to a query does not return entries without them.
+type GraphQLClient struct {
+	client graphql.Client
+	store  *Store
+	logger *slog.Logger
+}
+
+// NewGraphQLClient returns a GraphQLClient which wraps the given client.
+func NewGraphQLClient(client graphql.Client, store *Store, logger *slog.Logger) *GraphQLClient {
+	return &GraphQLClient{client: client, store: store, logger: logger}
+}
+
+// Query returns the cached result for the query if there is one, otherwise it
+// forwards the query to the wrapped client and caches the result.
+func (c *GraphQLClient) Query(ctx context.Context, q interface{}, variables map[string]interface{}) error {
+	vars, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("error encoding query variables: %w", err)
+	}
+	key := fmt.Sprintf("graphql:%T:%s:%s", q, queryHash(q), vars)
+
+	if lookup(c.store, c.logger, key, q) {
+		return nil
+	}
+
+	if err := c.client.Query(ctx, q, variables); err != nil {
+		return err
+	}
+
+	store(c.store, c.logger, key, q, false)
+
+	return nil
+}
+
+// queryHash returns a hash of the fields of the given query, including their
+// graphql tags, from which the GraphQL query is built.
+func queryHash(q interface{}) string {
+	h := sha256.New()
+	writeType(h, reflect.TypeOf(q), make(map[reflect.Type]bool))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// writeType writes the structure of t to h. Structs are written once, so that
+// recursive types terminate.
+func writeType(h hash.Hash, t reflect.Type, seen map[reflect.Type]bool) {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		fmt.Fprintf(h, "%s:", t.Kind())
+		writeType(h, t.Elem(), seen)
+	case reflect.Map:
+		fmt.Fprintf(h, "map[")
+		writeType(h, t.Key(), seen)
+		fmt.Fprintf(h, "]")
+		writeType(h, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			fmt.Fprintf(h, "%s", t)
+			return
+		}
+		seen[t] = true
+
+		fmt.Fprintf(h, "{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fmt.Fprintf(h, "%s %q ", f.Name, f.Tag)
+			writeType(h, f.Type, seen)
+			fmt.Fprintf(h, ";")
+		}
+		fmt.Fprintf(h, "}")
+	default:
+		fmt.Fprintf(h, "%s", t)
+	}
+}
+
+// RESTClient is a rest.Client which caches commit comparisons. Comparisons
+// between two full commit SHAs never expire.
+type RESTClient struct {
+	client rest.Client
+	store  *Store
+	logger *slog.Logger
+}
+
+// NewRESTClient returns a RESTClient which wraps the given client.
+func NewRESTClient(client rest.Client, store *Store, logger *slog.Logger) *RESTClient {
+	return &RESTClient{client: client, store: store, logger: logger}
+}
+
+// restComparison is the cached result of a CompareCommits call.
+type restComparison struct {
+	Comparison *ghrest.CommitsComparison
+	NextPage   int
+}
+
+// CompareCommits returns the cached comparison if there is one, otherwise it
+// forwards the call to the wrapped client and caches the result.
+func (c *RESTClient) CompareCommits(ctx context.Context, owner, repo, base, head string, opts *ghrest.ListOptions) (*ghrest.CommitsComparison, *ghrest.Response, error) {
+	var page, perPage int
+	if opts != nil {
+		page, perPage = opts.Page, opts.PerPage
+	}
+	key := fmt.Sprintf("rest:CompareCommits:%s/%s:%s...%s:page=%d:perPage=%d", owner, repo, base, head, page, perPage)
+
+	var cached restComparison
+	if lookup(c.store, c.logger, key, &cached) {
+		return cached.Comparison, &ghrest.Response{NextPage: cached.NextPage}, nil
+	}
+
+	comparison, resp, err := c.client.CompareCommits(ctx, owner, repo, base, head, opts)
+	if err != nil {
+		return comparison, resp, err
+	}
+
+	result := restComparison{Comparison: comparison}
+	if resp != nil {
+		result.NextPage = resp.NextPage
+	}
+
+	store(c.store, c.logger, key, &result, shaPattern.MatchString(base) && shaPattern.MatchString(head))
+
+	return comparison, resp, nil
+}
+
+// GrafanaClient is a grafana.HTTPClient which caches responses. Entries are
+// keyed on the URL path and the query parameters.
+type GrafanaClient struct {
+	client grafana.HTTPClient
+	store  *Store
+	logger *slog.Logger
+}
+
+// NewGrafanaClient returns a GrafanaClient which wraps the given client.
+func NewGrafanaClient(client grafana.HTTPClient, store *Store, logger *slog.Logger) *GrafanaClient {
+	return &GrafanaClient{client: client, store: store, logger: logger}
+}
+
+// Get returns the cached response if there is one, otherwise it forwards the
+// request to the wrapped client and caches the response.
+func (c *GrafanaClient) Get(ctx context.Context, p string, params url.Values) ([]byte, error) {
+	key := fmt.Sprintf("grafana:GET:%s?%s", p, params.Encode())
+
+	var body []byte
+	if lookup(c.store, c.logger, key, &body) {
+		return body, nil
+	}
+
+	body, err := c.client.Get(ctx, p, params)
+	if err != nil {
+		return nil, err
+	}
+
+	store(c.store, c.logger, key, body, false)
+
+	return body, nil
+}
+
+// lookup decodes the cached value for key into v and reports whether it was
+// found. Errors are logged and treated as cache misses.
+func lookup(s *Store, logger *slog.Logger, key string, v interface{}) bool {
+	found, err := s.Get(key, v)
+	if err != nil {
+		logger.Warn("cache: error reading entry", slog.String("key", key), slog.Any("error", err))
+		return false
+	}
+
+	if found {
+		logger.Debug("cache: hit", slog.String("key", key))
+	} else {
+		logger.Debug("cache: miss", slog.String("key", key))
+	}
+
+	return found
+}
+
+// store caches v for key. Errors are logged, so that a failure to write to the
+// cache does not fail the query.
+func store(s *Store, logger *slog.Logger, key string, v interface{}, immutable bool) {
+	if err := s.Set(key, v, immutable); err != nil {
+		logger.Warn("cache: error writing entry", slog.String("key", key), slog.Any("error", err))
+	}
+}
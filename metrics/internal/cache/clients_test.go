@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ghrest "github.com/google/go-github/v68/github"
+	"github.com/shurcooL/githubv4"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type fakeGraphQLClient struct {
+	calls int
+}
+
+type fakeQuery struct {
+	Repository struct {
+		Name      string
+		CreatedAt githubv4.DateTime
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (c *fakeGraphQLClient) Query(ctx context.Context, q interface{}, variables map[string]interface{}) error {
+	c.calls++
+	query := q.(*fakeQuery)
+	query.Repository.Name = string(variables["name"].(githubv4.String))
+	query.Repository.CreatedAt = githubv4.DateTime{Time: time.Date(2023, time.September, 8, 9, 0, 0, 0, time.UTC)}
+	return nil
+}
+
+func TestGraphQLClient(t *testing.T) {
+	store, _ := newTestStore(t, time.Hour)
+	fake := &fakeGraphQLClient{}
+	client := NewGraphQLClient(fake, store, discardLogger)
+
+	query := func(name string) *fakeQuery {
+		t.Helper()
+		q := new(fakeQuery)
+		variables := map[string]interface{}{
+			"owner": githubv4.String("hackebrot"),
+			"name":  githubv4.String(name),
+		}
+		if err := client.Query(context.Background(), q, variables); err != nil {
+			t.Fatalf("Query() returned unexpected error: %v", err)
+		}
+		return q
+	}
+
+	first := query("turtle")
+	second := query("turtle")
+
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("Query() from cache mismatch (-want +got):\n%s", diff)
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("client was called %d times, want 1", fake.calls)
+	}
+
+	// Different variables are cached separately.
+	if got := query("tortoise"); got.Repository.Name != "tortoise" {
+		t.Errorf("Query() returned %q, want %q", got.Repository.Name, "tortoise")
+	}
+
+	if fake.calls != 2 {
+		t.Errorf("client was called %d times, want 2", fake.calls)
+	}
+}
+
+func TestQueryHash(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+
+	var first struct {
+		Repository struct {
+			Name string
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	var second struct {
+		Repository struct {
+			Name string
+			URL  string
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	var third struct {
+		Repository struct {
+			Name string
+		} `graphql:"repository(owner: $owner, name: $name, followRenames: false)"`
+	}
+
+	if queryHash(&first) != queryHash(&first) {
+		t.Errorf("queryHash() is not stable")
+	}
+
+	// Queries with different fields or arguments are cached separately.
+	if queryHash(&first) == queryHash(&second) {
+		t.Errorf("queryHash() does not change when a field is added")
+	}
+	if queryHash(&first) == queryHash(&third) {
+		t.Errorf("queryHash() does not change when an argument changes")
+	}
+
+	// Recursive types terminate.
+	if queryHash(&node{}) == "" {
+		t.Errorf("queryHash() of recursive type is empty")
+	}
+}
+
+type fakeRESTClient struct {
+	calls int
+	err   error
+}
+
+func (c *fakeRESTClient) CompareCommits(ctx context.Context, owner, repo, base, head string, opts *ghrest.ListOptions) (*ghrest.CommitsComparison, *ghrest.Response, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	comparison := &ghrest.CommitsComparison{
+		TotalCommits: ghrest.Ptr(1),
+		Commits:      []*ghrest.RepositoryCommit{{SHA: ghrest.Ptr(head)}},
+	}
+	return comparison, &ghrest.Response{NextPage: opts.Page + 1}, nil
+}
+
+func TestRESTClient(t *testing.T) {
+	base := strings.Repeat("a", 40)
+	head := strings.Repeat("b", 40)
+
+	tests := []struct {
+		name          string
+		base          string
+		head          string
+		wantImmutable bool
+	}{
+		{name: "sha", base: base, head: head, wantImmutable: true},
+		{name: "ref", base: "v1.0.0", head: head, wantImmutable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, now := newTestStore(t, time.Hour)
+			fake := &fakeRESTClient{}
+			client := NewRESTClient(fake, store, discardLogger)
+
+			compare := func() (*ghrest.CommitsComparison, *ghrest.Response) {
+				t.Helper()
+				comparison, resp, err := client.CompareCommits(context.Background(), "hackebrot", "turtle", tt.base, tt.head, &ghrest.ListOptions{Page: 1, PerPage: 100})
+				if err != nil {
+					t.Fatalf("CompareCommits() returned unexpected error: %v", err)
+				}
+				return comparison, resp
+			}
+
+			first, firstResp := compare()
+			second, secondResp := compare()
+
+			if diff := cmp.Diff(first, second); diff != "" {
+				t.Errorf("CompareCommits() from cache mismatch (-want +got):\n%s", diff)
+			}
+			if secondResp.NextPage != firstResp.NextPage {
+				t.Errorf("CompareCommits() from cache NextPage = %d, want %d", secondResp.NextPage, firstResp.NextPage)
+			}
+			if fake.calls != 1 {
+				t.Errorf("client was called %d times, want 1", fake.calls)
+			}
+
+			// Only comparisons between two SHAs survive the TTL.
+			*now = now.Add(48 * time.Hour)
+			compare()
+
+			wantCalls := 2
+			if tt.wantImmutable {
+				wantCalls = 1
+			}
+			if fake.calls != wantCalls {
+				t.Errorf("client was called %d times after TTL, want %d", fake.calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestRESTClientError(t *testing.T) {
+	store, _ := newTestStore(t, time.Hour)
+	fake := &fakeRESTClient{err: errors.New("nope")}
+	client := NewRESTClient(fake, store, discardLogger)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.CompareCommits(context.Background(), "hackebrot", "turtle", "a", "b", &ghrest.ListOptions{Page: 1}); err == nil {
+			t.Fatalf("CompareCommits() did not return an error")
+		}
+	}
+
+	// Errors are not cached.
+	if fake.calls != 2 {
+		t.Errorf("client was called %d times, want 2", fake.calls)
+	}
+}
+
+type fakeGrafanaClient struct {
+	calls int
+}
+
+func (c *fakeGrafanaClient) Get(ctx context.Context, p string, params url.Values) ([]byte, error) {
+	c.calls++
+	return []byte(`[{"text": "` + params.Get("from") + `"}]`), nil
+}
+
+func TestGrafanaClient(t *testing.T) {
+	store, _ := newTestStore(t, time.Hour)
+	fake := &fakeGrafanaClient{}
+	client := NewGrafanaClient(fake, store, discardLogger)
+
+	get := func(from string) string {
+		t.Helper()
+		body, err := client.Get(context.Background(), "api/annotations", url.Values{"from": {from}, "to": {"now"}})
+		if err != nil {
+			t.Fatalf("Get() returned unexpected error: %v", err)
+		}
+		return string(body)
+	}
+
+	want := `[{"text": "now-6M"}]`
+
+	for i := 0; i < 2; i++ {
+		if got := get("now-6M"); got != want {
+			t.Errorf("Get() = %q, want %q", got, want)
+		}
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("client was called %d times, want 1", fake.calls)
+	}
+
+	get("now-1y")
+
+	if fake.calls != 2 {
+		t.Errorf("client was called %d times, want 2", fake.calls)
+	}
+}
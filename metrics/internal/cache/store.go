@@ -0,0 +1,269 @@
+// Package cache provides an on-disk cache for responses from the GitHub and
+// Grafana APIs, along with wrappers for the API clients which use it.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// Version of the cache entry format. Bump this when the format of cached
+// values changes, so that old entries are no longer used.
+const version = "v2"
+
+// Store is an on-disk key-value store. Each entry is stored in a separate JSON
+// file, named after the SHA-256 hash of its key. Entries expire after the TTL
+// unless they are immutable.
+type Store struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// entryPath matches the path of a cache entry relative to the cache
+// directory, as returned by path.
+var entryPath = regexp.MustCompile(`^([0-9a-f]{2})/([0-9a-f]{64})\.json$`)
+
+// entry is the file format of a cache entry.
+type entry struct {
+	Version   string
+	Key       string
+	CreatedAt int64
+	Immutable bool
+	Data      json.RawMessage
+}
+
+// Stats holds information about the entries in the cache.
+type Stats struct {
+	Dir              string
+	Entries          int
+	ImmutableEntries int
+	ExpiredEntries   int
+	Bytes            int64
+}
+
+// PruneResult holds information about the entries removed from the cache.
+type PruneResult struct {
+	Dir     string
+	Removed int
+	Bytes   int64
+}
+
+// NewStore returns a new Store for the given directory, which is created if it
+// does not exist.
+func NewStore(dir string, ttl time.Duration) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is required")
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("cache TTL must be greater than 0")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory at %s: %w", dir, err)
+	}
+
+	return &Store{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+// Dir returns the directory of the Store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// path returns the file path for the given key.
+func (s *Store) path(key string) string {
+	sum := sha256.Sum256([]byte(version + ":" + key))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, h[:2], h+".json")
+}
+
+// Get decodes the value for the given key into v, which must be a non-nil
+// pointer. It reports whether a value was found. Expired and unreadable
+// entries are treated as missing. v is left unchanged if the value cannot be
+// decoded.
+func (s *Store) Get(key string, v interface{}) (bool, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return false, fmt.Errorf("cache value must be a non-nil pointer, got %T", v)
+	}
+
+	e, _, err := readEntry(s.path(key))
+	if err != nil || e.Key != key || s.expired(e) {
+		return false, nil
+	}
+
+	// Decode into a new value first, so that v is not partially updated.
+	decoded := reflect.New(rv.Elem().Type())
+	if err := json.Unmarshal(e.Data, decoded.Interface()); err != nil {
+		return false, fmt.Errorf("error decoding cache entry for %s: %w", key, err)
+	}
+	rv.Elem().Set(decoded.Elem())
+
+	return true, nil
+}
+
+// Set stores the value for the given key. Immutable entries never expire.
+func (s *Store) Set(key string, v interface{}, immutable bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry for %s: %w", key, err)
+	}
+
+	content, err := json.Marshal(&entry{
+		Version:   version,
+		Key:       key,
+		CreatedAt: s.now().Unix(),
+		Immutable: immutable,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry for %s: %w", key, err)
+	}
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	// Write to a temporary file first, so that concurrent readers never see a
+	// partially written entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating cache entry for %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache entry for %s: %w", key, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache entry for %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing cache entry for %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Stats returns information about the entries in the cache.
+func (s *Store) Stats() (*Stats, error) {
+	stats := &Stats{Dir: s.dir}
+
+	err := s.walk(func(path string, e *entry, size int64) error {
+		stats.Entries++
+		stats.Bytes += size
+
+		if e.Immutable {
+			stats.ImmutableEntries++
+		}
+		if s.expired(e) {
+			stats.ExpiredEntries++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Prune removes expired entries from the cache. If all is true, it removes all
+// entries including immutable ones. Files which are not cache entries are left
+// in place.
+func (s *Store) Prune(all bool) (*PruneResult, error) {
+	result := &PruneResult{Dir: s.dir}
+
+	err := s.walk(func(path string, e *entry, size int64) error {
+		if !all && !s.expired(e) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("error removing cache entry at %s: %w", path, err)
+		}
+
+		result.Removed++
+		result.Bytes += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// expired reports whether the entry is older than the TTL or was written in
+// a previous entry format.
+func (s *Store) expired(e *entry) bool {
+	if e.Version != version {
+		return true
+	}
+	if e.Immutable {
+		return false
+	}
+	return s.now().Sub(time.Unix(e.CreatedAt, 0)) > s.ttl
+}
+
+// walk calls fn for each entry in the cache. Files which do not match the
+// layout of the cache directory or cannot be decoded as an entry are skipped,
+// so that unrelated files in the directory are never touched.
+func (s *Store) walk(fn func(path string, e *entry, size int64) error) error {
+	return filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return nil
+		}
+
+		m := entryPath.FindStringSubmatch(filepath.ToSlash(rel))
+		if m == nil || m[2][:2] != m[1] {
+			return nil
+		}
+
+		e, size, err := readEntry(path)
+		if err != nil || e.Key == "" || e.Version == "" {
+			return nil
+		}
+
+		return fn(path, e, size)
+	})
+}
+
+// readEntry reads and decodes the entry at the given path and returns its
+// size in bytes.
+func readEntry(path string) (*entry, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	e := new(entry)
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, int64(len(data)), err
+	}
+
+	return e, int64(len(data)), nil
+}
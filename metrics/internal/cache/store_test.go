@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type value struct {
+	Name  string
+	Count int
+}
+
+// newTestStore returns a Store in a temporary directory with a clock that can
+// be advanced by the test.
+func newTestStore(t *testing.T, ttl time.Duration) (*Store, *time.Time) {
+	t.Helper()
+
+	store, err := NewStore(t.TempDir(), ttl)
+	if err != nil {
+		t.Fatalf("NewStore() returned unexpected error: %v", err)
+	}
+
+	now := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	return store, &now
+}
+
+func TestNewStore(t *testing.T) {
+	if _, err := NewStore("", time.Hour); err == nil {
+		t.Errorf("NewStore() with empty dir did not return an error")
+	}
+
+	if _, err := NewStore(t.TempDir(), 0); err == nil {
+		t.Errorf("NewStore() with zero TTL did not return an error")
+	}
+
+	dir := filepath.Join(t.TempDir(), "nested", "cache")
+	if _, err := NewStore(dir, time.Hour); err != nil {
+		t.Fatalf("NewStore() returned unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("NewStore() did not create cache directory: %v", err)
+	}
+}
+
+func TestStoreGetSet(t *testing.T) {
+	store, now := newTestStore(t, time.Hour)
+
+	var got value
+	if found, err := store.Get("a", &got); err != nil || found {
+		t.Fatalf("Get() on empty cache = %v, %v, want false, nil", found, err)
+	}
+
+	if err := store.Set("a", &value{Name: "a", Count: 1}, false); err != nil {
+		t.Fatalf("Set() returned unexpected error: %v", err)
+	}
+	if err := store.Set("b", &value{Name: "b", Count: 2}, true); err != nil {
+		t.Fatalf("Set() returned unexpected error: %v", err)
+	}
+
+	if found, err := store.Get("a", &got); err != nil || !found {
+		t.Fatalf("Get() = %v, %v, want true, nil", found, err)
+	}
+	if diff := cmp.Diff(value{Name: "a", Count: 1}, got); diff != "" {
+		t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Mutable entries expire after the TTL, immutable entries never do.
+	*now = now.Add(2 * time.Hour)
+
+	if found, _ := store.Get("a", &got); found {
+		t.Errorf("Get() returned expired entry")
+	}
+	if found, _ := store.Get("b", &got); !found {
+		t.Errorf("Get() did not return immutable entry after TTL")
+	}
+
+	// Values that cannot be decoded leave the target unchanged.
+	var n int
+	if _, err := store.Get("b", &n); err == nil {
+		t.Errorf("Get() into mismatched type did not return an error")
+	}
+
+	if _, err := store.Get("b", got); err == nil {
+		t.Errorf("Get() into non-pointer did not return an error")
+	}
+}
+
+func TestStoreStatsPrune(t *testing.T) {
+	store, now := newTestStore(t, time.Hour)
+
+	for key, immutable := range map[string]bool{"a": false, "b": false, "c": true} {
+		if err := store.Set(key, &value{Name: key}, immutable); err != nil {
+			t.Fatalf("Set() returned unexpected error: %v", err)
+		}
+	}
+
+	// Write files which are not cache entries: a corrupt file in the layout
+	// of the store and unrelated JSON files.
+	foreign := []string{
+		filepath.Join(store.Dir(), "ab", strings.Repeat("ab", 32)+".json"),
+		filepath.Join(store.Dir(), "prs.json"),
+		filepath.Join(store.Dir(), "sub", "config.json"),
+	}
+	for i, content := range []string{"{", `{"Name":"prs"}`, "[1, 2]"} {
+		if err := os.MkdirAll(filepath.Dir(foreign[i]), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(foreign[i], []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*now = now.Add(2 * time.Hour)
+
+	// Refresh one of the mutable entries.
+	if err := store.Set("b", &value{Name: "b"}, false); err != nil {
+		t.Fatalf("Set() returned unexpected error: %v", err)
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("Stats() returned unexpected error: %v", err)
+	}
+
+	wantStats := &Stats{Dir: store.Dir(), Entries: 3, ImmutableEntries: 1, ExpiredEntries: 1}
+	if diff := cmp.Diff(wantStats, stats, ignoreBytes()); diff != "" {
+		t.Errorf("Stats() mismatch (-want +got):\n%s", diff)
+	}
+	if stats.Bytes == 0 {
+		t.Errorf("Stats() returned zero bytes")
+	}
+
+	// Prune removes the expired entry.
+	result, err := store.Prune(false)
+	if err != nil {
+		t.Fatalf("Prune() returned unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&PruneResult{Dir: store.Dir(), Removed: 1}, result, ignoreBytes()); diff != "" {
+		t.Errorf("Prune() mismatch (-want +got):\n%s", diff)
+	}
+
+	var got value
+	if found, _ := store.Get("b", &got); !found {
+		t.Errorf("Prune() removed entry that has not expired")
+	}
+
+	// Prune with all removes the remaining entries.
+	result, err = store.Prune(true)
+	if err != nil {
+		t.Fatalf("Prune() returned unexpected error: %v", err)
+	}
+	if result.Removed != 2 {
+		t.Errorf("Prune(all) removed %d entries, want 2", result.Removed)
+	}
+
+	if stats, _ := store.Stats(); stats.Entries != 0 {
+		t.Errorf("Stats() after Prune(all) = %d entries, want 0", stats.Entries)
+	}
+
+	for _, path := range foreign {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Prune() removed file which is not a cache entry: %v", err)
+		}
+	}
+}
+
+func ignoreBytes() cmp.Option {
+	return cmp.FilterPath(func(p cmp.Path) bool {
+		return p.Last().String() == ".Bytes"
+	}, cmp.Ignore())
+}
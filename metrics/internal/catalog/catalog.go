@@ -0,0 +1,115 @@
+// Package catalog reads service catalog files, which map services to the
+// GitHub repos they are developed in.
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"gopkg.in/yaml.v3"
+)
+
+// Same format as the services.csv file read by ciplatforms, e.g. mozilla/fxa
+var repoPattern = regexp.MustCompile(`^(?P<owner>[a-zA-Z0-9][a-zA-Z0-9._-]*)/(?P<name>[a-zA-Z0-9._-]+)$`)
+
+// Service is a service from the catalog along with its GitHub repo.
+type Service struct {
+	Name string
+	Repo *github.Repo
+}
+
+// entry is a service as it appears in JSON and YAML catalog files.
+type entry struct {
+	Service string `json:"service" yaml:"service"`
+	Repo    string `json:"repo" yaml:"repo"`
+}
+
+// ReadServices loads services from the given catalog file. The format is
+// derived from the file extension: .csv files use the same format as the
+// services.csv file read by ciplatforms (a header row followed by service
+// and repo columns), .json and .yaml/.yml files contain a list of objects
+// with service and repo keys.
+func ReadServices(filename string) ([]*Service, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file at %s: %w", filename, err)
+	}
+
+	var entries []entry
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".csv":
+		entries, err = decodeCSV(data)
+	case ".json":
+		err = json.Unmarshal(data, &entries)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &entries)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q. Please use .csv, .json, .yaml or .yml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding file at %s: %w", filename, err)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no services found in %s", filename)
+	}
+
+	services := make([]*Service, 0, len(entries))
+
+	for _, e := range entries {
+		if e.Service == "" {
+			return nil, fmt.Errorf("missing service name for repo %q", e.Repo)
+		}
+
+		match := repoPattern.FindStringSubmatch(e.Repo)
+		if match == nil {
+			return nil, fmt.Errorf("invalid GitHub repository format %q for service %s. Please use owner/name", e.Repo, e.Service)
+		}
+
+		services = append(services, &Service{
+			Name: e.Service,
+			Repo: &github.Repo{
+				Owner: match[repoPattern.SubexpIndex("owner")],
+				Name:  match[repoPattern.SubexpIndex("name")],
+			},
+		})
+	}
+
+	return services, nil
+}
+
+// decodeCSV decodes catalog entries from CSV records, skipping the header row.
+// Columns after the service and repo columns are ignored.
+func decodeCSV(data []byte) ([]entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid CSV format on line %d: expected at least 2 columns", i+1)
+		}
+
+		entries = append(entries, entry{Service: record[0], Repo: record[1]})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestReadServices(t *testing.T) {
+	want := []*Service{
+		{Name: "turtle", Repo: &github.Repo{Owner: "hackebrot", Name: "turtle"}},
+		{Name: "fxa", Repo: &github.Repo{Owner: "mozilla", Name: "fxa"}},
+	}
+
+	tests := []struct {
+		name        string
+		filename    string
+		content     string
+		errContains string
+	}{
+		{
+			name:     "csv",
+			filename: "services.csv",
+			content:  "service,repo,notes\nturtle,hackebrot/turtle,\nfxa,mozilla/fxa,auth\n",
+		},
+		{
+			name:     "json",
+			filename: "services.json",
+			content:  `[{"service": "turtle", "repo": "hackebrot/turtle"}, {"service": "fxa", "repo": "mozilla/fxa"}]`,
+		},
+		{
+			name:     "yaml",
+			filename: "services.yaml",
+			content:  "- service: turtle\n  repo: hackebrot/turtle\n- service: fxa\n  repo: mozilla/fxa\n",
+		},
+		{
+			name:        "csv__too__few__columns",
+			filename:    "services.csv",
+			content:     "service,repo\nturtle\n",
+			errContains: "expected at least 2 columns",
+		},
+		{
+			name:        "invalid__repo",
+			filename:    "services.csv",
+			content:     "service,repo\nturtle,https://github.com/hackebrot/turtle\n",
+			errContains: `invalid GitHub repository format "https://github.com/hackebrot/turtle" for service turtle`,
+		},
+		{
+			name:        "missing__service",
+			filename:    "services.yml",
+			content:     "- repo: hackebrot/turtle\n",
+			errContains: `missing service name for repo "hackebrot/turtle"`,
+		},
+		{
+			name:        "empty",
+			filename:    "services.csv",
+			content:     "service,repo\n",
+			errContains: "no services found",
+		},
+		{
+			name:        "unsupported__extension",
+			filename:    "services.txt",
+			content:     "turtle hackebrot/turtle\n",
+			errContains: `unsupported file extension ".txt"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+
+			got, err := ReadServices(filename)
+
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ReadServices() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadServices() returned unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ReadServices() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package datetime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Relative time expressions in the style of Grafana, e.g. now, now-90d or now-6M
+var relativePattern = regexp.MustCompile(`^now(?:(?P<sign>[+-])(?P<amount>\d+)(?P<unit>[smhdwMy]))?$`)
+
+// Parse parses a relative time expression such as now-90d, a date such as
+// 2006-01-02, an RFC 3339 timestamp or epoch milliseconds. Relative
+// expressions are resolved against now. Supported units are s (seconds),
+// m (minutes), h (hours), d (days), w (weeks), M (months) and y (years).
+func Parse(s string, now time.Time) (time.Time, error) {
+	if match := relativePattern.FindStringSubmatch(s); match != nil {
+		return parseRelative(match, now.UTC())
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q. Please use a relative time (e.g. now-90d), YYYY-MM-DD, RFC 3339 or epoch milliseconds", s)
+}
+
+// ParseEnd parses s like Parse, but resolves dates such as 2006-01-02 to the
+// last instant of that day rather than to midnight. Use it for the inclusive
+// end of a time window, so that the given day is part of the window.
+func ParseEnd(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	return Parse(s, now)
+}
+
+// parseRelative resolves the submatches of relativePattern against now.
+func parseRelative(match []string, now time.Time) (time.Time, error) {
+	sign := match[relativePattern.SubexpIndex("sign")]
+	if sign == "" {
+		return now, nil
+	}
+
+	amount, err := strconv.Atoi(match[relativePattern.SubexpIndex("amount")])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid amount in relative time: %w", err)
+	}
+
+	if sign == "-" {
+		amount = -amount
+	}
+
+	switch unit := match[relativePattern.SubexpIndex("unit")]; unit {
+	case "s":
+		return now.Add(time.Duration(amount) * time.Second), nil
+	case "m":
+		return now.Add(time.Duration(amount) * time.Minute), nil
+	case "h":
+		return now.Add(time.Duration(amount) * time.Hour), nil
+	case "d":
+		return now.AddDate(0, 0, amount), nil
+	case "w":
+		return now.AddDate(0, 0, 7*amount), nil
+	case "M":
+		return now.AddDate(0, amount, 0), nil
+	case "y":
+		return now.AddDate(amount, 0, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported unit %q in relative time", unit)
+	}
+}
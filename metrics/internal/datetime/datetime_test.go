@@ -0,0 +1,80 @@
+package datetime
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input       string
+		want        time.Time
+		errContains string
+	}{
+		{input: "now", want: now},
+		{input: "now-90d", want: time.Date(2024, time.January, 1, 12, 30, 0, 0, time.UTC)},
+		{input: "now-2w", want: time.Date(2024, time.March, 17, 12, 30, 0, 0, time.UTC)},
+		{input: "now-6M", want: time.Date(2023, time.October, 1, 12, 30, 0, 0, time.UTC)},
+		{input: "now-1y", want: time.Date(2023, time.March, 31, 12, 30, 0, 0, time.UTC)},
+		{input: "now-12h", want: time.Date(2024, time.March, 31, 0, 30, 0, 0, time.UTC)},
+		{input: "now-30m", want: time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)},
+		{input: "now+1d", want: time.Date(2024, time.April, 1, 12, 30, 0, 0, time.UTC)},
+		{input: "2024-01-02", want: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-01-02T15:04:05+01:00", want: time.Date(2024, time.January, 2, 14, 4, 5, 0, time.UTC)},
+		{input: "1706064620004", want: time.Date(2024, time.January, 24, 2, 50, 20, 4000000, time.UTC)},
+		{input: "now-90", errContains: "invalid time \"now-90\""},
+		{input: "yesterday", errContains: "invalid time \"yesterday\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input, now)
+
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Parse(%q) error = %v, want error containing %q", tt.input, err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnd(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		// Dates include the entire day.
+		{input: "2024-03-31", want: time.Date(2024, time.March, 31, 23, 59, 59, 999999999, time.UTC)},
+		{input: "2024-02-29", want: time.Date(2024, time.February, 29, 23, 59, 59, 999999999, time.UTC)},
+		{input: "now-1d", want: time.Date(2024, time.March, 30, 12, 30, 0, 0, time.UTC)},
+		{input: "2024-03-31T00:00:00Z", want: time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseEnd(tt.input, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseEnd(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,12 @@
 package export
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
-	"time"
-
-	"github.com/mozilla-services/rapid-release-model/pkg/github"
 )
 
 // Interface for CSV, JSON and other encoders
@@ -17,6 +14,13 @@ type Encoder interface {
 	Encode(w io.Writer, v interface{}) error
 }
 
+// AppendEncoder is implemented by encoders which can add records to
+// previously encoded data.
+type AppendEncoder interface {
+	Encoder
+	EncodeAppend(w io.Writer, existing []byte, v interface{}) error
+}
+
 type JSONEcoder struct{}
 
 func (j *JSONEcoder) Encode(w io.Writer, v interface{}) error {
@@ -25,6 +29,27 @@ func (j *JSONEcoder) Encode(w io.Writer, v interface{}) error {
 	return e.Encode(v)
 }
 
+// EncodeAppend writes a JSON array with the items of the existing JSON array
+// followed by the items of v, which must also encode to a JSON array.
+func (j *JSONEcoder) EncodeAppend(w io.Writer, existing []byte, v interface{}) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(existing, &items); err != nil {
+		return fmt.Errorf("unable to append to existing JSON, expected an array: %w", err)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var newItems []json.RawMessage
+	if err := json.Unmarshal(data, &newItems); err != nil {
+		return fmt.Errorf("unable to append type %T to JSON, expected an array: %w", v, err)
+	}
+
+	return j.Encode(w, append(items, newItems...))
+}
+
 func NewJSONEncoder() (*JSONEcoder, error) {
 	return &JSONEcoder{}, nil
 }
@@ -43,251 +68,60 @@ func NewPlainEncoder() (*PlainEncoder, error) {
 type CSVEncoder struct{}
 
 func (c *CSVEncoder) Encode(w io.Writer, v interface{}) error {
-	var records [][]string
-	var err error
-
-	csvw := csv.NewWriter(w)
-
-	switch v := v.(type) {
-	case []github.PullRequest:
-		records = PullRequestsToCSVRecords(v)
-	case []github.Release:
-		records = ReleasesToCSVRecords(v)
-	case []github.ReleaseWithPRs:
-		records, err = ReleasesWithPRsToCSVRecords(v)
-		if err != nil {
-			return err
-		}
-	case []github.Deployment:
-		records = DeploymentsToCSVRecords(v)
-	case map[string][]*github.DeploymentWithCommits:
-		records = DeploymentsWithCommitsToCSVRecords(v)
-	case *github.DeploymentWithCommits:
-		records = DeploymentWithCommitsToCSVRecords(v)
-	default:
-		return fmt.Errorf("unable to export type %T to CSV", v)
+	records, err := ToCSVRecords(v)
+	if err != nil {
+		return err
 	}
 
+	csvw := csv.NewWriter(w)
 	return csvw.WriteAll(records)
 }
 
-func NewCSVEncoder() (*CSVEncoder, error) {
-	return &CSVEncoder{}, nil
-}
-
-func PullRequestsToCSVRecords(prs []github.PullRequest) [][]string {
-	var records [][]string
-
-	// Add column headers to records
-	records = append(records, []string{
-		"number",
-		"title",
-		"createdAt",
-		"updatedAt",
-		"closedAt",
-		"mergedAt",
-	})
-
-	// Add a record for each pull request
-	for _, pr := range prs {
-		record := []string{
-			strconv.Itoa(pr.Number),
-			pr.Title,
-			pr.CreatedAt.Format(time.RFC3339),
-			pr.UpdatedAt.Format(time.RFC3339),
-			pr.ClosedAt.Format(time.RFC3339),
-			pr.MergedAt.Format(time.RFC3339),
-		}
-		records = append(records, record)
+// EncodeAppend writes the existing CSV records followed by the records for v,
+// without repeating the header row. The header row of v must match the
+// header row of the existing records.
+func (c *CSVEncoder) EncodeAppend(w io.Writer, existing []byte, v interface{}) error {
+	records, err := ToCSVRecords(v)
+	if err != nil {
+		return err
 	}
-	return records
-}
-
-func ReleasesToCSVRecords(rs []github.Release) [][]string {
-	var records [][]string
 
-	// Add column headers to records
-	records = append(records, []string{
-		"name",
-		"tagName",
-		"isDraft",
-		"isLatest",
-		"isPrerelease",
-		"description",
-		"createdAt",
-		"publishedAt",
-	})
-
-	// Add a record for each release
-	for _, r := range rs {
-		record := []string{
-			r.Name,
-			r.TagName,
-			strconv.FormatBool(r.IsDraft),
-			strconv.FormatBool(r.IsLatest),
-			strconv.FormatBool(r.IsPrerelease),
-			r.Description,
-			r.CreatedAt.Format(time.RFC3339),
-			r.PublishedAt.Format(time.RFC3339),
-		}
-		records = append(records, record)
+	header, err := csv.NewReader(bytes.NewReader(existing)).Read()
+	if err != nil {
+		return fmt.Errorf("unable to read header of existing CSV: %w", err)
 	}
-	return records
-}
-
-// ReleasesWithPRsToCSVRecords is identical to ReleasesToCSVRecords with the
-// addition of an extra column on the right for a JSON array of PR numbers.
-func ReleasesWithPRsToCSVRecords(rs []github.ReleaseWithPRs) ([][]string, error) {
-	var records [][]string
 
-	// Add column headers to records
-	records = append(records, []string{
-		"name",
-		"tagName",
-		"isDraft",
-		"isLatest",
-		"isPrerelease",
-		"description",
-		"createdAt",
-		"publishedAt",
-		"prs",
-	})
-
-	// Add a record for each release
-	for _, r := range rs {
-		prs, err := json.Marshal(r.PRs)
-		if err != nil {
-			return nil, fmt.Errorf("error encoding PRs as JSON: %w", err)
-		}
-		record := []string{
-			r.Release.Name,
-			r.Release.TagName,
-			strconv.FormatBool(r.Release.IsDraft),
-			strconv.FormatBool(r.Release.IsLatest),
-			strconv.FormatBool(r.Release.IsPrerelease),
-			r.Release.Description,
-			r.Release.CreatedAt.Format(time.RFC3339),
-			r.Release.PublishedAt.Format(time.RFC3339),
-			string(prs),
-		}
-		records = append(records, record)
+	if strings.Join(header, ",") != strings.Join(records[0], ",") {
+		return fmt.Errorf("unable to append to existing CSV, header %q does not match %q", header, records[0])
 	}
 
-	return records, nil
-}
-
-func DeploymentsToCSVRecords(ds []github.Deployment) [][]string {
-	var records [][]string
-
-	// Add column headers to records
-	records = append(records, []string{
-		"description",
-		"createdAt",
-		"updatedAt",
-		"originalEnvironment",
-		"latestEnvironment",
-		"task",
-		"state",
-		"abbreviatedCommitSHA",
-		"commitSHA",
-	})
+	if _, err := w.Write(existing); err != nil {
+		return err
+	}
 
-	// Add a record for each deployment
-	for _, d := range ds {
-		record := []string{
-			d.Description,
-			d.CreatedAt.Format(time.RFC3339),
-			d.UpdatedAt.Format(time.RFC3339),
-			d.OriginalEnvironment,
-			d.LatestEnvironment,
-			d.Task,
-			string(d.State),
-			string(d.Commit.AbbreviatedSHA),
-			string(d.Commit.SHA),
+	if !bytes.HasSuffix(existing, []byte("\n")) {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
 		}
-		records = append(records, record)
 	}
-	return records
-}
-
-func DeploymentWithCommitsToCSVRecords(d *github.DeploymentWithCommits) [][]string {
-	var records [][]string
 
-	// Add column headers to records
-	records = append(records, []string{
-		"description",
-		"createdAt",
-		"updatedAt",
-		"originalEnvironment",
-		"latestEnvironment",
-		"task",
-		"state",
-		"abbreviatedCommitSHA",
-		"commitSHA",
-		"deployedCommits",
-	})
-
-	var deployedSHAs []string
-	for _, commit := range d.DeployedCommits {
-		deployedSHAs = append(deployedSHAs, commit.SHA)
-	}
+	csvw := csv.NewWriter(w)
+	return csvw.WriteAll(records[1:])
+}
 
-	record := []string{
-		d.Description,
-		d.CreatedAt.Format(time.RFC3339),
-		d.UpdatedAt.Format(time.RFC3339),
-		d.OriginalEnvironment,
-		d.LatestEnvironment,
-		d.Task,
-		string(d.State),
-		string(d.Commit.AbbreviatedSHA),
-		string(d.Commit.SHA),
-		strings.Join(deployedSHAs, ","),
+// ToCSVRecords converts the given value to CSV records, including a header
+// row. See MarshalCSVRecords for how values are converted to columns.
+func ToCSVRecords(v interface{}) ([][]string, error) {
+	switch v := v.(type) {
+	case CSVRecords:
+		return v, nil
+	case []*RepoResult:
+		return RepoResultsToCSVRecords(v)
+	default:
+		return MarshalCSVRecords(v)
 	}
-	records = append(records, record)
-	return records
 }
 
-func DeploymentsWithCommitsToCSVRecords(dByEnv map[string][]*github.DeploymentWithCommits) [][]string {
-	var records [][]string
-
-	// Add column headers to records
-	records = append(records, []string{
-		"description",
-		"createdAt",
-		"updatedAt",
-		"originalEnvironment",
-		"latestEnvironment",
-		"task",
-		"state",
-		"abbreviatedCommitSHA",
-		"commitSHA",
-		"deployedCommits",
-	})
-
-	// Add a record for each deployment
-	for _, deploys := range dByEnv {
-		for _, d := range deploys {
-
-			var deployedSHAs []string
-			for _, commit := range d.DeployedCommits {
-				deployedSHAs = append(deployedSHAs, commit.SHA)
-			}
-
-			record := []string{
-				d.Description,
-				d.CreatedAt.Format(time.RFC3339),
-				d.UpdatedAt.Format(time.RFC3339),
-				d.OriginalEnvironment,
-				d.LatestEnvironment,
-				d.Task,
-				string(d.State),
-				string(d.Commit.AbbreviatedSHA),
-				string(d.Commit.SHA),
-				strings.Join(deployedSHAs, ","),
-			}
-			records = append(records, record)
-		}
-	}
-	return records
+func NewCSVEncoder() (*CSVEncoder, error) {
+	return &CSVEncoder{}, nil
 }
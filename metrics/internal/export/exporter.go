@@ -1,7 +1,11 @@
 package export
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
 )
 
@@ -40,3 +44,81 @@ func (f *FileExporter) Export(v interface{}) error {
 func NewFileExporter(f string, e Encoder) (*FileExporter, error) {
 	return &FileExporter{encoder: e, filename: f}, nil
 }
+
+// Appender is implemented by exporters which can add records to a previous
+// export instead of replacing it.
+type Appender interface {
+	Append(v interface{}) error
+}
+
+// Append adds the records for v to the existing file. It falls back to Export
+// if the file does not exist or is empty.
+func (f *FileExporter) Append(v interface{}) error {
+	existing, err := os.ReadFile(f.filename)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && len(bytes.TrimSpace(existing)) == 0) {
+		return f.Export(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	e, ok := f.encoder.(AppendEncoder)
+	if !ok {
+		return fmt.Errorf("appending is not supported by %T", f.encoder)
+	}
+
+	// Encode to a buffer first, so that the existing file is left untouched
+	// if the records cannot be appended.
+	var buf bytes.Buffer
+	if err := e.EncodeAppend(&buf, existing, v); err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.filename, buf.Bytes(), 0o644)
+}
+
+// Streamer is implemented by exporters which can export records in batches,
+// for example a page at a time while they are being fetched.
+type Streamer interface {
+	// CanStream reports whether the encoder of the exporter supports
+	// exporting records in batches.
+	CanStream() bool
+
+	// Stream calls fn with a function which exports a batch of records.
+	Stream(fn func(export func(v interface{}) error) error) error
+}
+
+func (s *WriterExporter) CanStream() bool {
+	return canStream(s.encoder)
+}
+
+func (s *WriterExporter) Stream(fn func(export func(v interface{}) error) error) error {
+	return fn(s.Export)
+}
+
+func (f *FileExporter) CanStream() bool {
+	return canStream(f.encoder)
+}
+
+// Stream creates the file and writes each batch of records to it.
+func (f *FileExporter) Stream(fn func(export func(v interface{}) error) error) error {
+	file, err := os.Create(f.filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = fn(func(v interface{}) error {
+		return f.encoder.Encode(file, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func canStream(e Encoder) bool {
+	s, ok := e.(StreamEncoder)
+	return ok && s.CanStream()
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldsHelp lists the available fields instead of exporting records when
+// passed as the only field.
+const FieldsHelp = "help"
+
+// CSVRecords are CSV records which are encoded as they are.
+type CSVRecords [][]string
+
+// FieldsEncoder projects records to the given fields, in the given order,
+// before encoding them with the JSON, NDJSON or CSV encoder it wraps. Fields
+// are the CSV columns of the records, so the same fields are available for
+// all encodings. JSON objects are therefore flat and keyed on the column
+// names, unlike JSON output without fields, which keeps the nested records
+// and their Go field names.
+type FieldsEncoder struct {
+	encoder Encoder
+	fields  []string
+}
+
+func NewFieldsEncoder(e Encoder, fields []string) (*FieldsEncoder, error) {
+	switch e.(type) {
+	case *JSONEcoder, *NDJSONEncoder, *CSVEncoder:
+	default:
+		return nil, fmt.Errorf("fields are only supported for 'json', 'ndjson' and 'csv' encodings")
+	}
+
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("field names cannot be empty")
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("duplicate field %q", field)
+		}
+		seen[field] = true
+	}
+
+	if seen[FieldsHelp] && len(fields) > 1 {
+		return nil, fmt.Errorf("%q cannot be combined with other fields", FieldsHelp)
+	}
+
+	return &FieldsEncoder{encoder: e, fields: fields}, nil
+}
+
+// Encode writes the records for v projected to the fields. If the fields are
+// FieldsHelp, it writes the available fields for v, one per line.
+func (f *FieldsEncoder) Encode(w io.Writer, v interface{}) error {
+	if f.help() {
+		header, _, err := fieldValues(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, strings.Join(header, "\n"))
+		return err
+	}
+
+	projected, err := f.project(v)
+	if err != nil {
+		return err
+	}
+	return f.encoder.Encode(w, projected)
+}
+
+// EncodeAppend appends the records for v projected to the fields.
+func (f *FieldsEncoder) EncodeAppend(w io.Writer, existing []byte, v interface{}) error {
+	e, ok := f.encoder.(AppendEncoder)
+	if !ok {
+		return fmt.Errorf("appending is not supported by %T", f.encoder)
+	}
+
+	projected, err := f.project(v)
+	if err != nil {
+		return err
+	}
+	return e.EncodeAppend(w, existing, projected)
+}
+
+// CanStream reports whether the wrapped encoder can encode records in
+// batches. The available fields are listed once for all records, so they are
+// never streamed.
+func (f *FieldsEncoder) CanStream() bool {
+	return !f.help() && canStream(f.encoder)
+}
+
+// help reports whether the fields are FieldsHelp.
+func (f *FieldsEncoder) help() bool {
+	return len(f.fields) == 1 && f.fields[0] == FieldsHelp
+}
+
+// project returns CSV records with the columns for the fields, or for JSON
+// and NDJSON a list of objects with a key for each field.
+func (f *FieldsEncoder) project(v interface{}) (interface{}, error) {
+	if _, ok := f.encoder.(*CSVEncoder); ok {
+		records, err := ToCSVRecords(v)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes, err := fieldIndexes(records[0], f.fields)
+		if err != nil {
+			return nil, err
+		}
+
+		projected := make(CSVRecords, 0, len(records))
+		for _, record := range records {
+			projected = append(projected, selectFields(record, indexes))
+		}
+		return projected, nil
+	}
+
+	header, rows, err := fieldValues(v)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := fieldIndexes(header, f.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*fieldsObject, 0, len(rows))
+	for _, row := range rows {
+		objects = append(objects, &fieldsObject{keys: f.fields, values: selectFields(row, indexes)})
+	}
+	return objects, nil
+}
+
+// fieldIndexes returns the index of each field in the header.
+func fieldIndexes(header []string, fields []string) ([]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+
+	indexes := make([]int, 0, len(fields))
+	for _, field := range fields {
+		i, ok := index[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q. Available fields: %s", field, strings.Join(header, ", "))
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// selectFields returns the values at the given indexes.
+func selectFields[T any](values []T, indexes []int) []T {
+	selected := make([]T, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, values[i])
+	}
+	return selected
+}
+
+// fieldValues returns the names of the fields of v, which are the columns of
+// its CSV records, and the unformatted value of each field for each record.
+func fieldValues(v interface{}) ([]string, [][]interface{}, error) {
+	if rs, ok := v.([]*RepoResult); ok {
+		header := []string{"service", "repo"}
+		var rows [][]interface{}
+
+		for i, r := range rs {
+			dataHeader, dataRows, err := fieldValues(r.Data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading fields of results for %s (%s): %w", r.Service, r.Repo, err)
+			}
+
+			// All results share the same fields, so only keep the first ones.
+			if i == 0 {
+				header = append(header, dataHeader...)
+			}
+
+			for _, row := range dataRows {
+				rows = append(rows, append([]interface{}{r.Service, r.Repo}, row...))
+			}
+		}
+
+		return header, rows, nil
+	}
+
+	columns, structs, err := csvTable(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]string, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+
+	rows := make([][]interface{}, 0, len(structs))
+	for _, s := range structs {
+		row := make([]interface{}, 0, len(columns))
+		for _, c := range columns {
+			value, err := c.raw(s)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading field %s: %w", c.name, err)
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// raw returns the value of the column for the given row, without formatting
+// it as a string. Only the key option applies.
+func (c *csvColumn) raw(row csvRow) (interface{}, error) {
+	v := c.value(row)
+	if v.IsValid() && c.opts.key != nil {
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+
+		var err error
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			v, err = sliceKeys(v, c.opts.key)
+		case reflect.Struct:
+			v, err = structKey(v, c.opts.key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if t, ok := v.Interface().(time.Time); ok && t.IsZero() {
+		return nil, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// fieldsObject is a JSON object with keys in a fixed order.
+type fieldsObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *fieldsObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("error encoding field %s as JSON: %w", key, err)
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
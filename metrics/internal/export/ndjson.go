@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+// StreamEncoder is implemented by encoders which can encode records in
+// batches, where the output for several batches is the same as the output for
+// all records at once.
+type StreamEncoder interface {
+	Encoder
+	CanStream() bool
+}
+
+// NDJSONEncoder writes one JSON object per line (also known as JSON Lines).
+// Slices are written as one line per item and results for multiple repos as
+// one line per record, tagged with the service and repo.
+type NDJSONEncoder struct{}
+
+func (n *NDJSONEncoder) Encode(w io.Writer, v interface{}) error {
+	e := json.NewEncoder(w)
+
+	if rs, ok := v.([]*RepoResult); ok {
+		for _, r := range rs {
+			err := encodeLines(r.Data, func(item interface{}) error {
+				return e.Encode(&RepoResult{Service: r.Service, Repo: r.Repo, Data: item})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return encodeLines(v, e.Encode)
+}
+
+// EncodeAppend writes the existing lines followed by the lines for v.
+func (n *NDJSONEncoder) EncodeAppend(w io.Writer, existing []byte, v interface{}) error {
+	if _, err := w.Write(existing); err != nil {
+		return err
+	}
+
+	if !bytes.HasSuffix(existing, []byte("\n")) {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return n.Encode(w, v)
+}
+
+// CanStream reports that records can be encoded in batches, because lines
+// for a batch never depend on previous batches.
+func (n *NDJSONEncoder) CanStream() bool {
+	return true
+}
+
+func NewNDJSONEncoder() (*NDJSONEncoder, error) {
+	return &NDJSONEncoder{}, nil
+}
+
+// encodeLines calls encode for each item of v if v is a slice or an array,
+// or for v itself otherwise. Deployments by environment are encoded in order
+// of environment, as each deployment includes its environment.
+func encodeLines(v interface{}, encode func(interface{}) error) error {
+	if dByEnv, ok := v.(map[string][]*github.DeploymentWithCommits); ok {
+		envs := make([]string, 0, len(dByEnv))
+		for env := range dByEnv {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
+
+		for _, env := range envs {
+			if err := encodeLines(dByEnv[env], encode); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return encode(v)
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
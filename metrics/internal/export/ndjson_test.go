@@ -0,0 +1,72 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileExporterStream(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "items.ndjson")
+
+	e, err := NewNDJSONEncoder()
+	if err != nil {
+		t.Fatalf("NewNDJSONEncoder() returned error: %v", err)
+	}
+
+	f, err := NewFileExporter(filename, e)
+	if err != nil {
+		t.Fatalf("NewFileExporter() returned error: %v", err)
+	}
+
+	if !f.CanStream() {
+		t.Fatalf("CanStream() = false, want true")
+	}
+
+	pages := [][]*item{{{Number: 1}, {Number: 2}}, {{Number: 3}}}
+
+	err = f.Stream(func(export func(v interface{}) error) error {
+		for _, page := range pages {
+			if err := export([]*RepoResult{{Service: "turtle", Repo: "hackebrot/turtle", Data: page}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unable to read exported file: %v", err)
+	}
+
+	want := `{"Service":"turtle","Repo":"hackebrot/turtle","Data":{"Number":1}}
+{"Service":"turtle","Repo":"hackebrot/turtle","Data":{"Number":2}}
+{"Service":"turtle","Repo":"hackebrot/turtle","Data":{"Number":3}}
+`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Stream() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileExporterCanStream(t *testing.T) {
+	e, err := NewJSONEncoder()
+	if err != nil {
+		t.Fatalf("NewJSONEncoder() returned error: %v", err)
+	}
+
+	f, err := NewFileExporter("items.json", e)
+	if err != nil {
+		t.Fatalf("NewFileExporter() returned error: %v", err)
+	}
+
+	// A JSON array cannot be written a page at a time.
+	if f.CanStream() {
+		t.Errorf("CanStream() = true, want false")
+	}
+}
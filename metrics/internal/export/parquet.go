@@ -0,0 +1,352 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/parquet-go/parquet-go"
+)
+
+// ParquetEncoder writes records as a Parquet file with a typed schema for each
+// model. Timestamps are stored in microseconds, which BigQuery and other data
+// warehouses load as TIMESTAMP, and zero times are stored as null. Nested
+// lists, such as the deployed commits of deployments, use the standard LIST
+// logical type. Results for multiple repos have additional service and repo
+// columns, which are null otherwise.
+type ParquetEncoder struct{}
+
+func (p *ParquetEncoder) Encode(w io.Writer, v interface{}) error {
+	results, ok := v.([]*RepoResult)
+	if !ok {
+		results = []*RepoResult{{Data: v}}
+	}
+
+	var data interface{}
+	if len(results) > 0 {
+		data = results[0].Data
+	}
+
+	switch data.(type) {
+	case []github.PullRequest:
+		return writeParquet(w, results, newParquetPullRequests)
+	case []github.Release:
+		return writeParquet(w, results, newParquetReleases)
+	case []github.Deployment:
+		return writeParquet(w, results, newParquetDeployments)
+	case map[string][]*github.DeploymentWithCommits:
+		return writeParquet(w, results, newParquetDeploymentsWithCommitsByEnv)
+	case *github.DeploymentWithCommits:
+		return writeParquet(w, results, newParquetDeploymentWithCommits)
+	case []grafana.Deployment:
+		return writeParquet(w, results, newParquetGrafanaDeployments)
+	case []*grafana.LinkedDeployment:
+		return writeParquet(w, results, newParquetLinkedDeployments)
+	default:
+		return fmt.Errorf("unable to export type %T to Parquet", data)
+	}
+}
+
+func NewParquetEncoder() (*ParquetEncoder, error) {
+	return &ParquetEncoder{}, nil
+}
+
+// writeParquet converts the data of each result to rows with fn and writes
+// them as a single Parquet file. The data of all results must be of type D.
+func writeParquet[D any, T any](w io.Writer, results []*RepoResult, fn func(r *RepoResult, data D) []T) error {
+	var rows []T
+
+	for _, r := range results {
+		data, ok := r.Data.(D)
+		if !ok {
+			return fmt.Errorf("unable to export type %T to Parquet with type %T", r.Data, *new(D))
+		}
+		rows = append(rows, fn(r, data)...)
+	}
+
+	pw := parquet.NewGenericWriter[T](w)
+
+	if _, err := pw.Write(rows); err != nil {
+		return fmt.Errorf("error writing Parquet rows: %w", err)
+	}
+
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("error writing Parquet file: %w", err)
+	}
+
+	return nil
+}
+
+type parquetPullRequest struct {
+	Service       string   `parquet:"service,optional"`
+	Repo          string   `parquet:"repo,optional"`
+	Number        int64    `parquet:"number"`
+	Title         string   `parquet:"title"`
+	Author        string   `parquet:"author"`
+	BaseRef       string   `parquet:"base_ref"`
+	HeadRef       string   `parquet:"head_ref"`
+	Labels        []string `parquet:"labels,list"`
+	Additions     int64    `parquet:"additions"`
+	Deletions     int64    `parquet:"deletions"`
+	ChangedFiles  int64    `parquet:"changed_files"`
+	Commits       int64    `parquet:"commits"`
+	Reviews       int64    `parquet:"reviews"`
+	CreatedAt     int64    `parquet:"created_at,optional,timestamp(microsecond)"`
+	UpdatedAt     int64    `parquet:"updated_at,optional,timestamp(microsecond)"`
+	ClosedAt      int64    `parquet:"closed_at,optional,timestamp(microsecond)"`
+	MergedAt      int64    `parquet:"merged_at,optional,timestamp(microsecond)"`
+	FirstCommitAt int64    `parquet:"first_commit_at,optional,timestamp(microsecond)"`
+	FirstReviewAt int64    `parquet:"first_review_at,optional,timestamp(microsecond)"`
+	ApprovedAt    int64    `parquet:"approved_at,optional,timestamp(microsecond)"`
+}
+
+// parquetTime returns the time in microseconds since the Unix epoch for
+// int64 fields with the timestamp tag. Zero times return 0, which optional
+// columns store as null, whereas zero time.Time fields are stored as year 1.
+func parquetTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMicro()
+}
+
+func newParquetPullRequests(r *RepoResult, prs []github.PullRequest) []parquetPullRequest {
+	rows := make([]parquetPullRequest, 0, len(prs))
+	for _, pr := range prs {
+		rows = append(rows, parquetPullRequest{
+			Service:       r.Service,
+			Repo:          r.Repo,
+			Number:        int64(pr.Number),
+			Title:         pr.Title,
+			Author:        pr.Author,
+			BaseRef:       pr.BaseRef,
+			HeadRef:       pr.HeadRef,
+			Labels:        pr.Labels,
+			Additions:     int64(pr.Additions),
+			Deletions:     int64(pr.Deletions),
+			ChangedFiles:  int64(pr.ChangedFiles),
+			Commits:       int64(pr.Commits),
+			Reviews:       int64(pr.Reviews),
+			CreatedAt:     parquetTime(pr.CreatedAt),
+			UpdatedAt:     parquetTime(pr.UpdatedAt),
+			ClosedAt:      parquetTime(pr.ClosedAt),
+			MergedAt:      parquetTime(pr.MergedAt),
+			FirstCommitAt: parquetTime(pr.FirstCommitAt),
+			FirstReviewAt: parquetTime(pr.FirstReviewAt),
+			ApprovedAt:    parquetTime(pr.ApprovedAt),
+		})
+	}
+	return rows
+}
+
+type parquetRelease struct {
+	Service      string `parquet:"service,optional"`
+	Repo         string `parquet:"repo,optional"`
+	Name         string `parquet:"name"`
+	TagName      string `parquet:"tag_name"`
+	IsDraft      bool   `parquet:"is_draft"`
+	IsLatest     bool   `parquet:"is_latest"`
+	IsPrerelease bool   `parquet:"is_prerelease"`
+	Description  string `parquet:"description"`
+	CreatedAt    int64  `parquet:"created_at,optional,timestamp(microsecond)"`
+	PublishedAt  int64  `parquet:"published_at,optional,timestamp(microsecond)"`
+}
+
+func newParquetReleases(r *RepoResult, releases []github.Release) []parquetRelease {
+	rows := make([]parquetRelease, 0, len(releases))
+	for _, release := range releases {
+		rows = append(rows, parquetRelease{
+			Service:      r.Service,
+			Repo:         r.Repo,
+			Name:         release.Name,
+			TagName:      release.TagName,
+			IsDraft:      release.IsDraft,
+			IsLatest:     release.IsLatest,
+			IsPrerelease: release.IsPrerelease,
+			Description:  release.Description,
+			CreatedAt:    parquetTime(release.CreatedAt),
+			PublishedAt:  parquetTime(release.PublishedAt),
+		})
+	}
+	return rows
+}
+
+type parquetCommit struct {
+	SHA            string   `parquet:"sha"`
+	AbbreviatedSHA string   `parquet:"abbreviated_sha"`
+	AuthoredDate   int64    `parquet:"authored_date,optional,timestamp(microsecond)"`
+	CommittedDate  int64    `parquet:"committed_date,optional,timestamp(microsecond)"`
+	Message        string   `parquet:"message"`
+	ParentSHAs     []string `parquet:"parent_shas,list"`
+
+	// Numbers of the merged pull requests associated with the commit
+	PRNumbers []int64 `parquet:"pr_numbers,list"`
+}
+
+func newParquetCommits(commits []*github.Commit) []parquetCommit {
+	rows := make([]parquetCommit, 0, len(commits))
+	for _, c := range commits {
+		row := parquetCommit{
+			SHA:            c.SHA,
+			AbbreviatedSHA: c.AbbreviatedSHA,
+			AuthoredDate:   parquetTime(c.AuthoredDate),
+			CommittedDate:  parquetTime(c.CommittedDate),
+			Message:        c.Message,
+		}
+		for _, p := range c.Parents {
+			row.ParentSHAs = append(row.ParentSHAs, p.SHA)
+		}
+		for _, pr := range c.PullRequests {
+			row.PRNumbers = append(row.PRNumbers, int64(pr.Number))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+type parquetDeploymentStatus struct {
+	State          string `parquet:"state"`
+	Description    string `parquet:"description"`
+	LogURL         string `parquet:"log_url"`
+	EnvironmentURL string `parquet:"environment_url"`
+	CreatedAt      int64  `parquet:"created_at,optional,timestamp(microsecond)"`
+}
+
+type parquetDeployment struct {
+	Service             string                    `parquet:"service,optional"`
+	Repo                string                    `parquet:"repo,optional"`
+	Description         string                    `parquet:"description"`
+	CreatedAt           int64                     `parquet:"created_at,optional,timestamp(microsecond)"`
+	UpdatedAt           int64                     `parquet:"updated_at,optional,timestamp(microsecond)"`
+	OriginalEnvironment string                    `parquet:"original_environment"`
+	LatestEnvironment   string                    `parquet:"latest_environment"`
+	Task                string                    `parquet:"task"`
+	State               string                    `parquet:"state"`
+	Ref                 string                    `parquet:"ref"`
+	CommitSHA           string                    `parquet:"commit_sha,optional"`
+	Statuses            []parquetDeploymentStatus `parquet:"statuses,list"`
+	DurationSeconds     *float64                  `parquet:"duration_seconds,optional"`
+	FailedAttempts      int64                     `parquet:"failed_attempts"`
+	DeployedCommits     []parquetCommit           `parquet:"deployed_commits,list"`
+}
+
+func newParquetDeployment(r *RepoResult, d *github.Deployment) parquetDeployment {
+	row := parquetDeployment{
+		Service:             r.Service,
+		Repo:                r.Repo,
+		Description:         d.Description,
+		CreatedAt:           parquetTime(d.CreatedAt),
+		UpdatedAt:           parquetTime(d.UpdatedAt),
+		OriginalEnvironment: d.OriginalEnvironment,
+		LatestEnvironment:   d.LatestEnvironment,
+		Task:                d.Task,
+		State:               d.State,
+		Ref:                 d.Ref,
+		DurationSeconds:     d.DurationSeconds,
+		FailedAttempts:      int64(d.FailedAttempts),
+	}
+	if d.Commit != nil {
+		row.CommitSHA = d.Commit.SHA
+	}
+	for _, s := range d.Statuses {
+		row.Statuses = append(row.Statuses, parquetDeploymentStatus{
+			State:          s.State,
+			Description:    s.Description,
+			LogURL:         s.LogURL,
+			EnvironmentURL: s.EnvironmentURL,
+			CreatedAt:      parquetTime(s.CreatedAt),
+		})
+	}
+	return row
+}
+
+func newParquetDeployments(r *RepoResult, deployments []github.Deployment) []parquetDeployment {
+	rows := make([]parquetDeployment, 0, len(deployments))
+	for i := range deployments {
+		rows = append(rows, newParquetDeployment(r, &deployments[i]))
+	}
+	return rows
+}
+
+func newParquetDeploymentWithCommits(r *RepoResult, d *github.DeploymentWithCommits) []parquetDeployment {
+	row := newParquetDeployment(r, d.Deployment)
+	row.DeployedCommits = newParquetCommits(d.DeployedCommits)
+	return []parquetDeployment{row}
+}
+
+// newParquetDeploymentsWithCommitsByEnv returns the rows for the deployments
+// of each environment, in order of environment.
+func newParquetDeploymentsWithCommitsByEnv(r *RepoResult, dByEnv map[string][]*github.DeploymentWithCommits) []parquetDeployment {
+	envs := make([]string, 0, len(dByEnv))
+	for env := range dByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	var rows []parquetDeployment
+	for _, env := range envs {
+		for _, d := range dByEnv[env] {
+			rows = append(rows, newParquetDeploymentWithCommits(r, d)...)
+		}
+	}
+	return rows
+}
+
+type parquetGrafanaDeployment struct {
+	ImageRepo   string   `parquet:"image_repo,optional"`
+	ImageTag    string   `parquet:"image_tag,optional"`
+	CreatedAt   int64    `parquet:"created_at,optional,timestamp(microsecond)"`
+	UpdatedAt   int64    `parquet:"updated_at,optional,timestamp(microsecond)"`
+	Env         string   `parquet:"env"`
+	Canary      bool     `parquet:"canary"`
+	ParseErrors []string `parquet:"parse_errors,list"`
+
+	// Only set for deployments linked to GitHub with --commits
+	Ref             string          `parquet:"ref,optional"`
+	ReleaseTagName  string          `parquet:"release_tag_name,optional"`
+	CommitSHA       string          `parquet:"commit_sha,optional"`
+	DeployedCommits []parquetCommit `parquet:"deployed_commits,list"`
+}
+
+func newParquetGrafanaDeployment(d *grafana.Deployment) parquetGrafanaDeployment {
+	row := parquetGrafanaDeployment{
+		CreatedAt:   parquetTime(d.CreatedAt),
+		UpdatedAt:   parquetTime(d.UpdatedAt),
+		Env:         d.Env,
+		Canary:      d.Canary,
+		ParseErrors: d.ParseErrors,
+	}
+	if d.DockerImage != nil {
+		row.ImageRepo = d.DockerImage.Repo
+		row.ImageTag = d.DockerImage.Tag
+	}
+	return row
+}
+
+func newParquetGrafanaDeployments(r *RepoResult, deployments []grafana.Deployment) []parquetGrafanaDeployment {
+	rows := make([]parquetGrafanaDeployment, 0, len(deployments))
+	for i := range deployments {
+		rows = append(rows, newParquetGrafanaDeployment(&deployments[i]))
+	}
+	return rows
+}
+
+func newParquetLinkedDeployments(r *RepoResult, deployments []*grafana.LinkedDeployment) []parquetGrafanaDeployment {
+	rows := make([]parquetGrafanaDeployment, 0, len(deployments))
+	for _, d := range deployments {
+		row := newParquetGrafanaDeployment(d.Deployment)
+		row.Ref = d.Ref
+		if d.Release != nil {
+			row.ReleaseTagName = d.Release.TagName
+		}
+		if d.Commit != nil {
+			row.CommitSHA = d.Commit.SHA
+		}
+		row.DeployedCommits = newParquetCommits(d.DeployedCommits)
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,264 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/parquet-go/parquet-go"
+)
+
+// encodeParquet encodes v and reads the rows back with the schema of T.
+func encodeParquet[T any](t *testing.T, v interface{}) ([]T, *parquet.File) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := (&ParquetEncoder{}).Encode(&buf, v); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+
+	data := bytes.NewReader(buf.Bytes())
+
+	rows, err := parquet.Read[T](data, data.Size())
+	if err != nil {
+		t.Fatalf("unable to read Parquet rows: %v", err)
+	}
+
+	// Open the file without a Go type, like other Parquet tools do.
+	f, err := parquet.OpenFile(data, data.Size())
+	if err != nil {
+		t.Fatalf("unable to open Parquet file: %v", err)
+	}
+
+	return rows, f
+}
+
+// wantColumnType checks the type of a top-level column in the file schema.
+func wantColumnType(t *testing.T, f *parquet.File, column string, want string) {
+	t.Helper()
+
+	for _, field := range f.Schema().Fields() {
+		if field.Name() != column {
+			continue
+		}
+		if got := field.Type().String(); got != want {
+			t.Errorf("type of column %s = %s, want %s", column, got, want)
+		}
+		return
+	}
+
+	t.Fatalf("column %s not found in schema:\n%s", column, f.Schema())
+}
+
+// wantListColumn checks that a column has the LIST logical type in the file
+// metadata, which tools need to read it as a list instead of a nested group.
+func wantListColumn(t *testing.T, f *parquet.File, column string) {
+	t.Helper()
+
+	for _, e := range f.Metadata().Schema {
+		if e.Name != column {
+			continue
+		}
+		if e.LogicalType == nil || e.LogicalType.List == nil {
+			t.Errorf("logical type of column %s = %v, want LIST", column, e.LogicalType)
+		}
+		return
+	}
+
+	t.Fatalf("column %s not found in schema:\n%s", column, f.Schema())
+}
+
+func TestParquetPullRequests(t *testing.T) {
+	mergedAt := time.Date(2023, time.September, 10, 7, 24, 16, 0, time.UTC)
+
+	prs := []github.PullRequest{{
+		Number:    1,
+		Title:     "Set up CI/CD workflow",
+		Author:    "hackebrot",
+		Labels:    []string{"ci", "infra"},
+		Additions: 120,
+		CreatedAt: mergedAt.Add(-48 * time.Hour),
+		MergedAt:  mergedAt,
+	}, {
+		// Zero times are stored as null.
+		Number: 2,
+		Title:  "Open PR",
+	}}
+
+	got, f := encodeParquet[parquetPullRequest](t, []*RepoResult{{Service: "turtle", Repo: "hackebrot/turtle", Data: prs}})
+
+	want := []parquetPullRequest{{
+		Service:   "turtle",
+		Repo:      "hackebrot/turtle",
+		Number:    1,
+		Title:     "Set up CI/CD workflow",
+		Author:    "hackebrot",
+		Labels:    []string{"ci", "infra"},
+		Additions: 120,
+		CreatedAt: parquetTime(mergedAt.Add(-48 * time.Hour)),
+		MergedAt:  parquetTime(mergedAt),
+	}, {
+		Service: "turtle",
+		Repo:    "hackebrot/turtle",
+		Number:  2,
+		Title:   "Open PR",
+	}}
+
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+
+	mergedAtColumn, _ := f.Schema().Lookup("merged_at")
+
+	rows := make([]parquet.Row, 2)
+	if n, _ := f.RowGroups()[0].Rows().ReadRows(rows); n != 2 {
+		t.Fatalf("read %d rows, want 2", n)
+	}
+	rows[1].Range(func(columnIndex int, values []parquet.Value) bool {
+		if columnIndex == mergedAtColumn.ColumnIndex && !values[0].IsNull() {
+			t.Errorf("merged_at of PR without merge time = %v, want null", values[0])
+		}
+		return true
+	})
+
+	wantColumnType(t, f, "merged_at", "TIMESTAMP(isAdjustedToUTC=true,unit=MICROS)")
+	wantColumnType(t, f, "number", "INT(64,true)")
+	wantListColumn(t, f, "labels")
+}
+
+func TestParquetReleases(t *testing.T) {
+	createdAt := time.Date(2020, time.May, 4, 14, 55, 36, 0, time.UTC)
+
+	releases := []github.Release{{
+		Name:      "20.1.0",
+		TagName:   "20.1.0",
+		IsLatest:  true,
+		CreatedAt: createdAt,
+	}}
+
+	got, f := encodeParquet[parquetRelease](t, releases)
+
+	// Results for a single repo have no service and repo.
+	want := []parquetRelease{{
+		Name:      "20.1.0",
+		TagName:   "20.1.0",
+		IsLatest:  true,
+		CreatedAt: parquetTime(createdAt),
+	}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+
+	wantColumnType(t, f, "is_latest", "BOOLEAN")
+	wantColumnType(t, f, "repo", "STRING")
+}
+
+func TestParquetDeploymentsWithCommits(t *testing.T) {
+	createdAt := time.Date(2022, time.May, 2, 20, 25, 5, 0, time.UTC)
+	duration := 90.5
+
+	commit := &github.Commit{
+		SHA:            "abc123abc123",
+		AbbreviatedSHA: "abc123",
+		CommittedDate:  createdAt.Add(-time.Hour),
+		Message:        "Fix bug",
+		Parents:        []*github.CommitParent{{SHA: "def456def456"}},
+		PullRequests:   []github.PullRequest{{Number: 12}},
+	}
+
+	dByEnv := map[string][]*github.DeploymentWithCommits{
+		"stage": {{Deployment: &github.Deployment{
+			OriginalEnvironment: "stage",
+			CreatedAt:           createdAt.Add(-time.Minute),
+		}}},
+		"prod": {{
+			Deployment: &github.Deployment{
+				OriginalEnvironment: "prod",
+				CreatedAt:           createdAt,
+				State:               "ACTIVE",
+				Commit:              commit,
+				Statuses:            []github.DeploymentStatus{{State: "SUCCESS", LogURL: "https://example.com/log", CreatedAt: createdAt}},
+				DurationSeconds:     &duration,
+				FailedAttempts:      1,
+			},
+			DeployedCommits: []*github.Commit{commit},
+		}},
+	}
+
+	got, f := encodeParquet[parquetDeployment](t, dByEnv)
+
+	// Deployments are ordered by environment.
+	want := []parquetDeployment{{
+		OriginalEnvironment: "prod",
+		CreatedAt:           parquetTime(createdAt),
+		State:               "ACTIVE",
+		CommitSHA:           "abc123abc123",
+		Statuses:            []parquetDeploymentStatus{{State: "SUCCESS", LogURL: "https://example.com/log", CreatedAt: parquetTime(createdAt)}},
+		DurationSeconds:     &duration,
+		FailedAttempts:      1,
+		DeployedCommits: []parquetCommit{{
+			SHA:            "abc123abc123",
+			AbbreviatedSHA: "abc123",
+			CommittedDate:  parquetTime(createdAt.Add(-time.Hour)),
+			Message:        "Fix bug",
+			ParentSHAs:     []string{"def456def456"},
+			PRNumbers:      []int64{12},
+		}},
+	}, {
+		OriginalEnvironment: "stage",
+		CreatedAt:           parquetTime(createdAt.Add(-time.Minute)),
+	}}
+
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+
+	wantListColumn(t, f, "deployed_commits")
+	wantColumnType(t, f, "duration_seconds", "DOUBLE")
+}
+
+func TestParquetGrafanaDeployments(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	deployments := []grafana.Deployment{{
+		DockerImage: &grafana.DockerImage{Repo: "hackebrot/turtle", Tag: "v1.0.0"},
+		CreatedAt:   createdAt,
+		Env:         "prod",
+		Canary:      true,
+	}, {
+		CreatedAt:   createdAt,
+		ParseErrors: []string{"docker image not found"},
+	}}
+
+	got, _ := encodeParquet[parquetGrafanaDeployment](t, deployments)
+
+	want := []parquetGrafanaDeployment{{
+		ImageRepo: "hackebrot/turtle",
+		ImageTag:  "v1.0.0",
+		CreatedAt: parquetTime(createdAt),
+		Env:       "prod",
+		Canary:    true,
+	}, {
+		CreatedAt:   parquetTime(createdAt),
+		ParseErrors: []string{"docker image not found"},
+	}}
+
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParquetUnsupportedType(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := (&ParquetEncoder{}).Encode(&buf, &github.CommitsComparison{})
+	if err == nil || !strings.Contains(err.Error(), "unable to export type *github.CommitsComparison to Parquet") {
+		t.Errorf("Encode() error = %v, want unsupported type", err)
+	}
+}
@@ -0,0 +1,668 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MarshalCSVRecords converts a struct, a slice of structs or a map of slices
+// of structs to CSV records, including a header row. Each exported field is a
+// column and columns are in the order of the fields. The column of a field
+// can be configured with a "csv" struct tag, which holds the column name
+// followed by options:
+//
+//	Name     string    `csv:"-"`                        // skip the field
+//	SHA      string    `csv:"commitSHA"`                // rename the column
+//	Week     time.Time `csv:",date"`                    // format times as YYYY-MM-DD
+//	Seconds  float64   `csv:",prec=0"`                  // format floats with 0 decimals
+//	Message  string    `csv:",firstline"`               // keep the first line only
+//	PRs      []PR      `csv:"prs,key=Number"`           // use a field of each element
+//	Commit   *Commit   `csv:"commitSHA,key=SHA"`        // use a field of the struct
+//	Commit   *Commit   `csv:",json"`                    // encode the value as JSON
+//	Metrics  *Metrics  `csv:",inline"`                  // add columns for its fields
+//	Commits  []*Commit `csv:",rows"`                    // add a row for each element
+//	_        struct{}  `csv:"sha,key=Commit.SHA"`       // add a derived column
+//
+// The key option holds a path of fields separated by dots, such as
+// "Commit.SHA". Each part of the path names a field or a method without
+// arguments, so that columns can be derived from several fields. Blank fields
+// add a column for the key of the struct they are declared in, which places
+// derived columns among the other columns.
+//
+// Fields of nested structs are added as columns named after the field and the
+// nested field, such as "cycleTimeCount" for the Count field of a CycleTime
+// field. Embedded structs and structs with the inline option add columns
+// without a prefix, unless the tag sets a name. Slices, maps and structs which
+// cannot be flattened are encoded as JSON, so lists are always JSON arrays.
+// Times are formatted as RFC 3339 and floats with 2 decimals by default. Nil
+// pointers and zero times result in empty columns.
+//
+// Structs with fields which have the rows option, such as reports, result in a
+// row for each element of these fields, in the order of the fields. The
+// columns of the elements take the place of the first of these fields and the
+// columns of the other fields are repeated on each row. Maps result in the
+// rows of their values, sorted by key.
+//
+// Types whose columns are not in the order of their fields implement a
+// CSVColumns method, which returns the names of the columns in order. Columns
+// which are not listed are left out.
+func MarshalCSVRecords(v interface{}) ([][]string, error) {
+	columns, rows, err := csvTable(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+
+	// Add column headers to records
+	header := make([]string, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	records = append(records, header)
+
+	// Add a record for each row
+	for _, row := range rows {
+		record := make([]string, 0, len(columns))
+		for _, c := range columns {
+			value, err := c.format(row)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding column %s: %w", c.name, err)
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// csvRow holds the structs a row is read from. The first struct is the one
+// which was passed to MarshalCSVRecords, or an element of it, and each
+// following struct is an element of a field with the rows option of the
+// struct before it.
+type csvRow []reflect.Value
+
+// csvTable returns the columns for a struct, a slice of structs or a map of
+// slices of structs along with the rows for them.
+func csvTable(v interface{}) ([]csvColumn, []csvRow, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	var items []reflect.Value
+	var t reflect.Type
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		t = rv.Type()
+		items = append(items, rv)
+	case reflect.Slice, reflect.Array:
+		t = rv.Type().Elem()
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i))
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if k := rv.Type().Elem().Kind(); k != reflect.Slice && k != reflect.Array {
+			break
+		}
+		t = rv.Type().Elem().Elem()
+
+		// Sort keys for a stable order of rows
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			values := rv.MapIndex(key)
+			for i := 0; i < values.Len(); i++ {
+				items = append(items, values.Index(i))
+			}
+		}
+	}
+
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct || t == timeType {
+		return nil, nil, fmt.Errorf("unable to export type %T to CSV", v)
+	}
+
+	ct, err := csvTypeFor(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []csvRow
+	for _, item := range items {
+		rows = ct.appendRows(rows, nil, item)
+	}
+
+	return ct.columns, rows, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvOptions holds the options of a csv struct tag.
+type csvOptions struct {
+	inline    bool
+	json      bool
+	rows      bool
+	date      bool
+	firstline bool
+	prec      int
+	key       []string
+}
+
+// csvColumn is a column for a field of a struct, which may be nested in other
+// structs. The level is the index of the struct in the row.
+type csvColumn struct {
+	name  string
+	level int
+	index []int
+	opts  csvOptions
+}
+
+// csvType holds the columns for a struct type along with the fields which
+// hold its rows, if any.
+type csvType struct {
+	columns []csvColumn
+
+	// Indices of the fields with the rows option and the type of their
+	// elements
+	rows []int
+	elem *csvType
+}
+
+// csvColumnOrder is implemented by types which set the order of their
+// columns.
+type csvColumnOrder interface {
+	CSVColumns() []string
+}
+
+// csvTypesCache holds the csvType for each struct type.
+var csvTypesCache sync.Map
+
+// csvTypeFor returns the csvType for the given struct type.
+func csvTypeFor(t reflect.Type) (*csvType, error) {
+	if ct, ok := csvTypesCache.Load(t); ok {
+		return ct.(*csvType), nil
+	}
+
+	ct := &csvType{}
+	if err := ct.appendColumns(t, "", nil, map[reflect.Type]bool{t: true}); err != nil {
+		return nil, fmt.Errorf("invalid csv struct tag for %s: %w", t, err)
+	}
+
+	if o, ok := reflect.New(t).Interface().(csvColumnOrder); ok {
+		columns, err := orderCSVColumns(ct.columns, o.CSVColumns())
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv columns for %s: %w", t, err)
+		}
+		ct.columns = columns
+	}
+
+	// Keep the first csvType stored for t, so that types can be compared.
+	actual, _ := csvTypesCache.LoadOrStore(t, ct)
+	return actual.(*csvType), nil
+}
+
+// appendColumns appends a column for each field of the struct type t. Fields
+// of nested structs are added recursively, unless the struct is already being
+// flattened, which would never end.
+func (ct *csvType) appendColumns(t reflect.Type, prefix string, index []int, flattening map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && f.Name != "_" {
+			continue
+		}
+
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, err := parseCSVTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		// Blank fields add a derived column for the struct they are
+		// declared in.
+		if f.Name == "_" {
+			if tag == "" {
+				continue
+			}
+			if name == "" || opts.key == nil {
+				return fmt.Errorf("blank field requires a column name and a key")
+			}
+			ct.columns = append(ct.columns, csvColumn{
+				name:  columnName(prefix, name),
+				index: append([]int{}, index...),
+				opts:  opts,
+			})
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if opts.rows {
+			if err := ct.appendRowsField(ft, fieldIndex); err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			continue
+		}
+
+		if ft.Kind() == reflect.Struct && ft != timeType && !opts.json && opts.key == nil && !flattening[ft] {
+			fieldPrefix := prefix
+			switch {
+			case name != "":
+				fieldPrefix = columnName(prefix, name)
+			case !f.Anonymous && !opts.inline:
+				fieldPrefix = columnName(prefix, lowerCamel(f.Name))
+			}
+
+			flattening[ft] = true
+			if err := ct.appendColumns(ft, fieldPrefix, fieldIndex, flattening); err != nil {
+				return err
+			}
+			delete(flattening, ft)
+			continue
+		}
+
+		if name == "" {
+			name = lowerCamel(f.Name)
+		}
+
+		ct.columns = append(ct.columns, csvColumn{
+			name:  columnName(prefix, name),
+			index: fieldIndex,
+			opts:  opts,
+		})
+	}
+
+	return nil
+}
+
+// appendRowsField adds the field with the given index, which holds a struct
+// or a slice of structs of type ft, to the fields with rows. The columns of
+// the structs are added for the first of these fields.
+func (ct *csvType) appendRowsField(ft reflect.Type, index []int) error {
+	if len(index) != 1 {
+		return fmt.Errorf("rows option is only supported for fields of the exported struct")
+	}
+
+	if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+		ft = ft.Elem()
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+	}
+	if ft.Kind() != reflect.Struct || ft == timeType {
+		return fmt.Errorf("rows option requires a struct or a slice of structs, got %s", ft)
+	}
+
+	elem, err := csvTypeFor(ft)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case ct.elem == nil:
+		ct.elem = elem
+		for _, c := range elem.columns {
+			c.level++
+			ct.columns = append(ct.columns, c)
+		}
+	case ct.elem != elem:
+		return fmt.Errorf("rows option requires the same type for all fields")
+	}
+
+	ct.rows = append(ct.rows, index[0])
+	return nil
+}
+
+// appendRows appends the rows for the struct v to rows, each of them prefixed
+// with the structs of the rows v is an element of.
+func (ct *csvType) appendRows(rows []csvRow, parents csvRow, v reflect.Value) []csvRow {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return rows
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return rows
+	}
+
+	row := append(append(csvRow{}, parents...), v)
+	if ct.elem == nil {
+		return append(rows, row)
+	}
+
+	for _, i := range ct.rows {
+		f := v.Field(i)
+		for f.Kind() == reflect.Pointer && !f.IsNil() {
+			f = f.Elem()
+		}
+
+		switch f.Kind() {
+		case reflect.Slice, reflect.Array:
+			for j := 0; j < f.Len(); j++ {
+				rows = ct.elem.appendRows(rows, row, f.Index(j))
+			}
+		default:
+			rows = ct.elem.appendRows(rows, row, f)
+		}
+	}
+
+	return rows
+}
+
+// orderCSVColumns returns the columns with the given names, in the order of
+// the names.
+func orderCSVColumns(columns []csvColumn, names []string) ([]csvColumn, error) {
+	byName := make(map[string]csvColumn, len(columns))
+	for _, c := range columns {
+		byName[c.name] = c
+	}
+
+	ordered := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		ordered = append(ordered, c)
+	}
+
+	return ordered, nil
+}
+
+// parseCSVTag returns the column name and the options of a csv struct tag.
+func parseCSVTag(tag string) (string, csvOptions, error) {
+	opts := csvOptions{prec: 2}
+
+	name, rest, _ := strings.Cut(tag, ",")
+	if rest == "" {
+		return name, opts, nil
+	}
+
+	for _, opt := range strings.Split(rest, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "inline":
+			opts.inline = true
+		case "json":
+			opts.json = true
+		case "rows":
+			opts.rows = true
+		case "date":
+			opts.date = true
+		case "firstline":
+			opts.firstline = true
+		case "prec":
+			prec, err := strconv.Atoi(value)
+			if err != nil || prec < 0 {
+				return "", opts, fmt.Errorf("invalid precision %q", value)
+			}
+			opts.prec = prec
+		case "key":
+			path := strings.Split(value, ".")
+			for _, p := range path {
+				if p == "" {
+					return "", opts, fmt.Errorf("invalid key %q", value)
+				}
+			}
+			opts.key = path
+		default:
+			return "", opts, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+
+	return name, opts, nil
+}
+
+// columnName returns the name for a column of a nested struct.
+func columnName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return prefix + string(r)
+}
+
+// lowerCamel converts the name of a Go field to a column name, such as
+// "createdAt" for CreatedAt, "sha" for SHA and "urlPath" for URLPath.
+func lowerCamel(s string) string {
+	r := []rune(s)
+
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+
+	// Keep the first letter of the next word in upper case.
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n--
+	}
+
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// value returns the field of the column for the given row, or the struct the
+// field is declared in for blank fields. The value is invalid if a struct the
+// field is nested in is a nil pointer.
+func (c *csvColumn) value(row csvRow) reflect.Value {
+	v := row[c.level]
+	for _, i := range c.index {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// format returns the value of the column for the given row.
+func (c *csvColumn) format(row csvRow) (string, error) {
+	v := c.value(row)
+	if !v.IsValid() {
+		return "", nil
+	}
+	return formatCSVValue(v, c.opts)
+}
+
+// formatCSVValue formats a value according to the given options.
+func formatCSVValue(v reflect.Value, opts csvOptions) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if opts.key != nil && v.Kind() == reflect.Struct {
+		field, err := structKey(v, opts.key)
+		if err != nil {
+			return "", err
+		}
+		if !field.IsValid() {
+			return "", nil
+		}
+		fieldOpts := opts
+		fieldOpts.key = nil
+		return formatCSVValue(field, fieldOpts)
+	}
+
+	if !opts.json {
+		if s, ok := formatCSVScalar(v, opts); ok {
+			return s, nil
+		}
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		if opts.key != nil {
+			values, err := sliceKeys(v, opts.key)
+			if err != nil {
+				return "", err
+			}
+			v = values
+		}
+
+		// Encode nil slices as empty JSON arrays rather than null.
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return "[]", nil
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", fmt.Errorf("error encoding %s as JSON: %w", v.Type(), err)
+	}
+	return string(data), nil
+}
+
+// formatCSVScalar formats times, strings, bools and numbers. It returns false
+// for values of other types.
+func formatCSVScalar(v reflect.Value, opts csvOptions) (string, bool) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", true
+		}
+		if opts.date {
+			return t.Format(time.DateOnly), true
+		}
+		return t.Format(time.RFC3339), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if opts.firstline {
+			line, _, _ := strings.Cut(v.String(), "\n")
+			return strings.TrimSpace(line), true
+		}
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', opts.prec, 64), true
+	default:
+		return "", false
+	}
+}
+
+// sliceKeys returns a slice with the value of the given key for each element
+// of a slice of structs.
+func sliceKeys(v reflect.Value, key []string) (reflect.Value, error) {
+	values := make([]interface{}, 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Pointer && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Pointer {
+			values = append(values, nil)
+			continue
+		}
+		if elem.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("key option requires a slice of structs, got %s", v.Type())
+		}
+
+		field, err := structKey(elem, key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !field.IsValid() {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, field.Interface())
+	}
+
+	return reflect.ValueOf(values), nil
+}
+
+// structKey returns the value at the given path of fields and methods without
+// arguments of a struct. The value is invalid if the path passes through a nil
+// pointer.
+func structKey(v reflect.Value, key []string) (reflect.Value, error) {
+	for _, name := range key {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("key %s requires a struct, got %s", strings.Join(key, "."), v.Type())
+		}
+
+		if f, ok := v.Type().FieldByName(name); ok {
+			field, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				// An embedded struct is a nil pointer.
+				return reflect.Value{}, nil
+			}
+			v = field
+			continue
+		}
+
+		method, err := structMethod(v, name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v = method.Call(nil)[0]
+	}
+
+	return v, nil
+}
+
+// structMethod returns the method of the struct v with the given name, which
+// must take no arguments and return a single value. Methods with pointer
+// receivers are called on a copy of v if v is not addressable.
+func structMethod(v reflect.Value, name string) (reflect.Value, error) {
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+
+	method := v.Addr().MethodByName(name)
+	if !method.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%s has no field or method %s", v.Type(), name)
+	}
+	if mt := method.Type(); mt.NumIn() != 0 || mt.NumOut() != 1 {
+		return reflect.Value{}, fmt.Errorf("method %s of %s must take no arguments and return a single value", name, v.Type())
+	}
+
+	return method, nil
+}
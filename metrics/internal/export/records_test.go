@@ -0,0 +1,278 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type item struct {
+	Number int
+}
+
+type percentiles struct {
+	Count    int
+	P50Hours float64
+}
+
+type node struct {
+	Name   string
+	Parent *node
+}
+
+// Fields of unexported embedded structs are not exported.
+type base struct {
+	ID string
+}
+
+type record struct {
+	base
+	Name      string
+	URLPath   string
+	Hidden    string `csv:"-"`
+	Renamed   string `csv:"title"`
+	CreatedAt time.Time
+	Week      time.Time `csv:",date"`
+	Seconds   *float64  `csv:",prec=0"`
+	Hours     *float64
+	Labels    []string
+	Items     []*item `csv:",key=Number"`
+	Review    percentiles
+	Pickup    *percentiles `csv:"pickupTime"`
+	Metrics   *percentiles `csv:",inline"`
+	Item      *item        `csv:",json"`
+	Message   string       `csv:",firstline"`
+	Owner     *item        `csv:"ownerNumber,key=Number"`
+
+	// The parent of the parent is encoded as JSON, because flattening it
+	// would never end.
+	Parent *node
+}
+
+func TestMarshalCSVRecords(t *testing.T) {
+	seconds, hours := 90.6, 1.5
+	at := time.Date(2024, time.May, 6, 12, 30, 0, 0, time.UTC)
+
+	records := []*record{{
+		base:      base{ID: "ignored"},
+		Name:      "full",
+		URLPath:   "/a,b",
+		Hidden:    "hidden",
+		Renamed:   "renamed",
+		CreatedAt: at,
+		Week:      at,
+		Seconds:   &seconds,
+		Hours:     &hours,
+		Labels:    []string{"bug", "ui"},
+		Items:     []*item{{Number: 1}, {Number: 2}},
+		Review:    percentiles{Count: 2, P50Hours: 0.25},
+		Pickup:    &percentiles{Count: 1},
+		Metrics:   &percentiles{Count: 3, P50Hours: 4},
+		Item:      &item{Number: 5},
+		Message:   " Fix bug\n\nDetails",
+		Owner:     &item{Number: 6},
+		Parent:    &node{Name: "parent", Parent: &node{Name: "grandparent"}},
+	}, {
+		// Nil pointers and zero times result in empty columns and nil
+		// slices in empty JSON arrays.
+		Name: "empty",
+	}}
+
+	got, err := MarshalCSVRecords(records)
+	if err != nil {
+		t.Fatalf("MarshalCSVRecords() returned error: %v", err)
+	}
+
+	want := [][]string{{
+		"name", "urlPath", "title", "createdAt", "week", "seconds", "hours",
+		"labels", "items",
+		"reviewCount", "reviewP50Hours", "pickupTimeCount", "pickupTimeP50Hours",
+		"count", "p50Hours", "item", "message", "ownerNumber", "parentName", "parentParent",
+	}, {
+		"full", "/a,b", "renamed", "2024-05-06T12:30:00Z", "2024-05-06", "91", "1.50",
+		`["bug","ui"]`, "[1,2]",
+		"2", "0.25", "1", "0.00",
+		"3", "4.00", `{"Number":5}`, "Fix bug", "6", "parent", `{"Name":"grandparent","Parent":null}`,
+	}, {
+		"empty", "", "", "", "", "", "",
+		"[]", "[]",
+		"0", "0.00", "", "",
+		"", "", "", "", "", "", "",
+	}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalCSVRecords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalCSVRecordsStruct(t *testing.T) {
+	got, err := MarshalCSVRecords(&item{Number: 7})
+	if err != nil {
+		t.Fatalf("MarshalCSVRecords() returned error: %v", err)
+	}
+
+	want := [][]string{{"number"}, {"7"}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalCSVRecords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type owner struct {
+	Name  string
+	Items []*item
+}
+
+// Count is a derived column of the owner.
+func (o *owner) Count() int {
+	return len(o.Items)
+}
+
+type entry struct {
+	Owner *owner   `csv:"-"`
+	_     struct{} `csv:"ownerName,key=Owner.Name"`
+	_     struct{} `csv:"ownerCount,key=Owner.Count"`
+	Value int
+}
+
+type report struct {
+	Name    string
+	Total   *entry   `csv:",rows"`
+	Entries []*entry `csv:",rows"`
+	Hidden  []*entry `csv:"-"`
+}
+
+func TestMarshalCSVRecordsRows(t *testing.T) {
+	o := &owner{Name: "turtle", Items: []*item{{Number: 1}, {Number: 2}}}
+
+	got, err := MarshalCSVRecords(&report{
+		Name:    "weekly",
+		Total:   &entry{Owner: o, Value: 3},
+		Entries: []*entry{{Owner: o, Value: 1}, nil, {Value: 2}},
+		Hidden:  []*entry{{Value: 4}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalCSVRecords() returned error: %v", err)
+	}
+
+	// The struct results in a row for each element of the fields with the
+	// rows option. Keys through nil pointers result in empty columns.
+	want := [][]string{
+		{"name", "ownerName", "ownerCount", "value"},
+		{"weekly", "turtle", "2", "3"},
+		{"weekly", "turtle", "2", "1"},
+		{"weekly", "", "", "2"},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalCSVRecords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalCSVRecordsMap(t *testing.T) {
+	got, err := MarshalCSVRecords(map[string][]*item{
+		"b": {{Number: 3}},
+		"a": {{Number: 1}, {Number: 2}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalCSVRecords() returned error: %v", err)
+	}
+
+	// Maps result in the rows of their values, sorted by key.
+	want := [][]string{{"number"}, {"1"}, {"2"}, {"3"}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalCSVRecords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type ordered struct {
+	Name   string
+	Number int
+	Hidden string
+}
+
+func (*ordered) CSVColumns() []string {
+	return []string{"number", "name"}
+}
+
+type misordered struct {
+	Name string
+}
+
+func (*misordered) CSVColumns() []string {
+	return []string{"title"}
+}
+
+func TestMarshalCSVRecordsOrder(t *testing.T) {
+	got, err := MarshalCSVRecords([]ordered{{Name: "a", Number: 1, Hidden: "x"}})
+	if err != nil {
+		t.Fatalf("MarshalCSVRecords() returned error: %v", err)
+	}
+
+	// Columns are in the given order and columns which are not listed are
+	// left out.
+	want := [][]string{{"number", "name"}, {"1", "a"}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalCSVRecords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalCSVRecordsErrors(t *testing.T) {
+	type invalid struct {
+		Seconds float64 `csv:",prec=x"`
+	}
+
+	type unknown struct {
+		Name string `csv:",upper"`
+	}
+
+	type join struct {
+		Labels []string `csv:",join"`
+	}
+
+	type blank struct {
+		_ struct{} `csv:"name"`
+	}
+
+	type missing struct {
+		Item *item
+		_    struct{} `csv:"title,key=Item.Title"`
+	}
+
+	type mixed struct {
+		Items  []*item  `csv:",rows"`
+		Owners []*owner `csv:",rows"`
+	}
+
+	tests := []struct {
+		name        string
+		v           interface{}
+		errContains string
+	}{
+		{name: "string", v: "hello", errContains: "unable to export type string to CSV"},
+		{name: "ints", v: []int{1, 2}, errContains: "unable to export type []int to CSV"},
+		{name: "prec", v: []invalid{{}}, errContains: `invalid precision "x"`},
+		{name: "option", v: []unknown{{}}, errContains: `unknown option "upper"`},
+		{name: "order", v: []misordered{{}}, errContains: `unknown column "title"`},
+		{name: "join", v: []join{{}}, errContains: `unknown option "join"`},
+		{name: "blank", v: []blank{{}}, errContains: "blank field requires a column name and a key"},
+		{name: "key", v: []missing{{Item: &item{}}}, errContains: "has no field or method Title"},
+		{name: "rows", v: mixed{}, errContains: "rows option requires the same type for all fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MarshalCSVRecords(tt.v)
+			if err == nil {
+				t.Fatalf("MarshalCSVRecords() returned no error, want %q", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("MarshalCSVRecords() error = %q, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package export
+
+import "fmt"
+
+// RepoResult tags the result of a query with the service and the GitHub repo
+// it was run against, when querying multiple repos from a service catalog.
+type RepoResult struct {
+	Service string
+	Repo    string
+	Data    interface{}
+}
+
+// RepoResultsToCSVRecords converts the data of each result to CSV records and
+// prefixes them with service and repo columns.
+func RepoResultsToCSVRecords(rs []*RepoResult) ([][]string, error) {
+	var records [][]string
+
+	for _, r := range rs {
+		dataRecords, err := ToCSVRecords(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error converting results for %s (%s) to CSV: %w", r.Service, r.Repo, err)
+		}
+
+		for i, record := range dataRecords {
+			// All results share the same header, so only keep the first one.
+			if i == 0 {
+				if len(records) == 0 {
+					records = append(records, append([]string{"service", "repo"}, record...))
+				}
+				continue
+			}
+			records = append(records, append([]string{r.Service, r.Repo}, record...))
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,578 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	_ "modernc.org/sqlite"
+)
+
+// SourceKeyedExporter is implemented by exporters which key records by the
+// GitHub repo or Grafana app they were retrieved from. Commands pass results
+// to them as []*RepoResult or *AppResult, even for a single repo or app.
+type SourceKeyedExporter interface {
+	Exporter
+	keysBySource()
+}
+
+// AppResult holds the result of a query for a Grafana app.
+type AppResult struct {
+	App  string
+	Data interface{}
+}
+
+// SQLiteEncoder selects the SQLiteExporter. It cannot encode to a writer.
+type SQLiteEncoder struct{}
+
+func (s *SQLiteEncoder) Encode(w io.Writer, v interface{}) error {
+	return fmt.Errorf("sqlite encoding requires a filename")
+}
+
+func NewSQLiteEncoder() (*SQLiteEncoder, error) {
+	return &SQLiteEncoder{}, nil
+}
+
+// sqliteSchema creates the tables for all supported records. Timestamps are
+// stored as RFC 3339 text in UTC, so that SQLite date functions work on them.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS repos (
+	id    INTEGER PRIMARY KEY,
+	owner TEXT NOT NULL,
+	name  TEXT NOT NULL,
+	UNIQUE (owner, name)
+);
+
+CREATE TABLE IF NOT EXISTS pull_requests (
+	repo_id         INTEGER NOT NULL REFERENCES repos (id),
+	number          INTEGER NOT NULL,
+	title           TEXT NOT NULL,
+	author          TEXT NOT NULL DEFAULT '',
+	base_ref        TEXT NOT NULL DEFAULT '',
+	head_ref        TEXT NOT NULL DEFAULT '',
+	additions       INTEGER NOT NULL DEFAULT 0,
+	deletions       INTEGER NOT NULL DEFAULT 0,
+	changed_files   INTEGER NOT NULL DEFAULT 0,
+	commits         INTEGER NOT NULL DEFAULT 0,
+	reviews         INTEGER NOT NULL DEFAULT 0,
+	created_at      TEXT,
+	updated_at      TEXT,
+	closed_at       TEXT,
+	merged_at       TEXT,
+	first_commit_at TEXT,
+	first_review_at TEXT,
+	approved_at     TEXT,
+	PRIMARY KEY (repo_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS pull_request_labels (
+	repo_id   INTEGER NOT NULL REFERENCES repos (id),
+	pr_number INTEGER NOT NULL,
+	label     TEXT NOT NULL,
+	PRIMARY KEY (repo_id, pr_number, label),
+	FOREIGN KEY (repo_id, pr_number) REFERENCES pull_requests (repo_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS releases (
+	repo_id       INTEGER NOT NULL REFERENCES repos (id),
+	tag_name      TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	is_draft      INTEGER NOT NULL,
+	is_latest     INTEGER NOT NULL,
+	is_prerelease INTEGER NOT NULL,
+	description   TEXT NOT NULL,
+	created_at    TEXT,
+	published_at  TEXT,
+	PRIMARY KEY (repo_id, tag_name)
+);
+
+CREATE TABLE IF NOT EXISTS release_pull_requests (
+	repo_id   INTEGER NOT NULL REFERENCES repos (id),
+	tag_name  TEXT NOT NULL,
+	pr_number INTEGER NOT NULL,
+	PRIMARY KEY (repo_id, tag_name, pr_number),
+	FOREIGN KEY (repo_id, tag_name) REFERENCES releases (repo_id, tag_name)
+);
+
+CREATE TABLE IF NOT EXISTS commits (
+	repo_id         INTEGER NOT NULL REFERENCES repos (id),
+	sha             TEXT NOT NULL,
+	abbreviated_sha TEXT NOT NULL,
+	authored_date   TEXT,
+	committed_date  TEXT,
+	message         TEXT NOT NULL,
+	PRIMARY KEY (repo_id, sha)
+);
+
+CREATE TABLE IF NOT EXISTS commit_parents (
+	repo_id    INTEGER NOT NULL REFERENCES repos (id),
+	sha        TEXT NOT NULL,
+	parent_sha TEXT NOT NULL,
+	PRIMARY KEY (repo_id, sha, parent_sha),
+	FOREIGN KEY (repo_id, sha) REFERENCES commits (repo_id, sha)
+);
+
+CREATE TABLE IF NOT EXISTS commit_pull_requests (
+	repo_id   INTEGER NOT NULL REFERENCES repos (id),
+	sha       TEXT NOT NULL,
+	pr_number INTEGER NOT NULL,
+	PRIMARY KEY (repo_id, sha, pr_number),
+	FOREIGN KEY (repo_id, sha) REFERENCES commits (repo_id, sha),
+	FOREIGN KEY (repo_id, pr_number) REFERENCES pull_requests (repo_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS deployments (
+	id                   INTEGER PRIMARY KEY,
+	repo_id              INTEGER NOT NULL REFERENCES repos (id),
+	original_environment TEXT NOT NULL,
+	created_at           TEXT NOT NULL,
+	commit_sha           TEXT NOT NULL,
+	latest_environment   TEXT NOT NULL,
+	description          TEXT NOT NULL,
+	updated_at           TEXT,
+	task                 TEXT NOT NULL,
+	state                TEXT NOT NULL,
+	ref                  TEXT NOT NULL,
+	duration_seconds     REAL,
+	failed_attempts      INTEGER NOT NULL,
+	UNIQUE (repo_id, original_environment, created_at, commit_sha)
+);
+
+CREATE TABLE IF NOT EXISTS deployment_statuses (
+	deployment_id   INTEGER NOT NULL REFERENCES deployments (id),
+	created_at      TEXT NOT NULL,
+	state           TEXT NOT NULL,
+	description     TEXT NOT NULL,
+	log_url         TEXT NOT NULL,
+	environment_url TEXT NOT NULL,
+	PRIMARY KEY (deployment_id, created_at, state)
+);
+
+CREATE TABLE IF NOT EXISTS deployed_commits (
+	deployment_id INTEGER NOT NULL REFERENCES deployments (id),
+	repo_id       INTEGER NOT NULL REFERENCES repos (id),
+	sha           TEXT NOT NULL,
+	PRIMARY KEY (deployment_id, sha),
+	FOREIGN KEY (repo_id, sha) REFERENCES commits (repo_id, sha)
+);
+
+CREATE TABLE IF NOT EXISTS grafana_deployments (
+	app        TEXT NOT NULL,
+	env        TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	image_repo TEXT NOT NULL,
+	image_tag  TEXT NOT NULL,
+	updated_at TEXT,
+	canary     INTEGER NOT NULL,
+	PRIMARY KEY (app, env, created_at, image_repo, image_tag)
+);
+`
+
+// SQLiteExporter upserts records into a SQLite database, so that data from
+// multiple runs and repos can be queried with SQL.
+type SQLiteExporter struct {
+	filename string
+}
+
+func (s *SQLiteExporter) keysBySource() {}
+
+// Export writes the records for v to the database in a single transaction.
+func (s *SQLiteExporter) Export(v interface{}) error {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", s.filename+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("error creating database schema: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	w := &sqliteWriter{ctx: ctx, tx: tx}
+
+	switch v := v.(type) {
+	case []*RepoResult:
+		for _, r := range v {
+			if err := w.writeRepoResult(r); err != nil {
+				return err
+			}
+		}
+	case *AppResult:
+		if err := w.writeAppResult(v); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unable to export type %T to SQLite without a repo or app", v)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func NewSQLiteExporter(filename string) (*SQLiteExporter, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("sqlite encoding requires a filename")
+	}
+	return &SQLiteExporter{filename: filename}, nil
+}
+
+// sqliteWriter writes records within a transaction.
+type sqliteWriter struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (w *sqliteWriter) exec(query string, args ...interface{}) error {
+	_, err := w.tx.ExecContext(w.ctx, query, args...)
+	return err
+}
+
+func (w *sqliteWriter) writeRepoResult(r *RepoResult) error {
+	owner, name, ok := strings.Cut(r.Repo, "/")
+	if !ok {
+		return fmt.Errorf("invalid repo %q", r.Repo)
+	}
+
+	repoID, err := w.writeRepo(owner, name)
+	if err != nil {
+		return fmt.Errorf("error writing repo %s: %w", r.Repo, err)
+	}
+
+	switch v := r.Data.(type) {
+	case []github.PullRequest:
+		for _, pr := range v {
+			if err := w.writePullRequest(repoID, &pr); err != nil {
+				return fmt.Errorf("error writing pull request %d: %w", pr.Number, err)
+			}
+		}
+	case []github.Release:
+		for _, rel := range v {
+			if err := w.writeRelease(repoID, &rel); err != nil {
+				return fmt.Errorf("error writing release %s: %w", rel.TagName, err)
+			}
+		}
+	case []github.ReleaseWithPRs:
+		for _, rel := range v {
+			if err := w.writeReleaseWithPRs(repoID, &rel); err != nil {
+				return fmt.Errorf("error writing release %s: %w", rel.TagName, err)
+			}
+		}
+	case []github.Deployment:
+		for _, d := range v {
+			if _, err := w.writeDeployment(repoID, &d); err != nil {
+				return fmt.Errorf("error writing deployment: %w", err)
+			}
+		}
+	case map[string][]*github.DeploymentWithCommits:
+		// Write environments in a stable order.
+		envs := make([]string, 0, len(v))
+		for env := range v {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
+
+		for _, env := range envs {
+			for _, d := range v[env] {
+				if err := w.writeDeploymentWithCommits(repoID, d); err != nil {
+					return fmt.Errorf("error writing deployment: %w", err)
+				}
+			}
+		}
+	case *github.DeploymentWithCommits:
+		if err := w.writeDeploymentWithCommits(repoID, v); err != nil {
+			return fmt.Errorf("error writing deployment: %w", err)
+		}
+	case *github.CommitsComparison:
+		for _, c := range v.Commits {
+			if err := w.writeCommit(repoID, c); err != nil {
+				return fmt.Errorf("error writing commit %s: %w", c.SHA, err)
+			}
+		}
+	case []github.Commit:
+		for _, c := range v {
+			if err := w.writeCommit(repoID, &c); err != nil {
+				return fmt.Errorf("error writing commit %s: %w", c.SHA, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unable to export type %T to SQLite", v)
+	}
+
+	return nil
+}
+
+func (w *sqliteWriter) writeAppResult(r *AppResult) error {
+	switch v := r.Data.(type) {
+	case []grafana.Deployment:
+		for _, d := range v {
+			if err := w.writeGrafanaDeployment(r.App, &d); err != nil {
+				return fmt.Errorf("error writing Grafana deployment: %w", err)
+			}
+		}
+	case []*grafana.LinkedDeployment:
+		for _, l := range v {
+			if err := w.writeGrafanaDeployment(r.App, l.Deployment); err != nil {
+				return fmt.Errorf("error writing Grafana deployment: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unable to export type %T to SQLite", v)
+	}
+
+	return nil
+}
+
+func (w *sqliteWriter) writeRepo(owner, name string) (int64, error) {
+	var id int64
+	err := w.tx.QueryRowContext(w.ctx, `
+		INSERT INTO repos (owner, name) VALUES (?, ?)
+		ON CONFLICT (owner, name) DO UPDATE SET owner = excluded.owner
+		RETURNING id`,
+		owner, name,
+	).Scan(&id)
+	return id, err
+}
+
+func (w *sqliteWriter) writePullRequest(repoID int64, pr *github.PullRequest) error {
+	if err := w.exec(`
+		INSERT INTO pull_requests (
+			repo_id, number, title, author, base_ref, head_ref, additions, deletions, changed_files, commits, reviews,
+			created_at, updated_at, closed_at, merged_at, first_commit_at, first_review_at, approved_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo_id, number) DO UPDATE SET
+			title = excluded.title,
+			author = excluded.author,
+			base_ref = excluded.base_ref,
+			head_ref = excluded.head_ref,
+			additions = excluded.additions,
+			deletions = excluded.deletions,
+			changed_files = excluded.changed_files,
+			commits = excluded.commits,
+			reviews = excluded.reviews,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			closed_at = excluded.closed_at,
+			merged_at = excluded.merged_at,
+			first_commit_at = excluded.first_commit_at,
+			first_review_at = excluded.first_review_at,
+			approved_at = excluded.approved_at`,
+		repoID, pr.Number, pr.Title, pr.Author, pr.BaseRef, pr.HeadRef, pr.Additions, pr.Deletions, pr.ChangedFiles, pr.Commits, pr.Reviews,
+		sqlTime(pr.CreatedAt), sqlTime(pr.UpdatedAt), sqlTime(pr.ClosedAt), sqlTime(pr.MergedAt),
+		sqlTime(pr.FirstCommitAt), sqlTime(pr.FirstReviewAt), sqlTime(pr.ApprovedAt),
+	); err != nil {
+		return err
+	}
+
+	// Labels may have been removed since the PR was last exported.
+	if err := w.exec(`DELETE FROM pull_request_labels WHERE repo_id = ? AND pr_number = ?`, repoID, pr.Number); err != nil {
+		return err
+	}
+
+	for _, label := range pr.Labels {
+		if err := w.exec(`
+			INSERT INTO pull_request_labels (repo_id, pr_number, label) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			repoID, pr.Number, label,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *sqliteWriter) writeRelease(repoID int64, r *github.Release) error {
+	return w.exec(`
+		INSERT INTO releases (repo_id, tag_name, name, is_draft, is_latest, is_prerelease, description, created_at, published_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo_id, tag_name) DO UPDATE SET
+			name = excluded.name,
+			is_draft = excluded.is_draft,
+			is_latest = excluded.is_latest,
+			is_prerelease = excluded.is_prerelease,
+			description = excluded.description,
+			created_at = excluded.created_at,
+			published_at = excluded.published_at`,
+		repoID, r.TagName, r.Name, r.IsDraft, r.IsLatest, r.IsPrerelease, r.Description, sqlTime(r.CreatedAt), sqlTime(r.PublishedAt),
+	)
+}
+
+func (w *sqliteWriter) writeReleaseWithPRs(repoID int64, r *github.ReleaseWithPRs) error {
+	if err := w.writeRelease(repoID, r.Release); err != nil {
+		return err
+	}
+
+	for _, pr := range r.PRs {
+		// PRs parsed from release notes only have a number.
+		if !pr.MergedAt.IsZero() {
+			if err := w.writePullRequest(repoID, &pr); err != nil {
+				return fmt.Errorf("error writing pull request %d: %w", pr.Number, err)
+			}
+		}
+
+		if err := w.exec(`
+			INSERT INTO release_pull_requests (repo_id, tag_name, pr_number) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			repoID, r.TagName, pr.Number,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *sqliteWriter) writeCommit(repoID int64, c *github.Commit) error {
+	if err := w.exec(`
+		INSERT INTO commits (repo_id, sha, abbreviated_sha, authored_date, committed_date, message)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo_id, sha) DO UPDATE SET
+			abbreviated_sha = excluded.abbreviated_sha,
+			authored_date = excluded.authored_date,
+			committed_date = excluded.committed_date,
+			message = excluded.message`,
+		repoID, c.SHA, c.AbbreviatedSHA, sqlTime(c.AuthoredDate), sqlTime(c.CommittedDate), c.Message,
+	); err != nil {
+		return err
+	}
+
+	for _, p := range c.Parents {
+		if err := w.exec(`
+			INSERT INTO commit_parents (repo_id, sha, parent_sha) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			repoID, c.SHA, p.SHA,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, pr := range c.PullRequests {
+		if err := w.writePullRequest(repoID, &pr); err != nil {
+			return fmt.Errorf("error writing pull request %d: %w", pr.Number, err)
+		}
+
+		if err := w.exec(`
+			INSERT INTO commit_pull_requests (repo_id, sha, pr_number) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			repoID, c.SHA, pr.Number,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDeployment upserts the deployment and its commit and returns the ID of
+// the deployment. GitHub deployments are keyed by environment, creation time
+// and commit, because the deployment model has no ID.
+func (w *sqliteWriter) writeDeployment(repoID int64, d *github.Deployment) (int64, error) {
+	var commitSHA string
+	if d.Commit != nil {
+		if err := w.writeCommit(repoID, d.Commit); err != nil {
+			return 0, fmt.Errorf("error writing commit %s: %w", d.Commit.SHA, err)
+		}
+		commitSHA = d.Commit.SHA
+	}
+
+	var id int64
+	err := w.tx.QueryRowContext(w.ctx, `
+		INSERT INTO deployments (repo_id, original_environment, created_at, commit_sha, latest_environment, description, updated_at, task, state, ref, duration_seconds, failed_attempts)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo_id, original_environment, created_at, commit_sha) DO UPDATE SET
+			latest_environment = excluded.latest_environment,
+			description = excluded.description,
+			updated_at = excluded.updated_at,
+			task = excluded.task,
+			state = excluded.state,
+			ref = excluded.ref,
+			duration_seconds = excluded.duration_seconds,
+			failed_attempts = excluded.failed_attempts
+		RETURNING id`,
+		repoID, d.OriginalEnvironment, sqlTime(d.CreatedAt), commitSHA, d.LatestEnvironment, d.Description, sqlTime(d.UpdatedAt), d.Task, d.State, d.Ref, d.DurationSeconds, d.FailedAttempts,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range d.Statuses {
+		if err := w.exec(`
+			INSERT INTO deployment_statuses (deployment_id, created_at, state, description, log_url, environment_url)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (deployment_id, created_at, state) DO UPDATE SET
+				description = excluded.description,
+				log_url = excluded.log_url,
+				environment_url = excluded.environment_url`,
+			id, sqlTime(s.CreatedAt), s.State, s.Description, s.LogURL, s.EnvironmentURL,
+		); err != nil {
+			return 0, fmt.Errorf("error writing status %s: %w", s.State, err)
+		}
+	}
+
+	return id, nil
+}
+
+func (w *sqliteWriter) writeDeploymentWithCommits(repoID int64, d *github.DeploymentWithCommits) error {
+	deploymentID, err := w.writeDeployment(repoID, d.Deployment)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range d.DeployedCommits {
+		if err := w.writeCommit(repoID, c); err != nil {
+			return fmt.Errorf("error writing commit %s: %w", c.SHA, err)
+		}
+
+		if err := w.exec(`
+			INSERT INTO deployed_commits (deployment_id, repo_id, sha) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`,
+			deploymentID, repoID, c.SHA,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *sqliteWriter) writeGrafanaDeployment(app string, d *grafana.Deployment) error {
+	var imageRepo, imageTag string
+	if d.DockerImage != nil {
+		imageRepo, imageTag = d.DockerImage.Repo, d.DockerImage.Tag
+	}
+
+	return w.exec(`
+		INSERT INTO grafana_deployments (app, env, created_at, image_repo, image_tag, updated_at, canary)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (app, env, created_at, image_repo, image_tag) DO UPDATE SET
+			updated_at = excluded.updated_at,
+			canary = excluded.canary`,
+		app, d.Env, sqlTime(d.CreatedAt), imageRepo, imageTag, sqlTime(d.UpdatedAt), d.Canary,
+	)
+}
+
+// sqlTime formats t as RFC 3339 in UTC. Zero times are stored as NULL.
+func sqlTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
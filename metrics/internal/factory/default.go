@@ -7,12 +7,15 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/cache"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/ratelimit"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/rest"
 	"golang.org/x/oauth2"
 )
@@ -33,11 +36,14 @@ type DefaultFactory struct {
 	exporter    export.Exporter
 	NewExporter func(string, export.Encoder) (export.Exporter, error)
 
+	cache    *cache.Store
+	newCache func(string, time.Duration) (*cache.Store, error)
+
 	githubRepo    *github.Repo
 	newGitHubRepo func(string, string) *github.Repo
 
 	githubHTTPClient    *http.Client
-	NewGitHubHTTPClient func() (*http.Client, error)
+	NewGitHubHTTPClient func(*slog.Logger) (*http.Client, error)
 
 	githubRESTAPI       *rest.API
 	NewGitHubRESTClient func(*http.Client) rest.Client
@@ -57,6 +63,7 @@ func NewDefaultFactory(ctx context.Context) *DefaultFactory {
 	f.NewLogger = newLogger()
 	f.newEncoder = newEncoder()
 	f.NewExporter = newExporter()
+	f.newCache = newCache()
 
 	f.newGitHubRepo = newGitHubRepo()
 	f.NewGitHubHTTPClient = newGitHubHTTPClient(ctx)
@@ -94,6 +101,23 @@ func (f *DefaultFactory) ConfigureEncoder(e string) error {
 	return nil
 }
 
+// ConfigureFields wraps the configured encoder, so that it only exports the
+// given fields.
+func (f *DefaultFactory) ConfigureFields(fields []string) error {
+	encoder, err := f.Encoder()
+	if err != nil {
+		return fmt.Errorf("error retrieving encoder from factory: %w", err)
+	}
+
+	fieldsEncoder, err := export.NewFieldsEncoder(encoder, fields)
+	if err != nil {
+		return fmt.Errorf("error creating a new fields encoder: %w", err)
+	}
+	f.encoder = fieldsEncoder
+
+	return nil
+}
+
 // Encoder returns the configured encoder or an error if it is unset.
 func (f *DefaultFactory) Encoder() (export.Encoder, error) {
 	if f.encoder == nil {
@@ -126,6 +150,25 @@ func (f *DefaultFactory) Exporter() (export.Exporter, error) {
 	return f.exporter, nil
 }
 
+// ConfigureCache initializes and stores the cache for API responses in the
+// given directory. API clients configured afterwards use the cache.
+func (f *DefaultFactory) ConfigureCache(dir string, ttl time.Duration) error {
+	store, err := f.newCache(dir, ttl)
+	if err != nil {
+		return fmt.Errorf("error creating cache: %w", err)
+	}
+	f.cache = store
+	return nil
+}
+
+// Cache returns the configured cache or an error if it is unset.
+func (f *DefaultFactory) Cache() (*cache.Store, error) {
+	if f.cache == nil {
+		return nil, fmt.Errorf("cache not configured")
+	}
+	return f.cache, nil
+}
+
 // ConfigureGitHubRepo sets the repo using the given owner and name.
 func (f *DefaultFactory) ConfigureGitHubRepo(owner, name string) {
 	f.githubRepo = f.newGitHubRepo(owner, name)
@@ -161,7 +204,12 @@ func (f *DefaultFactory) GitHubHTTPClient() (*http.Client, error) {
 // ConfigureGitHubHTTPClient initializes and stores a GitHub HTTP client.
 // Returns an error if creation fails.
 func (f *DefaultFactory) ConfigureGitHubHTTPClient() error {
-	httpClient, err := f.NewGitHubHTTPClient()
+	logger, err := f.Logger()
+	if err != nil {
+		return fmt.Errorf("error retrieving logger: %w", err)
+	}
+
+	httpClient, err := f.NewGitHubHTTPClient(logger)
 	if err != nil {
 		return fmt.Errorf("error creating a GitHub HTTP client: %w", err)
 	}
@@ -193,6 +241,9 @@ func (f *DefaultFactory) ConfigureGitHubRESTAPI() error {
 	}
 
 	client := f.NewGitHubRESTClient(httpClient)
+	if f.cache != nil {
+		client = cache.NewRESTClient(client, f.cache, logger)
+	}
 
 	api, err := f.newGitHubRESTAPI(client, logger)
 	if err != nil {
@@ -227,6 +278,9 @@ func (f *DefaultFactory) ConfigureGitHubGraphQLAPI() error {
 	}
 
 	client := f.NewGitHubGraphQLClient(httpClient)
+	if f.cache != nil {
+		client = cache.NewGraphQLClient(client, f.cache, logger)
+	}
 
 	api, err := f.newGitHubGraphQLAPI(client, logger)
 	if err != nil {
@@ -251,6 +305,15 @@ func (f *DefaultFactory) ConfigureGrafanaHubHTTPClient() error {
 	if err != nil {
 		return fmt.Errorf("error creating Grafana HTTP Client: %w", err)
 	}
+
+	if f.cache != nil {
+		logger, err := f.Logger()
+		if err != nil {
+			return fmt.Errorf("error retrieving logger from factory: %w", err)
+		}
+		client = cache.NewGrafanaClient(client, f.cache, logger)
+	}
+
 	f.grafanaHTTPClient = client
 	return nil
 }
@@ -286,12 +349,18 @@ func newEncoder() func(string) (export.Encoder, error) {
 		switch encoding {
 		case "json":
 			return export.NewJSONEncoder()
+		case "ndjson":
+			return export.NewNDJSONEncoder()
 		case "csv":
 			return export.NewCSVEncoder()
+		case "parquet":
+			return export.NewParquetEncoder()
 		case "plain":
 			return export.NewPlainEncoder()
+		case "sqlite":
+			return export.NewSQLiteEncoder()
 		default:
-			return nil, fmt.Errorf("unsupported Export.Encoding. Please use 'json', 'csv', or 'plain'")
+			return nil, fmt.Errorf("unsupported Export.Encoding. Please use 'json', 'ndjson', 'csv', 'parquet', 'plain', or 'sqlite'")
 		}
 	}
 }
@@ -299,6 +368,10 @@ func newEncoder() func(string) (export.Encoder, error) {
 // create a func to return a new export.Exporter.
 func newExporter() func(string, export.Encoder) (export.Exporter, error) {
 	return func(filename string, encoder export.Encoder) (export.Exporter, error) {
+		if _, ok := encoder.(*export.SQLiteEncoder); ok {
+			return export.NewSQLiteExporter(filename)
+		}
+
 		switch filename {
 		case "":
 			return export.NewWriterExporter(os.Stdout, encoder)
@@ -308,16 +381,27 @@ func newExporter() func(string, export.Encoder) (export.Exporter, error) {
 	}
 }
 
-// create a func to return a new authenticated http.Client based on env vars.
-func newGitHubHTTPClient(ctx context.Context) func() (*http.Client, error) {
-	return func() (*http.Client, error) {
+// create a func to return a new cache.Store.
+func newCache() func(string, time.Duration) (*cache.Store, error) {
+	return func(dir string, ttl time.Duration) (*cache.Store, error) {
+		return cache.NewStore(dir, ttl)
+	}
+}
+
+// create a func to return a new authenticated http.Client based on env vars,
+// which waits and retries requests when GitHub rate limits are exceeded.
+func newGitHubHTTPClient(ctx context.Context) func(*slog.Logger) (*http.Client, error) {
+	return func(logger *slog.Logger) (*http.Client, error) {
 		token, err := config.ReadFromEnvE("GITHUB", "TOKEN")
 		if err != nil {
 			return nil, fmt.Errorf("error creating GitHub HTTP Client: %w", err)
 		}
 		src := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 
-		return oauth2.NewClient(ctx, src), nil
+		client := oauth2.NewClient(ctx, src)
+		client.Transport = ratelimit.NewTransport(client.Transport, logger)
+
+		return client, nil
 	}
 }
 
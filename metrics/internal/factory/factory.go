@@ -4,7 +4,9 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/cache"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/export"
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
@@ -20,11 +22,19 @@ type GenericFactory interface {
 
 	Encoder() (export.Encoder, error)
 	ConfigureEncoder(string) error
+	ConfigureFields([]string) error
 
 	Exporter() (export.Exporter, error)
 	ConfigureExporter(string) error
 }
 
+// CacheFactory provides methods for configuring the on-disk cache for API
+// responses.
+type CacheFactory interface {
+	Cache() (*cache.Store, error)
+	ConfigureCache(string, time.Duration) error
+}
+
 // GitHubFactory provides methods for managing GitHub repositories, HTTP
 // clients, and API clients.
 type GitHubFactory interface {
@@ -52,10 +62,11 @@ type GrafanaFactory interface {
 }
 
 // Factory combines interfaces for configuring logging, encoding, exporting,
-// GitHub API clients, and Grafana clients, providing a unified setup for
-// dependencies.
+// caching, GitHub API clients, and Grafana clients, providing a unified setup
+// for dependencies.
 type Factory interface {
 	GenericFactory
+	CacheFactory
 	GitHubFactory
 	GrafanaFactory
 }
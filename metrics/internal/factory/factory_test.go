@@ -2,8 +2,12 @@ package factory
 
 import (
 	"context"
+	"log/slog"
 	"reflect"
 	"testing"
+
+	"github.com/mozilla-services/rapid-release-model/metrics/internal/config"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/ratelimit"
 )
 
 func TestNewDefaultFactory_FunctionFieldsNotNil(t *testing.T) {
@@ -17,6 +21,7 @@ func TestNewDefaultFactory_FunctionFieldsNotNil(t *testing.T) {
 		{"NewLogger", f.NewLogger},
 		{"NewExporter", f.NewExporter},
 		{"newEncoder", f.newEncoder},
+		{"newCache", f.newCache},
 		{"newGitHubRepo", f.newGitHubRepo},
 		{"newGitHubHTTPClient", f.NewGitHubHTTPClient},
 		{"NewGitHubRESTClient", f.NewGitHubRESTClient},
@@ -35,3 +40,16 @@ func TestNewDefaultFactory_FunctionFieldsNotNil(t *testing.T) {
 		})
 	}
 }
+
+func TestNewGitHubHTTPClient_RateLimitTransport(t *testing.T) {
+	t.Setenv(config.EnvKey("GITHUB", "TOKEN"), "token")
+
+	client, err := newGitHubHTTPClient(context.Background())(slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := client.Transport.(*ratelimit.Transport); !ok {
+		t.Errorf("client.Transport is %T, want *ratelimit.Transport", client.Transport)
+	}
+}
@@ -4,32 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
-	"regexp"
 	"strconv"
 	"time"
 )
 
 // AnnotationsFilter holds parameters for filtering Grafana Annotations
 type AnnotationsFilter struct {
-	App   string
-	From  string
-	To    string
+	App  string
+	From string
+	To   string
+
+	// Maximum number of annotations per request
 	Limit int
+
+	// Maximum number of annotations across all requests
+	MaxAnnotations int
 }
 
-// CreateURLValues creates URL values based on the given AnnotationsFilter
-func CreateURLValues(f *AnnotationsFilter) url.Values {
+// CreateURLValues creates URL values based on the given AnnotationsFilter and
+// the tags of the given rules
+func CreateURLValues(f *AnnotationsFilter, rules *AnnotationRules) url.Values {
 	values := make(url.Values)
 
-	// Fixed parameters for deployments
 	values.Add("type", "annotation")
-	values.Add("tags", "event_type:deployment")
-	values.Add("tags", "event_status:complete")
+	for _, t := range rules.Tags {
+		values.Add("tags", t)
+	}
 
 	// Variable parameters specified by the user
-	values.Add("tags", fmt.Sprintf("app:%s", f.App))
+	values.Add("tags", rules.appTag(f.App))
 	values.Add("from", f.From)
 	values.Add("to", f.To)
 	values.Add("limit", strconv.Itoa(f.Limit))
@@ -39,12 +44,15 @@ func CreateURLValues(f *AnnotationsFilter) url.Values {
 
 // Annotation represents an annotation object as returned by the Grafana REST API
 type Annotation struct {
-	Text      string   `json:"text"`
-	CreatedAt int64    `json:"created"`
-	UpdatedAt int64    `json:"updated"`
-	Time      int64    `json:"time"`
-	TimeEnd   int64    `json:"timeEnd"`
-	Tags      []string `json:"tags"`
+	ID           int64    `json:"id"`
+	DashboardUID string   `json:"dashboardUID"`
+	PanelID      int64    `json:"panelId"`
+	Text         string   `json:"text"`
+	CreatedAt    int64    `json:"created"`
+	UpdatedAt    int64    `json:"updated"`
+	Time         int64    `json:"time"`
+	TimeEnd      int64    `json:"timeEnd"`
+	Tags         []string `json:"tags"`
 }
 
 // DockerImage used for a Deployment
@@ -60,102 +68,135 @@ type Deployment struct {
 	UpdatedAt   time.Time
 	Env         string
 	Canary      bool
+
+	// Errors reading fields from the annotation
+	ParseErrors []string
 }
 
-// readDockerImageFromText reads the Docker image repo and tag from the
-// Annotation's text. If no complete info is found, return an error.
-func readDockerImageFromText(t string) (*DockerImage, error) {
-	pattern := `<b>Docker Image:</b> (?P<repo>\w+):(?P<tag>[a-zA-Z0-9\.]+)<br>`
-	re := regexp.MustCompile(pattern)
+// newDeploymentFromAnnotation creates a new Deployment from the given
+// Annotation. Fields which cannot be read from the annotation are left empty
+// and reported in the ParseErrors of the Deployment.
+func newDeploymentFromAnnotation(a *Annotation, rules *AnnotationRules) *Deployment {
+	values := rules.match(a)
 
-	var repo string
-	var tag string
+	deployment := &Deployment{
+		CreatedAt: time.UnixMilli(a.CreatedAt).UTC(),
+		UpdatedAt: time.UnixMilli(a.UpdatedAt).UTC(),
+	}
 
-	for _, match := range re.FindAllStringSubmatch(t, 1) {
-		for i, name := range re.SubexpNames() {
-			if name == "repo" {
-				repo = match[i]
-			}
-			if name == "tag" {
-				tag = match[i]
-			}
+	// Fields which are not mapped to a group are not read.
+	read := func(field string) (string, bool) {
+		group, ok := rules.Fields[field]
+		if !ok {
+			return "", false
+		}
+		value, ok := values[group]
+		if !ok && field != FieldCanary {
+			deployment.ParseErrors = append(deployment.ParseErrors, fmt.Sprintf("no match for %s (group %q)", field, group))
 		}
+		return value, ok
 	}
 
-	if repo == "" || tag == "" {
-		return nil, fmt.Errorf("no Docker image tag in text")
+	imageRepo, _ := read(FieldImageRepo)
+	if tag, ok := read(FieldImageTag); ok {
+		deployment.DockerImage = &DockerImage{Repo: imageRepo, Tag: tag}
 	}
 
-	return &DockerImage{Repo: repo, Tag: tag}, nil
+	deployment.Env, _ = read(FieldEnv)
+	_, deployment.Canary = read(FieldCanary)
+
+	return deployment
 }
 
-// readEnvironmentFromTags
-func readEnvironmentFromTags(tags []string) (string, error) {
-	pattern := `env:(\w+)`
-	re := regexp.MustCompile(pattern)
-	for _, t := range tags {
-		if submatches := re.FindStringSubmatch(t); submatches != nil {
-			return submatches[1], nil
+// QueryAnnotations fetches deployment annotations from the Grafana REST API.
+// Annotations are returned newest first, so if a request returns as many
+// annotations as the limit, the next request narrows the end of the time
+// window to the oldest annotation received. Annotations at that time are
+// returned again and skipped by ID. Paging stops when the time window is
+// exhausted, a request returns no new annotations (more than the limit share a
+// single time) or the maximum number of annotations is reached. The last two
+// leave out older annotations in the time window and are logged as warnings.
+func QueryAnnotations(ctx context.Context, httpClient HTTPClient, logger *slog.Logger, filter *AnnotationsFilter, rules *AnnotationRules) ([]Annotation, error) {
+	// Create HTTP request query parameters
+	urlValues := CreateURLValues(filter, rules)
+
+	var annotations []Annotation
+	seen := make(map[int64]bool)
+
+	for {
+		respData, err := httpClient.Get(ctx, "api/annotations", urlValues)
+		if err != nil {
+			return nil, err
 		}
-	}
-	return "", fmt.Errorf("cannot find env in tags")
-}
 
-// readCanaryFromText returns if it's a canary deployment
-func readCanaryFromText(t string) bool {
-	pattern := `Canary Deployment:`
-	re := regexp.MustCompile(pattern)
-	return re.MatchString(t)
-}
+		var page []Annotation
 
-// newDeploymentFromAnnotation creates a new Deployment from the given Annotation
-func newDeploymentFromAnnotation(a *Annotation) (*Deployment, error) {
-	dockerImage, err := readDockerImageFromText(a.Text)
-	if err != nil {
-		log.Printf("unable to read Docker Image from annotation: %v", err)
-	}
+		if err := json.Unmarshal(respData, &page); err != nil {
+			return nil, err
+		}
 
-	env, err := readEnvironmentFromTags(a.Tags)
-	if err != nil {
-		log.Printf("unable to read Environment from annotation: %v", err)
-	}
+		if len(page) == 0 {
+			break
+		}
 
-	deployment := &Deployment{
-		CreatedAt:   time.UnixMilli(a.CreatedAt).UTC(),
-		UpdatedAt:   time.UnixMilli(a.UpdatedAt).UTC(),
-		Env:         env,
-		DockerImage: dockerImage,
-		Canary:      readCanaryFromText(a.Text),
-	}
+		oldest := page[0].Time
+		added := 0
 
-	return deployment, nil
-}
+		for _, a := range page {
+			if a.Time < oldest {
+				oldest = a.Time
+			}
+			if seen[a.ID] {
+				continue
+			}
+			seen[a.ID] = true
+
+			annotations = append(annotations, a)
+			added++
+
+			if filter.MaxAnnotations > 0 && len(annotations) == filter.MaxAnnotations {
+				logger.Warn(
+					"grafana.QueryAnnotations: reached the maximum number of annotations, skipping older annotations",
+					slog.String("app", filter.App),
+					slog.Int("maxAnnotations", filter.MaxAnnotations),
+					slog.Time("oldest", time.UnixMilli(a.Time).UTC()),
+				)
+				return annotations, nil
+			}
+		}
 
-// QueryDeployments fetches information about Deployments from the Grafana REST API
-func QueryDeployments(ctx context.Context, httpClient HTTPClient, filter *AnnotationsFilter) ([]Deployment, error) {
-	// Create HTTP request query parameters
-	urlValues := CreateURLValues(filter)
+		if len(page) < filter.Limit {
+			break
+		}
 
-	respData, err := httpClient.Get(ctx, "api/annotations", urlValues)
-	if err != nil {
-		return nil, err
+		if added == 0 {
+			logger.Warn(
+				"grafana.QueryAnnotations: more annotations than the limit share a single time, skipping older annotations",
+				slog.String("app", filter.App),
+				slog.Int("limit", filter.Limit),
+				slog.Time("oldest", time.UnixMilli(oldest).UTC()),
+			)
+			break
+		}
+
+		urlValues.Set("to", strconv.FormatInt(oldest, 10))
 	}
 
-	var annotations []Annotation
+	return annotations, nil
+}
 
-	if err := json.Unmarshal(respData, &annotations); err != nil {
+// QueryDeployments fetches information about Deployments from the Grafana
+// REST API and reads them from annotations using the given rules
+func QueryDeployments(ctx context.Context, httpClient HTTPClient, logger *slog.Logger, filter *AnnotationsFilter, rules *AnnotationRules) ([]Deployment, error) {
+	annotations, err := QueryAnnotations(ctx, httpClient, logger, filter, rules)
+	if err != nil {
 		return nil, err
 	}
 
 	var deployments []Deployment
 
 	for _, a := range annotations {
-		deployment, err := newDeploymentFromAnnotation(&a)
-		if err != nil {
-			log.Printf("error creating Deployment from Annotation %v", err)
-			continue
-		}
-		deployments = append(deployments, *deployment)
+		deployments = append(deployments, *newDeploymentFromAnnotation(&a, rules))
 	}
 
 	return deployments, nil
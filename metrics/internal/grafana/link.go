@@ -0,0 +1,177 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+// shaPattern matches Docker image tags which are (abbreviated) commit SHAs.
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// LinkedDeployment is a Grafana deployment linked to the GitHub release or
+// commit referenced by its Docker image tag.
+type LinkedDeployment struct {
+	Deployment *Deployment
+
+	// Git ref the Docker image tag resolved to. Empty if the tag matches
+	// neither a release nor a commit SHA.
+	Ref string
+
+	// Release with the tag of the Docker image, if any
+	Release *github.Release
+
+	// Deployed commit. Nil if it was not found in the comparison with the
+	// previous deployment, such as for the oldest deployment of each
+	// environment.
+	Commit *github.Commit
+
+	// Commits between the previous deployment in the same environment and
+	// this deployment
+	DeployedCommits []*github.Commit
+}
+
+// Options for the LinkDeployments function
+type LinkOptions struct {
+	ReleaseLimit int
+	Commits      *github.CommitsOpts
+}
+
+// releaseForTag returns the release for the Docker image tag. Release tags
+// often have a "v" prefix, which Docker image tags do not.
+func releaseForTag(releases map[string]*github.Release, tag string) *github.Release {
+	if r, ok := releases[tag]; ok {
+		return r
+	}
+	if r, ok := releases["v"+tag]; ok {
+		return r
+	}
+	return releases[strings.TrimPrefix(tag, "v")]
+}
+
+// LinkDeployments maps Grafana deployments to GitHub releases by the tag of
+// their Docker image or to commits if the tag is a commit SHA. It then
+// compares each deployment with the previous deployment in the same
+// environment to find the deployed commits. Canary deployments and
+// deployments which cannot be linked are not compared. Comparisons which fail
+// are logged and leave the deployed commits empty. Deployments are returned
+// in the given order.
+func LinkDeployments(
+	ctx context.Context,
+	repo *github.Repo,
+	deployments []Deployment,
+	r github.ReleasesService, c github.CommitsComparisonService,
+	logger *slog.Logger,
+	opts *LinkOptions,
+) ([]*LinkedDeployment, error) {
+	releases, err := r.QueryReleases(ctx, repo, opts.ReleaseLimit, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error querying releases: %w", err)
+	}
+
+	releasesByTag := make(map[string]*github.Release)
+	for i := range releases {
+		// Draft releases are not tagged yet.
+		if releases[i].IsDraft {
+			continue
+		}
+		releasesByTag[releases[i].TagName] = &releases[i]
+	}
+
+	linked := make([]*LinkedDeployment, 0, len(deployments))
+	linkedByEnv := make(map[string][]*LinkedDeployment)
+
+	for i := range deployments {
+		d := &deployments[i]
+		l := &LinkedDeployment{Deployment: d}
+
+		if d.DockerImage != nil {
+			if release := releaseForTag(releasesByTag, d.DockerImage.Tag); release != nil {
+				l.Release = release
+				l.Ref = release.TagName
+			} else if shaPattern.MatchString(d.DockerImage.Tag) {
+				l.Ref = d.DockerImage.Tag
+			}
+		}
+
+		if l.Ref == "" {
+			logger.Debug(
+				"grafana.LinkDeployments: unable to link deployment",
+				slog.String("env", d.Env),
+				slog.Time("createdAt", d.CreatedAt),
+				slog.Any("dockerImage", d.DockerImage),
+			)
+		}
+
+		linked = append(linked, l)
+
+		if l.Ref != "" && !d.Canary {
+			linkedByEnv[d.Env] = append(linkedByEnv[d.Env], l)
+		}
+	}
+
+	var envs []string
+	for env := range linkedByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	for _, env := range envs {
+		ds := linkedByEnv[env]
+		sort.SliceStable(ds, func(i, j int) bool {
+			return ds[i].Deployment.CreatedAt.Before(ds[j].Deployment.CreatedAt)
+		})
+
+		for i := 1; i < len(ds); i++ {
+			base, head := ds[i-1].Ref, ds[i].Ref
+
+			// Redeployments of the same ref ship no commits.
+			if base == head {
+				continue
+			}
+
+			comparison, err := c.CompareCommits(ctx, repo, base, head, opts.Commits.Limit)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("error comparing commits for %s..%s: %w", base, head, err)
+				}
+
+				// Tags which look like commit SHAs, such as dates, may not
+				// resolve to a commit. Leave the deployed commits empty.
+				logger.Warn(
+					"grafana.LinkDeployments: unable to compare commits",
+					slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+					slog.String("env", env),
+					slog.String("base", base),
+					slog.String("head", head),
+					slog.Any("error", err),
+				)
+				continue
+			}
+
+			ds[i].DeployedCommits = comparison.Commits
+
+			// Commits are in ascending order, so the last commit is the
+			// deployed commit unless the comparison was truncated.
+			if n := len(comparison.Commits); n > 0 && n == comparison.TotalCommits {
+				ds[i].Commit = comparison.Commits[n-1]
+			}
+
+			logger.Debug(
+				"grafana.LinkDeployments: found commits between deployments",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.String("env", env),
+				slog.String("base", base),
+				slog.String("head", head),
+				slog.Int("count", len(comparison.Commits)),
+			)
+		}
+	}
+
+	return linked, nil
+}
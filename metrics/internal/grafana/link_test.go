@@ -0,0 +1,83 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+type fakeReleasesService struct{}
+
+func (f *fakeReleasesService) QueryReleases(ctx context.Context, repo *github.Repo, limit int, window *github.TimeWindow) ([]github.Release, error) {
+	return []github.Release{{TagName: "v1.0.0"}}, nil
+}
+
+// fakeComparisonService returns the known comparisons and fails like the
+// GitHub API for refs which do not exist.
+type fakeComparisonService struct {
+	comparisons map[string]*github.CommitsComparison
+}
+
+func (f *fakeComparisonService) CompareCommits(ctx context.Context, repo *github.Repo, base string, head string, limit int) (*github.CommitsComparison, error) {
+	if c, ok := f.comparisons[base+"..."+head]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("GET https://api.github.com/repos/%s/%s/compare/%s...%s: 404 Not Found", repo.Owner, repo.Name, base, head)
+}
+
+func TestLinkDeploymentsUnresolvableTag(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	commit := &github.Commit{SHA: "1abc111aaaaaaaaaaa"}
+
+	c := &fakeComparisonService{comparisons: map[string]*github.CommitsComparison{
+		"v1.0.0...1abc111": {TotalCommits: 1, Commits: []*github.Commit{commit}},
+	}}
+
+	// The date tag 20240103 looks like a commit SHA, but does not resolve to
+	// a commit.
+	deployments := []Deployment{
+		{DockerImage: &DockerImage{Tag: "20240103"}, CreatedAt: day(3), Env: "prod"},
+		{DockerImage: &DockerImage{Tag: "1abc111"}, CreatedAt: day(2), Env: "prod"},
+		{DockerImage: &DockerImage{Tag: "1.0.0"}, CreatedAt: day(1), Env: "prod"},
+	}
+
+	logbuf := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logbuf, nil))
+
+	linked, err := LinkDeployments(
+		context.Background(),
+		&github.Repo{Owner: "hackebrot", Name: "turtle"},
+		deployments,
+		&fakeReleasesService{}, c,
+		logger,
+		&LinkOptions{ReleaseLimit: 10, Commits: &github.CommitsOpts{Limit: 10}},
+	)
+	if err != nil {
+		t.Fatalf("LinkDeployments() returned unexpected error: %v", err)
+	}
+
+	want := [][]*github.Commit{nil, {commit}, nil}
+
+	var got [][]*github.Commit
+	for _, l := range linked {
+		got = append(got, l.DeployedCommits)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LinkDeployments() deployed commits mismatch (-want +got):\n%s", diff)
+	}
+
+	if logs := logbuf.String(); !strings.Contains(logs, "unable to compare commits") || !strings.Contains(logs, "head=20240103") {
+		t.Errorf("LinkDeployments() did not log the failed comparison:\n%s", logs)
+	}
+}
@@ -0,0 +1,208 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fields of a Deployment which can be read from an annotation
+const (
+	FieldImageRepo = "imageRepo"
+	FieldImageTag  = "imageTag"
+	FieldEnv       = "env"
+	FieldCanary    = "canary"
+)
+
+// Sources of an annotation which patterns are matched against
+const (
+	SourceText = "text"
+	SourceTags = "tags"
+)
+
+// AnnotationPattern is a regular expression with named groups which is
+// matched against the text or each of the tags of an annotation.
+type AnnotationPattern struct {
+	Source string `json:"source" yaml:"source"`
+	Regex  string `json:"regex" yaml:"regex"`
+
+	re *regexp.Regexp
+}
+
+// AnnotationRules configure which annotations are fetched for deployments and
+// how deployments are read from them.
+type AnnotationRules struct {
+	// Tags of deployment annotations
+	Tags []string `json:"tags" yaml:"tags"`
+
+	// Tag with the name of the app, where {app} is replaced with the app
+	AppTag string `json:"appTag" yaml:"appTag"`
+
+	Patterns []*AnnotationPattern `json:"patterns" yaml:"patterns"`
+
+	// Maps Deployment fields to named groups of the patterns. A deployment is
+	// a canary deployment if the group for the canary field matched a
+	// non-empty string.
+	Fields map[string]string `json:"fields" yaml:"fields"`
+}
+
+// DefaultAnnotationRules returns the rules for annotations created by
+// Mozilla's deployment pipelines.
+func DefaultAnnotationRules() *AnnotationRules {
+	return &AnnotationRules{
+		Tags:   []string{"event_type:deployment", "event_status:complete"},
+		AppTag: "app:{app}",
+		Patterns: []*AnnotationPattern{
+			{Source: SourceText, Regex: `<b>Docker Image:</b> (?P<repo>[\w./-]+):(?P<tag>[\w.-]+)<br>`},
+			{Source: SourceTags, Regex: `env:(?P<env>\w+)`},
+			{Source: SourceText, Regex: `(?P<canary>Canary Deployment:)`},
+		},
+		Fields: map[string]string{
+			FieldImageRepo: "repo",
+			FieldImageTag:  "tag",
+			FieldEnv:       "env",
+			FieldCanary:    "canary",
+		},
+	}
+}
+
+// LoadAnnotationRules reads rules from a .json, .yaml or .yml file. Keys which
+// are not set in the file keep their default values.
+func LoadAnnotationRules(filename string) (*AnnotationRules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file at %s: %w", filename, err)
+	}
+
+	rules := new(AnnotationRules)
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		err = json.Unmarshal(data, rules)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, rules)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q. Please use .json, .yaml or .yml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding file at %s: %w", filename, err)
+	}
+
+	defaults := DefaultAnnotationRules()
+	if rules.Tags == nil {
+		rules.Tags = defaults.Tags
+	}
+	if rules.AppTag == "" {
+		rules.AppTag = defaults.AppTag
+	}
+	if rules.Patterns == nil {
+		rules.Patterns = defaults.Patterns
+	}
+	if rules.Fields == nil {
+		rules.Fields = defaults.Fields
+	}
+
+	if err := rules.Compile(); err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", filename, err)
+	}
+
+	return rules, nil
+}
+
+// Compile validates the rules and compiles the patterns.
+func (r *AnnotationRules) Compile() error {
+	if !strings.Contains(r.AppTag, "{app}") {
+		return fmt.Errorf("appTag %q does not contain {app}", r.AppTag)
+	}
+
+	groups := make(map[string]bool)
+
+	for _, p := range r.Patterns {
+		if p.Source != SourceText && p.Source != SourceTags {
+			return fmt.Errorf("unknown source %q for pattern %q. Please use %q or %q", p.Source, p.Regex, SourceText, SourceTags)
+		}
+
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return fmt.Errorf("error compiling pattern: %w", err)
+		}
+		p.re = re
+
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				groups[name] = true
+			}
+		}
+	}
+
+	var fields []string
+	for field := range r.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		switch field {
+		case FieldImageRepo, FieldImageTag, FieldEnv, FieldCanary:
+		default:
+			return fmt.Errorf("unknown field %q", field)
+		}
+
+		if group := r.Fields[field]; !groups[group] {
+			return fmt.Errorf("no pattern has group %q for field %q", group, field)
+		}
+	}
+
+	for _, field := range []string{FieldImageTag, FieldEnv} {
+		if _, ok := r.Fields[field]; !ok {
+			return fmt.Errorf("field %q is required", field)
+		}
+	}
+
+	return nil
+}
+
+// appTag returns the tag for the given app.
+func (r *AnnotationRules) appTag(app string) string {
+	return strings.ReplaceAll(r.AppTag, "{app}", app)
+}
+
+// match returns the values of the named groups of all patterns which match
+// the annotation. The first non-empty match of a group wins.
+func (r *AnnotationRules) match(a *Annotation) map[string]string {
+	values := make(map[string]string)
+
+	add := func(p *AnnotationPattern, s string) bool {
+		match := p.re.FindStringSubmatch(s)
+		if match == nil {
+			return false
+		}
+		for i, name := range p.re.SubexpNames() {
+			if _, ok := values[name]; name != "" && match[i] != "" && !ok {
+				values[name] = match[i]
+			}
+		}
+		return true
+	}
+
+	for _, p := range r.Patterns {
+		switch p.Source {
+		case SourceText:
+			add(p, a.Text)
+		case SourceTags:
+			for _, t := range a.Tags {
+				if add(p, t) {
+					break
+				}
+			}
+		}
+	}
+
+	return values
+}
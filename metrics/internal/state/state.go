@@ -0,0 +1,108 @@
+// Package state persists sync cursors between runs of the metrics CLI, so that
+// subsequent runs only fetch items which are newer than the ones seen before.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Version of the state file format
+const version = 1
+
+// State holds the time of the newest item seen for each cursor key.
+type State struct {
+	Version int
+	Cursors map[string]time.Time
+}
+
+// Key returns the cursor key for the given parts, e.g. the kind of item, the
+// repo and the environment.
+func Key(parts ...string) string {
+	return strings.Join(parts, "/")
+}
+
+// New returns an empty State.
+func New() *State {
+	return &State{Version: version, Cursors: make(map[string]time.Time)}
+}
+
+// Load reads the State from the given file. A missing file results in an empty
+// State. A file which cannot be decoded results in an error rather than an
+// empty State, because syncing from scratch would append duplicate items to
+// existing exports.
+func Load(filename string) (*State, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file at %s: %w", filename, err)
+	}
+
+	s := New()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("state file at %s is corrupt: %w. Please fix or remove it", filename, err)
+	}
+
+	if s.Version != version {
+		return nil, fmt.Errorf("unsupported state file version %d at %s. Please remove it", s.Version, filename)
+	}
+
+	if s.Cursors == nil {
+		s.Cursors = make(map[string]time.Time)
+	}
+
+	return s, nil
+}
+
+// Cursor returns the time of the newest item seen for the given key.
+func (s *State) Cursor(key string) (time.Time, bool) {
+	t, ok := s.Cursors[key]
+	return t, ok
+}
+
+// Advance moves the cursor for the given key to t, unless the cursor is
+// already at or after t.
+func (s *State) Advance(key string, t time.Time) {
+	if cursor, ok := s.Cursors[key]; ok && !t.After(cursor) {
+		return
+	}
+	s.Cursors[key] = t.UTC()
+}
+
+// Save writes the State to the given file. It writes to a temporary file
+// first, so that an interrupted run does not leave a corrupt state file.
+func (s *State) Save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("error writing state file at %s: %w", filename, err)
+	}
+
+	return nil
+}
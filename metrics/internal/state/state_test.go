@@ -0,0 +1,81 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	// A missing state file results in an empty state.
+	s, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if len(s.Cursors) != 0 {
+		t.Fatalf("Load() returned %d cursors, want 0", len(s.Cursors))
+	}
+
+	key := Key("deployments", "hackebrot/turtle", "prod")
+	first := time.Date(2022, time.May, 1, 20, 25, 5, 0, time.UTC)
+
+	s.Advance(key, first)
+	s.Advance(key, first.Add(-time.Hour)) // Cursors never move backwards.
+
+	if err := s.Save(filename); err != nil {
+		t.Fatalf("Save() returned unexpected error: %v", err)
+	}
+
+	got, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(s, got); diff != "" {
+		t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+	}
+
+	if cursor, ok := got.Cursor(key); !ok || !cursor.Equal(first) {
+		t.Errorf("Cursor(%q) = %v, %v, want %v, true", key, cursor, ok, first)
+	}
+
+	if _, ok := got.Cursor(Key("prs", "hackebrot/turtle")); ok {
+		t.Errorf("Cursor() returned a cursor for an unknown key")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{name: "corrupt", content: `{"Version": 1, "Cursors": {`, errContains: "is corrupt"},
+		{name: "version", content: `{"Version": 2, "Cursors": {}}`, errContains: "unsupported state file version 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "state.json")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(filename)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("Load() error = %v, want error containing %q", err, tt.errContains)
+			}
+
+			// The corrupt file is left untouched for the user to inspect.
+			if data, _ := os.ReadFile(filename); string(data) != tt.content {
+				t.Errorf("Load() modified the state file")
+			}
+		})
+	}
+}
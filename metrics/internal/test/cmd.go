@@ -13,6 +13,7 @@ import (
 	"github.com/mozilla-services/rapid-release-model/metrics/internal/grafana"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/rest"
 	"github.com/spf13/cobra"
 )
 
@@ -35,6 +36,10 @@ func newLogger(w io.Writer) func(io.Writer, slog.Level) *slog.Logger {
 // create a func to return a new export.Exporter.
 func newExporter(w io.Writer) func(string, export.Encoder) (export.Exporter, error) {
 	return func(filename string, encoder export.Encoder) (export.Exporter, error) {
+		if _, ok := encoder.(*export.SQLiteEncoder); ok {
+			return export.NewSQLiteExporter(filename)
+		}
+
 		switch filename {
 		case "":
 			return export.NewWriterExporter(w, encoder)
@@ -60,6 +65,14 @@ func newGitHubGraphQLClient(wantReqParams *WantReqParams) func(*http.Client) gra
 	}
 }
 
+// create a func to return a new rest.Client with the authenticated http.Client.
+func newGitHubRESTClient() func(*http.Client) rest.Client {
+	return func(c *http.Client) rest.Client {
+		repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+		return &FakeGitHubRESTClient{repo: repo}
+	}
+}
+
 // ExecuteCmd uses the passed in function to create a command and execute it
 func ExecuteCmd(newCmd func(factory.Factory) *cobra.Command, args []string, wantReqParams *WantReqParams) (string, string, error) {
 	ctx := context.Background()
@@ -80,7 +93,7 @@ func ExecuteCmd(newCmd func(factory.Factory) *cobra.Command, args []string, want
 	// the factory fails to configure the GitHub GraphQL and REST API clients due
 	// to a missing GitHub token environment variable. Fake API clients in tests
 	// do not use this http.Client.
-	f.NewGitHubHTTPClient = func() (*http.Client, error) {
+	f.NewGitHubHTTPClient = func(*slog.Logger) (*http.Client, error) {
 		return &http.Client{Transport: &noopTransport{}}, nil
 	}
 
@@ -89,6 +102,11 @@ func ExecuteCmd(newCmd func(factory.Factory) *cobra.Command, args []string, want
 	// GitHub GraphQL API.
 	f.NewGitHubGraphQLClient = newGitHubGraphQLClient(wantReqParams)
 
+	// Overwrite NewGitHubRESTClient to return a fake client that returns
+	// canned responses (fixtures) rather than sending requests to the live
+	// GitHub REST API.
+	f.NewGitHubRESTClient = newGitHubRESTClient()
+
 	// Overwrite NewGrafanaHTTPClient to return a fake client that returns
 	// canned responses (fixtures) rather than sending queries to the live
 	// Grafana REST API.
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	ghrest "github.com/google/go-github/v68/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
 	"github.com/shurcooL/githubv4"
@@ -43,6 +45,11 @@ func (c *FakeGitHubGraphQLClient) Query(ctx context.Context, q interface{}, vari
 
 	var key string
 
+	// Default filename for fixtures. We don't know the endCursor before we
+	// perform the request. As a result, the filename for the first page does
+	// not contain the endCursor suffix. Subsequent requests have the suffix.
+	filename := "query"
+
 	// Update this for other GraphQL queries under test.
 	switch v := q.(type) {
 	case *graphql.PullRequestsQuery:
@@ -53,26 +60,71 @@ func (c *FakeGitHubGraphQLClient) Query(ctx context.Context, q interface{}, vari
 		key = "deployments"
 	case *graphql.DeploymentQuery:
 		key = "deployed-commits"
+	case *graphql.AssociatedPullRequestsQuery:
+		// Fixtures for comparisons are named after the compared tags.
+		key = "associated-prs"
+		base := strings.TrimPrefix(string(variables["baseRef"].(githubv4.String)), "refs/tags/")
+		head := strings.TrimPrefix(string(variables["headRef"].(githubv4.String)), "refs/tags/")
+		filename = fmt.Sprintf("query_%s...%s", base, head)
+	case *graphql.CompareQuery:
+		key = "compare-refs"
+		filename = fmt.Sprintf("query_%s...%s", variables["baseRef"], variables["headRef"])
+	case *graphql.CommitHistoryQuery:
+		// Fixtures for commit histories are named after the head commit.
+		key = "history"
+		filename = fmt.Sprintf("query_%s", variables["oid"])
 	default:
-		return fmt.Errorf("unsupported query: %+v", v)
+		// Queries for the pull requests of commits are built at runtime and
+		// have a variable for each commit. Fixtures are named after the
+		// first commit of the batch.
+		oid, ok := variables["oid0"]
+		if !ok {
+			return fmt.Errorf("unsupported query: %+v", v)
+		}
+		key = "commit-prs"
+		filename = fmt.Sprintf("query_%s", oid)
 	}
 
-	// Default filename for fixtures. We don't know the endCursor before we
-	// perform the request. As a result, the filename for the first page does
-	// not contain the endCursor suffix. Subsequent requests have the suffix.
-	filename := "query.json"
-
 	// The endCursor variable is set, which means we're serving the next page.
 	// The `after` GraphQL parameter is set to the value of `endCursor` of the
 	// previous request. Add the suffix to the fixture filenamme.
-	if c := variables["endCursor"]; c != (*githubv4.String)(nil) {
-		filename = fmt.Sprintf("query_%s.json", c)
+	if c, ok := variables["endCursor"]; ok && c != (*githubv4.String)(nil) {
+		filename = fmt.Sprintf("%s_%s", filename, c)
 	}
 
-	jsonData, err := LoadFixture("github", key, filename)
+	jsonData, err := LoadFixture("github", key, filename+".json")
 	if err != nil {
 		return err
 	}
 
 	return json.Unmarshal(jsonData, q)
 }
+
+// FakeGitHubRESTClient returns canned responses (fixtures) rather than
+// sending requests to the live GitHub REST API.
+type FakeGitHubRESTClient struct {
+	repo *github.Repo
+}
+
+func (c *FakeGitHubRESTClient) CompareCommits(ctx context.Context, owner, repo, base, head string, opts *ghrest.ListOptions) (*ghrest.CommitsComparison, *ghrest.Response, error) {
+	// Verify that the request is performed for the specified GitHub repo
+	if !cmp.Equal(owner, c.repo.Owner) {
+		return nil, nil, fmt.Errorf("owner in request (%v) does not match app config (%v)", owner, c.repo.Owner)
+	}
+	if !cmp.Equal(repo, c.repo.Name) {
+		return nil, nil, fmt.Errorf("repo in request (%v) does not match app config (%v)", repo, c.repo.Name)
+	}
+
+	// Fixtures contain all commits for a comparison, so there is no next page.
+	jsonData, err := LoadFixture("github", "compare", fmt.Sprintf("%s...%s.json", base, head))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comparison := new(ghrest.CommitsComparison)
+	if err := json.Unmarshal(jsonData, comparison); err != nil {
+		return nil, nil, err
+	}
+
+	return comparison, &ghrest.Response{}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -18,16 +19,51 @@ type GrafanaReqParams struct {
 // sending queries to the live Grafana REST API.
 type FakeGrafanaClient struct {
 	reqParams *GrafanaReqParams
+	requests  int
 }
 
 func (c *FakeGrafanaClient) Get(ctx context.Context, p string, params url.Values) ([]byte, error) {
-	if c.reqParams != nil && !cmp.Equal(p, c.reqParams.Path) {
-		return nil, fmt.Errorf("unexpected path for HTTP query\n%v", cmp.Diff(p, c.reqParams.Path))
+	c.requests++
+
+	if c.reqParams != nil {
+		if !cmp.Equal(p, c.reqParams.Path) {
+			return nil, fmt.Errorf("unexpected path for HTTP query\n%v", cmp.Diff(p, c.reqParams.Path))
+		}
+
+		// Requests for further pages narrow the end of the time window, so
+		// only the first request is expected to match "to".
+		gotParams, wantParams := params, c.reqParams.Params
+		if c.requests > 1 {
+			gotParams, wantParams = withoutKey(gotParams, "to"), withoutKey(wantParams, "to")
+		}
+
+		if !cmp.Equal(gotParams, wantParams) {
+			return nil, fmt.Errorf("unexpected URL values for HTTP query\n%v", cmp.Diff(gotParams, wantParams))
+		}
+	}
+
+	// Fixtures for further pages are named after the end of the time window.
+	if c.requests > 1 {
+		return LoadFixture("grafana", p, fmt.Sprintf("response_%s.json", params.Get("to")))
 	}
 
-	if c.reqParams != nil && !cmp.Equal(params, c.reqParams.Params) {
-		return nil, fmt.Errorf("unexpected URL values for HTTP query\n%v", cmp.Diff(params, c.reqParams.Params))
+	// Fixtures for apps other than turtle are named after the app.
+	for _, tag := range params["tags"] {
+		if app, ok := strings.CutPrefix(tag, "app:"); ok && app != "turtle" {
+			return LoadFixture("grafana", p, fmt.Sprintf("response__%s.json", app))
+		}
 	}
 
 	return LoadFixture("grafana", p, "response.json")
 }
+
+// withoutKey returns a copy of the given URL values without the given key.
+func withoutKey(values url.Values, key string) url.Values {
+	c := make(url.Values)
+	for k, v := range values {
+		if k != key {
+			c[k] = v
+		}
+	}
+	return c
+}
@@ -0,0 +1,296 @@
+// Package dora computes the four DORA metrics (deployment frequency, lead time
+// for changes, change failure rate and time to restore service) from GitHub
+// deployments and the commits they shipped.
+package dora
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+// Period is the granularity of the metrics breakdown.
+type Period string
+
+const (
+	Week  Period = "week"
+	Month Period = "month"
+)
+
+// Start returns the beginning of the period containing t, in UTC. Weeks start
+// on Monday.
+func (p Period) Start(t time.Time) (time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch p {
+	case Week:
+		// time.Weekday starts on Sunday (0), ISO weeks start on Monday.
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset), nil
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported period %q. Please use 'week' or 'month'", p)
+	}
+}
+
+// Next returns the beginning of the period following the one starting at t.
+func (p Period) Next(t time.Time) time.Time {
+	if p == Month {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 7)
+}
+
+// Options for the Compute function
+type Options struct {
+	Since  time.Time
+	Until  time.Time
+	Period Period
+}
+
+// Options for the QueryReport function
+type QueryOptions struct {
+	Env     string
+	Metrics *Options
+	Commits *github.CommitsOpts
+	Limit   int
+
+	// Maximum number of concurrent commit comparisons
+	Concurrency int
+}
+
+// Metrics holds the four DORA metrics for the time window from Start to End.
+type Metrics struct {
+	// Period of the breakdown, or "total" for the entire time window
+	Period string
+
+	Start time.Time
+	End   time.Time
+
+	// Number of deployments and the number of those that failed
+	Deployments       int
+	FailedDeployments int
+
+	// Average number of deployments per day
+	DeploymentFrequency float64
+
+	// Median time from authoring a commit to deploying it, in hours
+	LeadTimeForChangesHours float64
+
+	// Ratio of failed deployments to all deployments
+	ChangeFailureRate float64
+
+	// Median time from a failed deployment to the next successful one, in hours
+	TimeToRestoreHours float64
+}
+
+// Report holds the DORA metrics for a single environment, both for the entire
+// time window and broken down by period.
+type Report struct {
+	Env    string
+	Period Period `csv:"-"`
+
+	// Rows of CSV exports, the entire time window followed by each period
+	Summary *Metrics   `csv:",rows"`
+	Periods []*Metrics `csv:",rows"`
+}
+
+// incident spans from a failed deployment to the next successful deployment
+// in the same environment. Consecutive failures are part of the same incident.
+type incident struct {
+	start    time.Time
+	restored time.Time
+}
+
+// QueryReport fetches deployments with their commits for the given
+// environment and computes a DORA metrics report for them.
+func QueryReport(
+	ctx context.Context,
+	repo *github.Repo,
+	d github.DeploymentsService, c github.CommitsComparisonService,
+	logger *slog.Logger,
+	opts *QueryOptions,
+) (*Report, error) {
+	logger.Debug(
+		"dora.QueryReport: querying deployments with commits",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.String("env", opts.Env),
+		slog.Int("limit", opts.Limit),
+		slog.Time("since", opts.Metrics.Since),
+		slog.Time("until", opts.Metrics.Until),
+		slog.String("period", string(opts.Metrics.Period)),
+	)
+
+	envs := []string{opts.Env}
+
+	// Fetch one more deployment than the limit, to find out whether the window
+	// holds more deployments than the limit.
+	deploymentsByEnv, err := github.QueryDeploymentsWithCommits(ctx, repo, d, c, logger, &github.DeploymentWithCommitsOptions{
+		Deployments: &github.DeploymentsOpts{
+			Envs:   &envs,
+			Limit:  opts.Limit + 1,
+			Window: &github.TimeWindow{Since: opts.Metrics.Since, Until: opts.Metrics.Until},
+		},
+		Commits:     opts.Commits,
+		Concurrency: opts.Concurrency,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying deployments with commits: %w", err)
+	}
+
+	// Deployments are fetched newest first, so exceeding the limit means that
+	// older deployments within the window are missing from the report.
+	count := 0
+	for _, deployments := range deploymentsByEnv {
+		count += len(deployments)
+	}
+	if count > opts.Limit {
+		return nil, fmt.Errorf(
+			"reached the limit of %d deployments between since (%s) and until (%s). Please raise the limit",
+			opts.Limit, opts.Metrics.Since.Format(time.RFC3339), opts.Metrics.Until.Format(time.RFC3339),
+		)
+	}
+
+	report, err := Compute(deploymentsByEnv[opts.Env], opts.Metrics)
+	if err != nil {
+		return nil, fmt.Errorf("error computing DORA metrics: %w", err)
+	}
+	report.Env = opts.Env
+
+	return report, nil
+}
+
+// Compute calculates the DORA metrics for the given deployments, which are
+// expected to belong to a single environment. Deployments outside of the time
+// window from opts.Since to opts.Until are ignored.
+func Compute(deployments []*github.DeploymentWithCommits, opts *Options) (*Report, error) {
+	if opts.Since.IsZero() || opts.Until.IsZero() {
+		return nil, fmt.Errorf("since and until are required")
+	}
+
+	if !opts.Since.Before(opts.Until) {
+		return nil, fmt.Errorf("since (%s) must be before until (%s)", opts.Since.Format(time.RFC3339), opts.Until.Format(time.RFC3339))
+	}
+
+	first, err := opts.Period.Start(opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort a copy of the deployments in ascending order to find incidents.
+	sorted := make([]*github.DeploymentWithCommits, len(deployments))
+	copy(sorted, deployments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	incidents := findIncidents(sorted)
+
+	report := &Report{
+		Period:  opts.Period,
+		Summary: computeMetrics(sorted, incidents, opts.Since, opts.Until),
+	}
+	report.Summary.Period = "total"
+
+	for start := first; start.Before(opts.Until); start = opts.Period.Next(start) {
+		end := opts.Period.Next(start)
+
+		// Clip the first and last period to the time window.
+		from, to := start, end
+		if from.Before(opts.Since) {
+			from = opts.Since
+		}
+		if to.After(opts.Until) {
+			to = opts.Until
+		}
+
+		metrics := computeMetrics(sorted, incidents, from, to)
+		metrics.Period, metrics.Start, metrics.End = string(opts.Period), start, end
+		report.Periods = append(report.Periods, metrics)
+	}
+
+	return report, nil
+}
+
+// findIncidents returns incidents for the given deployments, which must be
+// sorted in ascending order. Incidents that have not been restored yet have a
+// zero restored time.
+func findIncidents(deployments []*github.DeploymentWithCommits) []*incident {
+	var incidents []*incident
+	var current *incident
+
+	for _, d := range deployments {
+		if d.Failed() {
+			if current == nil {
+				current = &incident{start: d.CreatedAt}
+				incidents = append(incidents, current)
+			}
+			continue
+		}
+
+		if current != nil {
+			current.restored = d.CreatedAt
+			current = nil
+		}
+	}
+
+	return incidents
+}
+
+// computeMetrics calculates metrics for deployments and incidents which
+// started in the half-open interval from start to end.
+func computeMetrics(deployments []*github.DeploymentWithCommits, incidents []*incident, start, end time.Time) *Metrics {
+	metrics := &Metrics{Start: start, End: end}
+
+	var leadTimes []time.Duration
+
+	for _, d := range deployments {
+		if !inWindow(d.CreatedAt, start, end) {
+			continue
+		}
+
+		metrics.Deployments++
+
+		if d.Failed() {
+			metrics.FailedDeployments++
+			continue
+		}
+
+		for _, commit := range d.DeployedCommits {
+			leadTimes = append(leadTimes, d.CreatedAt.Sub(commit.AuthoredDate))
+		}
+	}
+
+	var restoreTimes []time.Duration
+
+	for _, i := range incidents {
+		if !inWindow(i.start, start, end) || i.restored.IsZero() {
+			continue
+		}
+		restoreTimes = append(restoreTimes, i.restored.Sub(i.start))
+	}
+
+	if days := end.Sub(start).Hours() / 24; days > 0 {
+		metrics.DeploymentFrequency = float64(metrics.Deployments) / days
+	}
+
+	if metrics.Deployments > 0 {
+		metrics.ChangeFailureRate = float64(metrics.FailedDeployments) / float64(metrics.Deployments)
+	}
+
+	metrics.LeadTimeForChangesHours = github.Percentile(leadTimes, 50).Hours()
+	metrics.TimeToRestoreHours = github.Percentile(restoreTimes, 50).Hours()
+
+	return metrics
+}
+
+// inWindow reports whether t is in the half-open interval from start to end.
+func inWindow(t, start, end time.Time) bool {
+	return !t.Before(start) && t.Before(end)
+}
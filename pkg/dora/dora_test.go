@@ -0,0 +1,423 @@
+package dora_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ghrest "github.com/google/go-github/v68/github"
+	"github.com/mozilla-services/rapid-release-model/pkg/dora"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
+	"github.com/mozilla-services/rapid-release-model/pkg/github/rest"
+	"github.com/mozilla-services/rapid-release-model/pkg/internal/test"
+)
+
+func TestQueryReport(t *testing.T) {
+	ctx := context.Background()
+
+	logbuf := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logbuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	graphQLClient := test.NewFakeGraphQLClient()
+	graphQLAPI := graphql.NewGitHubGraphQLAPI(graphQLClient, logger)
+	registerGraphQLresponses(t, graphQLClient)
+
+	restClient := test.NewFakeGitHubRESTClient()
+	restAPI := rest.NewGitHubRESTAPI(restClient, logger)
+	registerRESTresponses(t, restClient)
+
+	since := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, time.January, 22, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		opts        *dora.QueryOptions
+		want        *dora.Report
+		errContains string
+	}{
+		{
+			name: "success__week",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   10,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since, Until: until, Period: dora.Week},
+			},
+			want: &dora.Report{
+				Env:    "production",
+				Period: dora.Week,
+				Summary: &dora.Metrics{
+					Period:                  "total",
+					Start:                   since,
+					End:                     until,
+					Deployments:             4,
+					FailedDeployments:       1,
+					DeploymentFrequency:     4.0 / 21,
+					LeadTimeForChangesHours: 14,
+					ChangeFailureRate:       0.25,
+					TimeToRestoreHours:      6,
+				},
+				Periods: []*dora.Metrics{
+					{
+						Period:                  "week",
+						Start:                   since,
+						End:                     since.AddDate(0, 0, 7),
+						Deployments:             1,
+						DeploymentFrequency:     1.0 / 7,
+						LeadTimeForChangesHours: 24,
+					},
+					{
+						Period:                  "week",
+						Start:                   since.AddDate(0, 0, 7),
+						End:                     since.AddDate(0, 0, 14),
+						Deployments:             2,
+						FailedDeployments:       1,
+						DeploymentFrequency:     2.0 / 7,
+						LeadTimeForChangesHours: 2,
+						ChangeFailureRate:       0.5,
+						TimeToRestoreHours:      6,
+					},
+					{
+						Period:                  "week",
+						Start:                   since.AddDate(0, 0, 14),
+						End:                     until,
+						Deployments:             1,
+						DeploymentFrequency:     1.0 / 7,
+						LeadTimeForChangesHours: 14,
+					},
+				},
+			},
+		},
+		{
+			name: "success__month",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   10,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since.AddDate(0, 0, 5), Until: until, Period: dora.Month},
+			},
+			want: &dora.Report{
+				Env:    "production",
+				Period: dora.Month,
+				Summary: &dora.Metrics{
+					Period:                  "total",
+					Start:                   since.AddDate(0, 0, 5),
+					End:                     until,
+					Deployments:             3,
+					FailedDeployments:       1,
+					DeploymentFrequency:     3.0 / 16,
+					LeadTimeForChangesHours: 4,
+					ChangeFailureRate:       1.0 / 3,
+					TimeToRestoreHours:      6,
+				},
+				Periods: []*dora.Metrics{
+					{
+						Period:                  "month",
+						Start:                   since,
+						End:                     since.AddDate(0, 1, 0),
+						Deployments:             3,
+						FailedDeployments:       1,
+						DeploymentFrequency:     3.0 / 16,
+						LeadTimeForChangesHours: 4,
+						ChangeFailureRate:       1.0 / 3,
+						TimeToRestoreHours:      6,
+					},
+				},
+			},
+		},
+		{
+			name: "error__period",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   10,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since, Until: until, Period: "day"},
+			},
+			errContains: "unsupported period \"day\"",
+		},
+		{
+			name: "error__window",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   10,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: until, Until: since, Period: dora.Week},
+			},
+			errContains: "since (2024-01-22T00:00:00Z) must be before until (2024-01-01T00:00:00Z)",
+		},
+		{
+			// The window holds exactly as many deployments as the limit.
+			name: "success__limit",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   4,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since, Until: until, Period: dora.Month},
+			},
+			want: &dora.Report{
+				Env:    "production",
+				Period: dora.Month,
+				Summary: &dora.Metrics{
+					Period:                  "total",
+					Start:                   since,
+					End:                     until,
+					Deployments:             4,
+					FailedDeployments:       1,
+					DeploymentFrequency:     4.0 / 21,
+					LeadTimeForChangesHours: 14,
+					ChangeFailureRate:       0.25,
+					TimeToRestoreHours:      6,
+				},
+				Periods: []*dora.Metrics{
+					{
+						Period:                  "month",
+						Start:                   since,
+						End:                     since.AddDate(0, 1, 0),
+						Deployments:             4,
+						FailedDeployments:       1,
+						DeploymentFrequency:     4.0 / 21,
+						LeadTimeForChangesHours: 14,
+						ChangeFailureRate:       0.25,
+						TimeToRestoreHours:      6,
+					},
+				},
+			},
+		},
+		{
+			name: "error__limit",
+			opts: &dora.QueryOptions{
+				Env:     "production",
+				Limit:   2,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since, Until: until, Period: dora.Week},
+			},
+			errContains: "reached the limit of 2 deployments between since (2024-01-01T00:00:00Z) and until (2024-01-22T00:00:00Z)",
+		},
+		{
+			name: "error__nope",
+			opts: &dora.QueryOptions{
+				Env:     "helloworld",
+				Limit:   10,
+				Commits: &github.CommitsOpts{Limit: 250},
+				Metrics: &dora.Options{Since: since, Until: until, Period: dora.Week},
+			},
+			errContains: "error querying deployments with commits: error querying deployments: nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Validate testcase configuration
+			if tt.errContains != "" && tt.want != nil {
+				t.Fatal("cannot set both errContains and want")
+			}
+
+			got, err := dora.QueryReport(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, graphQLAPI, restAPI, logger, tt.opts)
+
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("QueryReport() did not return an error")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error did not contain message\ngot:     %v\nmissing: %v", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Logf("logging %s\n", logbuf.String())
+				t.Errorf("QueryReport() = \n  %v\n", cmp.Diff(got, tt.want))
+			}
+
+			t.Cleanup(func() {
+				logbuf.Reset()
+			})
+		})
+	}
+}
+
+func registerGraphQLresponses(t *testing.T, c *test.FakeGraphQLClient) {
+	t.Helper()
+
+	deploymentsJsonData := `{
+		"Repository": {
+			"Name": "turtle",
+			"Owner": {
+				"Login": "hackebrot"
+			},
+			"Deployments": {
+				"PageInfo": {
+					"HasNextPage": false,
+					"EndCursor": "abc123"
+				},
+				"Nodes": [
+					{
+						"Description": "Deployment04",
+						"CreatedAt": "2024-01-16T10:00:00Z",
+						"UpdatedAt": "2024-01-16T10:00:00Z",
+						"OriginalEnvironment": "production",
+						"LatestEnvironment": "production",
+						"Task": "deploy",
+						"State": "ACTIVE",
+						"Commit": {
+							"AbbreviatedOid": "4444444",
+							"Oid": "4444444ddddddddddd",
+							"AuthoredDate": "2024-01-16T06:00:00Z",
+							"CommittedDate": "2024-01-16T06:00:00Z",
+							"Message": "commit 4"
+						}
+					},
+					{
+						"Description": "Deployment03",
+						"CreatedAt": "2024-01-09T16:00:00Z",
+						"UpdatedAt": "2024-01-09T16:00:00Z",
+						"OriginalEnvironment": "production",
+						"LatestEnvironment": "production",
+						"Task": "deploy",
+						"State": "INACTIVE",
+						"Commit": {
+							"AbbreviatedOid": "3333333",
+							"Oid": "3333333ccccccccccc",
+							"AuthoredDate": "2024-01-09T14:00:00Z",
+							"CommittedDate": "2024-01-09T14:00:00Z",
+							"Message": "commit 3"
+						}
+					},
+					{
+						"Description": "Deployment02",
+						"CreatedAt": "2024-01-09T10:00:00Z",
+						"UpdatedAt": "2024-01-09T10:00:00Z",
+						"OriginalEnvironment": "production",
+						"LatestEnvironment": "production",
+						"Task": "deploy",
+						"State": "FAILURE",
+						"Commit": {
+							"AbbreviatedOid": "2222222",
+							"Oid": "2222222bbbbbbbbbbb",
+							"AuthoredDate": "2024-01-09T08:00:00Z",
+							"CommittedDate": "2024-01-09T08:00:00Z",
+							"Message": "commit 2"
+						}
+					},
+					{
+						"Description": "Deployment01",
+						"CreatedAt": "2024-01-02T10:00:00Z",
+						"UpdatedAt": "2024-01-02T10:00:00Z",
+						"OriginalEnvironment": "production",
+						"LatestEnvironment": "production",
+						"Task": "deploy",
+						"State": "INACTIVE",
+						"Commit": {
+							"AbbreviatedOid": "1111111",
+							"Oid": "1111111aaaaaaaaaaa",
+							"AuthoredDate": "2024-01-01T10:00:00Z",
+							"CommittedDate": "2024-01-01T10:00:00Z",
+							"Message": "commit 1"
+						}
+					}
+				]
+			}
+		}
+	}`
+
+	c.RegisterResponse(
+		test.GraphQLQueryKey{
+			QueryType: "*graphql.DeploymentsQuery",
+			RepoOwner: "hackebrot",
+			RepoName:  "turtle",
+			Extra:     test.GraphQLQueryKeyExtra{Environments: "production"},
+		},
+		&test.GraphQLResponse{
+			Content: deploymentsJsonData,
+		},
+	)
+
+	c.RegisterResponse(
+		test.GraphQLQueryKey{
+			QueryType: "*graphql.DeploymentsQuery",
+			RepoOwner: "hackebrot",
+			RepoName:  "turtle",
+			Extra:     test.GraphQLQueryKeyExtra{Environments: "helloworld"},
+		},
+		&test.GraphQLResponse{
+			Err: errors.New("nope"),
+		},
+	)
+}
+
+// newRepositoryCommit returns a REST API commit with the given SHA, message
+// and date for both author and committer.
+func newRepositoryCommit(sha string, message string, date time.Time) *ghrest.RepositoryCommit {
+	return &ghrest.RepositoryCommit{
+		SHA: ghrest.Ptr(sha),
+		Commit: &ghrest.Commit{
+			SHA:       ghrest.Ptr(sha),
+			Author:    &ghrest.CommitAuthor{Date: &ghrest.Timestamp{Time: date}},
+			Committer: &ghrest.CommitAuthor{Date: &ghrest.Timestamp{Time: date}},
+			Message:   ghrest.Ptr(message),
+		},
+	}
+}
+
+func registerRESTresponses(t *testing.T, c *test.FakeGitHubRESTClient) {
+	t.Helper()
+
+	comparisons := []struct {
+		base    string
+		head    string
+		commits []*ghrest.RepositoryCommit
+	}{
+		{
+			base: "3333333ccccccccccc",
+			head: "4444444ddddddddddd",
+			commits: []*ghrest.RepositoryCommit{
+				newRepositoryCommit("4444444aaaaaaaaaaa", "commit 4a", time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC)),
+				newRepositoryCommit("4444444ddddddddddd", "commit 4", time.Date(2024, time.January, 16, 6, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			base: "2222222bbbbbbbbbbb",
+			head: "3333333ccccccccccc",
+			commits: []*ghrest.RepositoryCommit{
+				newRepositoryCommit("3333333ccccccccccc", "commit 3", time.Date(2024, time.January, 9, 14, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			base: "1111111aaaaaaaaaaa",
+			head: "2222222bbbbbbbbbbb",
+			commits: []*ghrest.RepositoryCommit{
+				newRepositoryCommit("2222222aaaaaaaaaaa", "commit 2a", time.Date(2024, time.January, 8, 10, 0, 0, 0, time.UTC)),
+				newRepositoryCommit("2222222bbbbbbbbbbb", "commit 2", time.Date(2024, time.January, 9, 8, 0, 0, 0, time.UTC)),
+			},
+		},
+	}
+
+	for _, comparison := range comparisons {
+		c.RegisterCommitComparison(
+			test.RESTCommitComparisonQueryKey{
+				RepoOwner: "hackebrot",
+				RepoName:  "turtle",
+				Base:      comparison.base,
+				Head:      comparison.head,
+				Page:      1,
+			},
+			&test.RESTCommitComparisonResponse{
+				APIResponse: &ghrest.Response{NextPage: 0},
+				Comparison: &ghrest.CommitsComparison{
+					TotalCommits: ghrest.Ptr(len(comparison.commits)),
+					Commits:      comparison.commits,
+				},
+			},
+		)
+	}
+}
@@ -6,12 +6,22 @@ import (
 
 // PullRequestsService provides access to GitHub Pull Request functionality.
 type PullRequestsService interface {
-	QueryPullRequests(ctx context.Context, repo *Repo, limit int) ([]PullRequest, error)
+	QueryPullRequests(ctx context.Context, repo *Repo, opts *PullRequestsOpts) ([]PullRequest, error)
+}
+
+// PullRequestsStreamService passes GitHub Pull Requests to fn page by page.
+type PullRequestsStreamService interface {
+	StreamPullRequests(ctx context.Context, repo *Repo, opts *PullRequestsOpts, fn func([]PullRequest) error) error
 }
 
 // DeploymentsService provides access to GitHub Deployment functionality.
 type DeploymentsService interface {
-	QueryDeployments(ctx context.Context, repo *Repo, envs *[]string, limit int) ([]Deployment, error)
+	QueryDeployments(ctx context.Context, repo *Repo, envs *[]string, limit int, window *TimeWindow) ([]Deployment, error)
+}
+
+// DeploymentsStreamService passes GitHub Deployments to fn page by page.
+type DeploymentsStreamService interface {
+	StreamDeployments(ctx context.Context, repo *Repo, envs *[]string, limit int, window *TimeWindow, fn func([]Deployment) error) error
 }
 
 // DeploymentService provides access to GitHub Deployment functionality.
@@ -21,7 +31,12 @@ type DeploymentService interface {
 
 // ReleasesService provides access to GitHub Release functionality.
 type ReleasesService interface {
-	QueryReleases(ctx context.Context, repo *Repo, limit int) ([]Release, error)
+	QueryReleases(ctx context.Context, repo *Repo, limit int, window *TimeWindow) ([]Release, error)
+}
+
+// ReleasesStreamService passes GitHub Releases to fn page by page.
+type ReleasesStreamService interface {
+	StreamReleases(ctx context.Context, repo *Repo, limit int, window *TimeWindow, fn func([]Release) error) error
 }
 
 // CommitsComparisonService provides commit comparison functionality.
@@ -38,3 +53,15 @@ type RefComparisonService interface {
 type HistoryService interface {
 	QueryHistory(ctx context.Context, repo *Repo, head string, base string, limit int) ([]Commit, error)
 }
+
+// AssociatedPullRequestsService provides the pull requests associated with
+// the commits between two Git references.
+type AssociatedPullRequestsService interface {
+	QueryAssociatedPullRequests(ctx context.Context, repo *Repo, base string, head string, limit int) ([]PullRequest, error)
+}
+
+// CommitPullRequestsService provides the pull requests associated with
+// commits.
+type CommitPullRequestsService interface {
+	QueryCommitPullRequests(ctx context.Context, repo *Repo, shas []string) (map[string][]PullRequest, error)
+}
@@ -0,0 +1,267 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// ChangeFailureReason explains why a deployment is considered a change
+// failure.
+type ChangeFailureReason string
+
+const (
+	// GitHub marked the deployment as failed
+	ReasonFailed ChangeFailureReason = "failed"
+
+	// The deployment shipped a commit that reverts another commit
+	ReasonRevert ChangeFailureReason = "revert"
+
+	// The deployment shipped a commit that matches a hotfix pattern
+	ReasonHotfix ChangeFailureReason = "hotfix"
+
+	// The deployment shipped an older commit than the previous deployment
+	ReasonRollback ChangeFailureReason = "rollback"
+)
+
+// DefaultHotfixPatterns match commit messages of hotfixes.
+var DefaultHotfixPatterns = []string{`(?i)\bhot-?fix`}
+
+// revertPattern matches commit messages created by git revert and GitHub's
+// revert button.
+var revertPattern = regexp.MustCompile(`(?m)^Revert "|This reverts commit [0-9a-f]+`)
+
+// ChangeFailureOptions configures the ClassifyChangeFailures function.
+type ChangeFailureOptions struct {
+	HotfixPatterns []*regexp.Regexp
+}
+
+// NewChangeFailureOptions compiles the given hotfix patterns.
+func NewChangeFailureOptions(hotfixPatterns []string) (*ChangeFailureOptions, error) {
+	opts := &ChangeFailureOptions{}
+
+	for _, p := range hotfixPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hotfix pattern %q: %w", p, err)
+		}
+		opts.HotfixPatterns = append(opts.HotfixPatterns, re)
+	}
+
+	return opts, nil
+}
+
+// DeploymentChangeFailure represents a deployment along with the reasons it is
+// considered a change failure. Deployments that failed or remediate a failed
+// change are change failures.
+type DeploymentChangeFailure struct {
+	Env        string
+	Deployment *Deployment `csv:"-"`
+
+	// Columns for the deployment
+	_ struct{} `csv:"deploymentCreatedAt,key=Deployment.CreatedAt"`
+	_ struct{} `csv:"deploymentState,key=Deployment.State"`
+	_ struct{} `csv:"abbreviatedCommitSHA,key=Deployment.Commit.AbbreviatedSHA"`
+	_ struct{} `csv:"commitSHA,key=Deployment.Commit.SHA"`
+
+	ChangeFailure bool
+	Reasons       []ChangeFailureReason
+
+	// SHAs of the deployed commits that are reverts or hotfixes
+	CommitSHAs []string
+}
+
+// ChangeFailureSummary holds the change failure rate for an environment.
+type ChangeFailureSummary struct {
+	Env               string
+	Deployments       int
+	ChangeFailures    int
+	ChangeFailureRate float64
+
+	// Number of deployments for each reason. A deployment may have more than
+	// one reason.
+	Failed    int
+	Reverts   int
+	Hotfixes  int
+	Rollbacks int
+}
+
+// ChangeFailureReport holds the classification of each deployment and summary
+// change failure rates for each environment.
+type ChangeFailureReport struct {
+	Deployments []*DeploymentChangeFailure `csv:",rows"`
+	Summary     []*ChangeFailureSummary    `csv:"-"`
+}
+
+// ClassifyChangeFailures classifies each deployment as a change failure or
+// not. Deployments are sorted by environment and by time in descending order.
+func ClassifyChangeFailures(deploymentsByEnv map[string][]*DeploymentWithCommits, opts *ChangeFailureOptions) *ChangeFailureReport {
+	report := &ChangeFailureReport{}
+
+	var envs []string
+	for env := range deploymentsByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	for _, env := range envs {
+		// Sort a copy of the deployments in ascending order to find rollbacks.
+		sorted := make([]*DeploymentWithCommits, len(deploymentsByEnv[env]))
+		copy(sorted, deploymentsByEnv[env])
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		})
+
+		summary := &ChangeFailureSummary{Env: env}
+		classified := make([]*DeploymentChangeFailure, 0, len(sorted))
+		firstDeployed := make(map[string]time.Time)
+
+		for i, d := range sorted {
+			var previous *Deployment
+			if i > 0 {
+				previous = sorted[i-1].Deployment
+			}
+
+			c := classifyChangeFailure(d, previous, firstDeployed, opts)
+			c.Env = env
+			classified = append(classified, c)
+
+			if d.Commit != nil {
+				if _, ok := firstDeployed[d.Commit.SHA]; !ok {
+					firstDeployed[d.Commit.SHA] = d.CreatedAt
+				}
+			}
+
+			summary.Deployments++
+			if c.ChangeFailure {
+				summary.ChangeFailures++
+			}
+			for _, r := range c.Reasons {
+				switch r {
+				case ReasonFailed:
+					summary.Failed++
+				case ReasonRevert:
+					summary.Reverts++
+				case ReasonHotfix:
+					summary.Hotfixes++
+				case ReasonRollback:
+					summary.Rollbacks++
+				}
+			}
+		}
+
+		if summary.Deployments > 0 {
+			summary.ChangeFailureRate = float64(summary.ChangeFailures) / float64(summary.Deployments)
+		}
+
+		// Report the newest deployments first.
+		for i := len(classified) - 1; i >= 0; i-- {
+			report.Deployments = append(report.Deployments, classified[i])
+		}
+		report.Summary = append(report.Summary, summary)
+	}
+
+	return report
+}
+
+// classifyChangeFailure classifies a single deployment. previous is the
+// deployment before it in the same environment or nil, firstDeployed holds the
+// time each SHA was first deployed to the same environment.
+func classifyChangeFailure(d *DeploymentWithCommits, previous *Deployment, firstDeployed map[string]time.Time, opts *ChangeFailureOptions) *DeploymentChangeFailure {
+	c := &DeploymentChangeFailure{Deployment: d.Deployment}
+
+	if d.Failed() {
+		c.Reasons = append(c.Reasons, ReasonFailed)
+	}
+
+	var isRevert, isHotfix bool
+
+	for _, commit := range d.DeployedCommits {
+		matched := false
+
+		if revertPattern.MatchString(commit.Message) {
+			isRevert, matched = true, true
+		}
+
+		for _, re := range opts.HotfixPatterns {
+			if re.MatchString(commit.Message) {
+				isHotfix, matched = true, true
+				break
+			}
+		}
+
+		if matched {
+			c.CommitSHAs = append(c.CommitSHAs, commit.SHA)
+		}
+	}
+
+	if isRevert {
+		c.Reasons = append(c.Reasons, ReasonRevert)
+	}
+
+	if isHotfix {
+		c.Reasons = append(c.Reasons, ReasonHotfix)
+	}
+
+	if isRollback(d.Deployment, previous, firstDeployed) {
+		c.Reasons = append(c.Reasons, ReasonRollback)
+	}
+
+	c.ChangeFailure = len(c.Reasons) > 0
+
+	return c
+}
+
+// isRollback reports whether the deployment shipped a different commit than
+// the previous deployment, which was either first deployed before or is older
+// than the commit of the previous deployment. Deploying a commit again after
+// rolling back from it is a roll-forward, not a rollback.
+func isRollback(d *Deployment, previous *Deployment, firstDeployed map[string]time.Time) bool {
+	if previous == nil || d.Commit == nil || previous.Commit == nil {
+		return false
+	}
+
+	if d.Commit.SHA == previous.Commit.SHA {
+		return false
+	}
+
+	deployedAt, ok := firstDeployed[d.Commit.SHA]
+	if previousDeployedAt, previousOk := firstDeployed[previous.Commit.SHA]; ok && previousOk && deployedAt.Before(previousDeployedAt) {
+		return true
+	}
+
+	if d.Commit.CommittedDate.IsZero() || previous.Commit.CommittedDate.IsZero() {
+		return false
+	}
+
+	return d.Commit.CommittedDate.Before(previous.Commit.CommittedDate)
+}
+
+// QueryChangeFailures fetches deployments with their commits and classifies
+// each deployment as a change failure or not.
+func QueryChangeFailures(
+	ctx context.Context,
+	repo *Repo,
+	d DeploymentsService, c CommitsComparisonService,
+	logger *slog.Logger,
+	opts *DeploymentWithCommitsOptions,
+	changeFailureOpts *ChangeFailureOptions,
+) (*ChangeFailureReport, error) {
+	deploymentsByEnv, err := QueryDeploymentsWithCommits(ctx, repo, d, c, logger, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error querying deployments with commits: %w", err)
+	}
+
+	report := ClassifyChangeFailures(deploymentsByEnv, changeFailureOpts)
+
+	logger.Debug(
+		"github.QueryChangeFailures: classified deployments",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("count", len(report.Deployments)),
+	)
+
+	return report, nil
+}
@@ -0,0 +1,121 @@
+package github_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestClassifyChangeFailures(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.May, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	v1 := &github.Commit{SHA: "1abc111", Message: "Add turtle", CommittedDate: day(1)}
+	v2 := &github.Commit{SHA: "2abc222", Message: "Add shell", CommittedDate: day(2)}
+	revert := &github.Commit{SHA: "3abc333", Message: "Revert \"Add shell\"\n\nThis reverts commit 2abc222.", CommittedDate: day(3)}
+	hotfix := &github.Commit{SHA: "4abc444", Message: "HOTFIX: feed turtle", CommittedDate: day(4)}
+
+	deployment := func(d int, state string, commit *github.Commit, deployed ...*github.Commit) *github.DeploymentWithCommits {
+		return &github.DeploymentWithCommits{
+			Deployment:      &github.Deployment{CreatedAt: day(d), State: state, Commit: commit},
+			DeployedCommits: deployed,
+		}
+	}
+
+	// Deployments are passed in descending order, like GitHub returns them.
+	deploymentsByEnv := map[string][]*github.DeploymentWithCommits{
+		// Deploying v2 again after rolling back to v1 is a roll-forward.
+		"dev": {
+			deployment(4, "ACTIVE", v2),
+			deployment(3, "INACTIVE", v1),
+			deployment(2, "INACTIVE", v2, v2),
+			deployment(1, "INACTIVE", v1, v1),
+		},
+		"prod": {
+			deployment(7, "ACTIVE", hotfix, hotfix),
+			deployment(6, "INACTIVE", v1),
+			deployment(5, "FAILURE", v2, v2),
+			deployment(4, "INACTIVE", revert, revert),
+			deployment(3, "INACTIVE", v2, v2),
+			deployment(1, "INACTIVE", v1, v1),
+		},
+		"stage": {
+			deployment(2, "ACTIVE", v2, v2),
+		},
+	}
+
+	opts, err := github.NewChangeFailureOptions(github.DefaultHotfixPatterns)
+	if err != nil {
+		t.Fatalf("NewChangeFailureOptions() returned unexpected error: %v", err)
+	}
+
+	report := github.ClassifyChangeFailures(deploymentsByEnv, opts)
+
+	type classification struct {
+		Env        string
+		CreatedAt  time.Time
+		Reasons    []github.ChangeFailureReason
+		CommitSHAs []string
+	}
+
+	var got []classification
+	for _, c := range report.Deployments {
+		if c.ChangeFailure != (len(c.Reasons) > 0) {
+			t.Errorf("ChangeFailure = %v for reasons %v", c.ChangeFailure, c.Reasons)
+		}
+		got = append(got, classification{Env: c.Env, CreatedAt: c.Deployment.CreatedAt, Reasons: c.Reasons, CommitSHAs: c.CommitSHAs})
+	}
+
+	want := []classification{
+		{Env: "dev", CreatedAt: day(4)},
+		{Env: "dev", CreatedAt: day(3), Reasons: []github.ChangeFailureReason{github.ReasonRollback}},
+		{Env: "dev", CreatedAt: day(2)},
+		{Env: "dev", CreatedAt: day(1)},
+		{Env: "prod", CreatedAt: day(7), Reasons: []github.ChangeFailureReason{github.ReasonHotfix}, CommitSHAs: []string{"4abc444"}},
+		{Env: "prod", CreatedAt: day(6), Reasons: []github.ChangeFailureReason{github.ReasonRollback}},
+		{Env: "prod", CreatedAt: day(5), Reasons: []github.ChangeFailureReason{github.ReasonFailed, github.ReasonRollback}},
+		{Env: "prod", CreatedAt: day(4), Reasons: []github.ChangeFailureReason{github.ReasonRevert}, CommitSHAs: []string{"3abc333"}},
+		{Env: "prod", CreatedAt: day(3)},
+		{Env: "prod", CreatedAt: day(1)},
+		{Env: "stage", CreatedAt: day(2)},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ClassifyChangeFailures() deployments mismatch (-want +got):\n%s", diff)
+	}
+
+	wantSummary := []*github.ChangeFailureSummary{
+		{Env: "dev", Deployments: 4, ChangeFailures: 1, ChangeFailureRate: 0.25, Rollbacks: 1},
+		{Env: "prod", Deployments: 6, ChangeFailures: 4, ChangeFailureRate: 4.0 / 6, Failed: 1, Reverts: 1, Hotfixes: 1, Rollbacks: 2},
+		{Env: "stage", Deployments: 1},
+	}
+
+	if diff := cmp.Diff(wantSummary, report.Summary); diff != "" {
+		t.Errorf("ClassifyChangeFailures() summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewChangeFailureOptions(t *testing.T) {
+	if _, err := github.NewChangeFailureOptions([]string{"hotfix("}); err == nil {
+		t.Errorf("NewChangeFailureOptions() with invalid pattern did not return an error")
+	}
+
+	opts, err := github.NewChangeFailureOptions([]string{`^\[urgent\]`})
+	if err != nil {
+		t.Fatalf("NewChangeFailureOptions() returned unexpected error: %v", err)
+	}
+
+	report := github.ClassifyChangeFailures(map[string][]*github.DeploymentWithCommits{
+		"prod": {{
+			Deployment:      &github.Deployment{State: "ACTIVE", Commit: &github.Commit{SHA: "1abc111"}},
+			DeployedCommits: []*github.Commit{{SHA: "1abc111", Message: "[urgent] feed turtle"}, {SHA: "2abc222", Message: "hotfix: not configured"}},
+		}},
+	}, opts)
+
+	if got := report.Deployments[0].CommitSHAs; !cmp.Equal(got, []string{"1abc111"}) {
+		t.Errorf("ClassifyChangeFailures() with custom pattern matched %v, want [1abc111]", got)
+	}
+}
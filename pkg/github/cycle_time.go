@@ -0,0 +1,219 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// PullRequestCycleTime breaks down the time from the first commit of a merged
+// PR to its merge into phases. Phases are nil if the PR is missing the data
+// for them, for example pickup time for PRs which were merged without review.
+type PullRequestCycleTime struct {
+	PullRequest *PullRequest `csv:"-"`
+
+	// Columns for the PR
+	_ struct{} `csv:"number,key=PullRequest.Number"`
+	_ struct{} `csv:"title,key=PullRequest.Title"`
+	_ struct{} `csv:"author,key=PullRequest.Author"`
+	_ struct{} `csv:"createdAt,key=PullRequest.CreatedAt"`
+	_ struct{} `csv:"mergedAt,key=PullRequest.MergedAt"`
+
+	// Time from the first commit to merging the PR, in hours
+	CycleTimeHours float64
+
+	// Time from the first commit to opening the PR, in hours
+	CodingHours *float64
+
+	// Time from opening the PR to the first review, in hours
+	PickupHours *float64
+
+	// Time from the first review to the first approval, in hours
+	ReviewHours *float64
+
+	// Time from the first approval to merging the PR, in hours
+	MergeHours *float64
+}
+
+// CycleTimePercentiles holds percentiles for a cycle time phase. Count is the
+// number of PRs with data for the phase.
+type CycleTimePercentiles struct {
+	Count    int
+	P50Hours float64
+	P75Hours float64
+	P90Hours float64
+}
+
+// CycleTimeSummary holds cycle time percentiles for PRs merged in a week.
+type CycleTimeSummary struct {
+	// Start of the week (Monday 00:00 UTC)
+	Week         time.Time `csv:",date"`
+	PullRequests int
+	CycleTime    CycleTimePercentiles
+	Coding       CycleTimePercentiles
+	Pickup       CycleTimePercentiles
+	Review       CycleTimePercentiles
+	Merge        CycleTimePercentiles
+}
+
+// CycleTimeReport holds the cycle time for each merged PR and summary
+// percentiles for each week.
+type CycleTimeReport struct {
+	PullRequests []*PullRequestCycleTime `csv:",rows"`
+	Summary      []*CycleTimeSummary     `csv:"-"`
+}
+
+// phaseDuration returns the time between start and end, or false if either is
+// unknown. Phases which end before they start, such as commits authored after
+// the PR was opened, take no time.
+func phaseDuration(start, end time.Time) (time.Duration, bool) {
+	if start.IsZero() || end.IsZero() {
+		return 0, false
+	}
+	if end.Before(start) {
+		return 0, true
+	}
+	return end.Sub(start), true
+}
+
+// hours returns the duration in hours.
+func hours(d time.Duration) *float64 {
+	h := d.Hours()
+	return &h
+}
+
+// weekStart returns the start of the week of t (Monday 00:00 UTC).
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// cycleTimeDurations collects the durations of each phase for percentiles.
+type cycleTimeDurations struct {
+	cycleTime, coding, pickup, review, merge []time.Duration
+}
+
+func cycleTimePercentiles(durations []time.Duration) CycleTimePercentiles {
+	return CycleTimePercentiles{
+		Count:    len(durations),
+		P50Hours: Percentile(durations, 50).Hours(),
+		P75Hours: Percentile(durations, 75).Hours(),
+		P90Hours: Percentile(durations, 90).Hours(),
+	}
+}
+
+// ComputeCycleTimes breaks down the cycle time of each merged PR and computes
+// percentiles for the PRs merged in each week. PRs which were not merged are
+// ignored. PRs and weeks are sorted by time in descending order.
+func ComputeCycleTimes(pullRequests []PullRequest) *CycleTimeReport {
+	report := &CycleTimeReport{}
+	durationsByWeek := make(map[time.Time]*cycleTimeDurations)
+
+	for i := range pullRequests {
+		pr := &pullRequests[i]
+		if pr.MergedAt.IsZero() {
+			continue
+		}
+
+		// The first commit may have been authored after the PR was opened.
+		start := pr.CreatedAt
+		if !pr.FirstCommitAt.IsZero() && pr.FirstCommitAt.Before(start) {
+			start = pr.FirstCommitAt
+		}
+		cycleTime, _ := phaseDuration(start, pr.MergedAt)
+
+		c := &PullRequestCycleTime{PullRequest: pr, CycleTimeHours: cycleTime.Hours()}
+
+		week := weekStart(pr.MergedAt)
+		durations, ok := durationsByWeek[week]
+		if !ok {
+			durations = &cycleTimeDurations{}
+			durationsByWeek[week] = durations
+		}
+		durations.cycleTime = append(durations.cycleTime, cycleTime)
+
+		if d, ok := phaseDuration(pr.FirstCommitAt, pr.CreatedAt); ok {
+			c.CodingHours = hours(d)
+			durations.coding = append(durations.coding, d)
+		}
+		if d, ok := phaseDuration(pr.CreatedAt, pr.FirstReviewAt); ok {
+			c.PickupHours = hours(d)
+			durations.pickup = append(durations.pickup, d)
+		}
+		if d, ok := phaseDuration(pr.FirstReviewAt, pr.ApprovedAt); ok {
+			c.ReviewHours = hours(d)
+			durations.review = append(durations.review, d)
+		}
+		if d, ok := phaseDuration(pr.ApprovedAt, pr.MergedAt); ok {
+			c.MergeHours = hours(d)
+			durations.merge = append(durations.merge, d)
+		}
+
+		report.PullRequests = append(report.PullRequests, c)
+	}
+
+	sort.SliceStable(report.PullRequests, func(i, j int) bool {
+		return report.PullRequests[i].PullRequest.MergedAt.After(report.PullRequests[j].PullRequest.MergedAt)
+	})
+
+	var weeks []time.Time
+	for week := range durationsByWeek {
+		weeks = append(weeks, week)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].After(weeks[j]) })
+
+	for _, week := range weeks {
+		durations := durationsByWeek[week]
+		report.Summary = append(report.Summary, &CycleTimeSummary{
+			Week:         week,
+			PullRequests: len(durations.cycleTime),
+			CycleTime:    cycleTimePercentiles(durations.cycleTime),
+			Coding:       cycleTimePercentiles(durations.coding),
+			Pickup:       cycleTimePercentiles(durations.pickup),
+			Review:       cycleTimePercentiles(durations.review),
+			Merge:        cycleTimePercentiles(durations.merge),
+		})
+	}
+
+	return report
+}
+
+// Options for the QueryCycleTimes function
+type CycleTimeOptions struct {
+	Limit  int
+	Window *TimeWindow
+}
+
+// QueryCycleTimes fetches the PRs merged last, or merged within the window,
+// and breaks down the cycle time of each PR.
+func QueryCycleTimes(
+	ctx context.Context,
+	repo *Repo,
+	p PullRequestsService,
+	logger *slog.Logger,
+	opts *CycleTimeOptions,
+) (*CycleTimeReport, error) {
+	pullRequests, err := p.QueryPullRequests(ctx, repo, &PullRequestsOpts{
+		Limit:   opts.Limit,
+		Window:  opts.Window,
+		States:  []PullRequestState{PullRequestStateMerged},
+		OrderBy: PullRequestOrderMerged,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying pull requests: %w", err)
+	}
+
+	report := ComputeCycleTimes(pullRequests)
+
+	logger.Debug(
+		"github.QueryCycleTimes: computed cycle times",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("count", len(report.PullRequests)),
+		slog.Int("weeks", len(report.Summary)),
+	)
+
+	return report, nil
+}
@@ -0,0 +1,91 @@
+package github_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestComputeCycleTimes(t *testing.T) {
+	// 2024-05-06 is a Monday.
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.May, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	pullRequests := []github.PullRequest{{
+		// Reviewed and approved
+		Number:        1,
+		FirstCommitAt: at(6, 8),
+		CreatedAt:     at(6, 10),
+		FirstReviewAt: at(6, 14),
+		ApprovedAt:    at(7, 10),
+		MergedAt:      at(7, 12),
+	}, {
+		// Merged without review, first commit pushed after opening the PR
+		Number:        2,
+		CreatedAt:     at(8, 10),
+		FirstCommitAt: at(8, 11),
+		MergedAt:      at(8, 16),
+	}, {
+		// Reviewed, but not approved, in the following week
+		Number:        3,
+		FirstCommitAt: at(13, 9),
+		CreatedAt:     at(13, 10),
+		FirstReviewAt: at(13, 11),
+		MergedAt:      at(13, 12),
+	}, {
+		// Not merged
+		Number:    4,
+		CreatedAt: at(13, 10),
+	}}
+
+	report := github.ComputeCycleTimes(pullRequests)
+
+	hours := func(h float64) *float64 { return &h }
+
+	type cycleTime struct {
+		Number         int
+		CycleTimeHours float64
+		CodingHours    *float64
+		PickupHours    *float64
+		ReviewHours    *float64
+		MergeHours     *float64
+	}
+
+	var got []cycleTime
+	for _, c := range report.PullRequests {
+		got = append(got, cycleTime{c.PullRequest.Number, c.CycleTimeHours, c.CodingHours, c.PickupHours, c.ReviewHours, c.MergeHours})
+	}
+
+	want := []cycleTime{
+		{Number: 3, CycleTimeHours: 3, CodingHours: hours(1), PickupHours: hours(1)},
+		{Number: 2, CycleTimeHours: 6, CodingHours: hours(0)},
+		{Number: 1, CycleTimeHours: 28, CodingHours: hours(2), PickupHours: hours(4), ReviewHours: hours(20), MergeHours: hours(2)},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ComputeCycleTimes() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantSummary := []*github.CycleTimeSummary{{
+		Week:         at(13, 0),
+		PullRequests: 1,
+		CycleTime:    github.CycleTimePercentiles{Count: 1, P50Hours: 3, P75Hours: 3, P90Hours: 3},
+		Coding:       github.CycleTimePercentiles{Count: 1, P50Hours: 1, P75Hours: 1, P90Hours: 1},
+		Pickup:       github.CycleTimePercentiles{Count: 1, P50Hours: 1, P75Hours: 1, P90Hours: 1},
+	}, {
+		Week:         at(6, 0),
+		PullRequests: 2,
+		CycleTime:    github.CycleTimePercentiles{Count: 2, P50Hours: 17, P75Hours: 22.5, P90Hours: 25.8},
+		Coding:       github.CycleTimePercentiles{Count: 2, P50Hours: 1, P75Hours: 1.5, P90Hours: 1.8},
+		Pickup:       github.CycleTimePercentiles{Count: 1, P50Hours: 4, P75Hours: 4, P90Hours: 4},
+		Review:       github.CycleTimePercentiles{Count: 1, P50Hours: 20, P75Hours: 20, P90Hours: 20},
+		Merge:        github.CycleTimePercentiles{Count: 1, P50Hours: 2, P75Hours: 2, P90Hours: 2},
+	}}
+
+	if diff := cmp.Diff(wantSummary, report.Summary); diff != "" {
+		t.Errorf("ComputeCycleTimes() summary mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // Options for the DeploymentsService
@@ -99,20 +103,36 @@ func QueryDeployedCommits(
 
 // Options for the DeploymentsService
 type DeploymentsOpts struct {
-	Envs  *[]string
-	Limit int
+	Envs   *[]string
+	Limit  int
+	Window *TimeWindow
 }
 
 // Options for the QueryDeploymentsWithCommits function
 type DeploymentWithCommitsOptions struct {
 	Deployments *DeploymentsOpts
 	Commits     *CommitsOpts
+
+	// Maximum number of concurrent commit comparisons. Values smaller than 1
+	// compare commits sequentially.
+	Concurrency int
+}
+
+// commitsComparison is a range of commits between two deployments.
+type commitsComparison struct {
+	deployment *DeploymentWithCommits
+	base       string
+	head       string
 }
 
 // QueryDeploymentsWithCommits fetches deployments across environments and
 // determines the commits deployed between each deployment and its previous one.
 // It uses DeploymentsService to fetch deployments and commit ranges, and
 // CommitsComparisonService to fetch commits for the identified ranges.
+// If the time window has a start, the oldest deployment of each environment is
+// compared to the latest deployment before the window, which is not returned.
+// Commit ranges are compared concurrently, up to opts.Concurrency at a time.
+// The first failed comparison cancels the remaining ones.
 func QueryDeploymentsWithCommits(
 	ctx context.Context,
 	repo *Repo,
@@ -134,7 +154,7 @@ func QueryDeploymentsWithCommits(
 		),
 	)
 
-	deployments, err := d.QueryDeployments(ctx, repo, opts.Deployments.Envs, opts.Deployments.Limit)
+	deployments, err := d.QueryDeployments(ctx, repo, opts.Deployments.Envs, opts.Deployments.Limit, opts.Deployments.Window)
 	if err != nil {
 		return nil, fmt.Errorf("error querying deployments: %w", err)
 	}
@@ -160,17 +180,77 @@ func QueryDeploymentsWithCommits(
 		slog.Group("deployments", envCounts...),
 	)
 
-	for _, envDeployments := range deploysWithCommitsByEnv {
+	// Sort environments so that comparisons are started in a stable order.
+	envs := make([]string, 0, len(deploysWithCommitsByEnv))
+	for env := range deploysWithCommitsByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	bases, err := queryBaseDeployments(ctx, repo, d, envs, opts.Deployments.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	var comparisons []*commitsComparison
+
+	for _, env := range envs {
+		envDeployments := deploysWithCommitsByEnv[env]
+
 		for i := 0; i < len(envDeployments)-1; i++ {
-			// current deployment
-			head := envDeployments[i].Commit.SHA
+			comparisons = append(comparisons, &commitsComparison{
+				deployment: envDeployments[i],
+				// current deployment
+				head: envDeployments[i].Commit.SHA,
+				// previous deployment
+				base: envDeployments[i+1].Commit.SHA,
+			})
+		}
+
+		lastDeployment := envDeployments[len(envDeployments)-1]
+
+		base, ok := bases[env]
+		if !ok {
+			// Without a previous deployment, the oldest deployment only
+			// deploys its own commit.
+			lastDeployment.DeployedCommits = []*Commit{lastDeployment.Commit}
+			continue
+		}
 
-			// previous deployment
-			base := envDeployments[i+1].Commit.SHA
+		comparisons = append(comparisons, &commitsComparison{
+			deployment: lastDeployment,
+			head:       lastDeployment.Commit.SHA,
+			base:       base.Commit.SHA,
+		})
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	logger.Debug(
+		"github.QueryDeploymentsWithCommits: comparing commits",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("comparisons", len(comparisons)),
+		slog.Int("concurrency", concurrency),
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+
+	for _, job := range comparisons {
+		job := job // Create a copy to avoid referencing the same loop variable memory.
 
-			comparison, err := c.CompareCommits(ctx, repo, base, head, opts.Commits.Limit)
+		g.Go(func() error {
+			// Skip remaining comparisons once a comparison has failed.
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+
+			comparison, err := c.CompareCommits(gctx, repo, job.base, job.head, opts.Commits.Limit)
 			if err != nil {
-				return nil, fmt.Errorf("error comparing commits for commits %s..%s: %w", base, head, err)
+				return fmt.Errorf("error comparing commits for commits %s..%s: %w", job.base, job.head, err)
 			}
 
 			logger.Debug(
@@ -178,18 +258,103 @@ func QueryDeploymentsWithCommits(
 				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
 				slog.Int("count", len(comparison.Commits)),
 				slog.Group("head",
-					slog.String("commit.SHA", head),
+					slog.String("commit.SHA", job.head),
 				),
 				slog.Group("base",
-					slog.String("commit.SHA", base),
+					slog.String("commit.SHA", job.base),
 				),
 			)
-			envDeployments[i].DeployedCommits = comparison.Commits
-		}
 
-		lastDeployment := envDeployments[len(envDeployments)-1]
-		lastDeployment.DeployedCommits = []*Commit{lastDeployment.Commit}
+			// Each comparison writes to its own deployment, which keeps the
+			// output order independent of the order comparisons complete in.
+			job.deployment.DeployedCommits = comparison.Commits
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	return deploysWithCommitsByEnv, nil
 }
+
+// queryBaseDeployments fetches the latest deployment before the start of the
+// time window for each of the given environments. Deployments are grouped by
+// their latest environment, so deployments of other environments are ignored.
+// It returns no deployments if the window has no start.
+func queryBaseDeployments(ctx context.Context, repo *Repo, d DeploymentsService, envs []string, window *TimeWindow) (map[string]*Deployment, error) {
+	bases := make(map[string]*Deployment)
+
+	if window == nil || window.Since.IsZero() {
+		return bases, nil
+	}
+
+	// Both ends of the window are inclusive, so end the window for the base
+	// just before the start of the given window.
+	before := &TimeWindow{Until: window.Since.Add(-time.Nanosecond)}
+
+	for _, env := range envs {
+		deployments, err := d.QueryDeployments(ctx, repo, &[]string{env}, 1, before)
+		if err != nil {
+			return nil, fmt.Errorf("error querying deployment before %s: %w", window.Since.Format(time.RFC3339), err)
+		}
+
+		if len(deployments) > 0 && deployments[0].LatestEnvironment == env {
+			bases[env] = &deployments[0]
+		}
+	}
+
+	return bases, nil
+}
+
+// QueryDeployedPullRequests fetches the merged pull requests associated with
+// the deployed commits of the given deployments and sets them on each commit.
+// The commits of all deployments are looked up together, which allows the
+// CommitPullRequestsService to batch requests.
+func QueryDeployedPullRequests(
+	ctx context.Context,
+	repo *Repo,
+	p CommitPullRequestsService,
+	logger *slog.Logger,
+	deployments ...*DeploymentWithCommits,
+) error {
+	var shas []string
+	seen := make(map[string]bool)
+
+	for _, d := range deployments {
+		for _, commit := range d.DeployedCommits {
+			if !seen[commit.SHA] {
+				seen[commit.SHA] = true
+				shas = append(shas, commit.SHA)
+			}
+		}
+	}
+
+	logger.Debug(
+		"github.QueryDeployedPullRequests: querying pull requests for deployed commits",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.String("github.CommitPullRequestsService", fmt.Sprintf("%T", p)),
+		slog.Int("commits", len(shas)),
+	)
+
+	pullRequestsBySHA, err := p.QueryCommitPullRequests(ctx, repo, shas)
+	if err != nil {
+		return fmt.Errorf("error querying pull requests for deployed commits: %w", err)
+	}
+
+	for _, d := range deployments {
+		for _, commit := range d.DeployedCommits {
+			commit.PullRequests = pullRequestsBySHA[commit.SHA]
+		}
+	}
+
+	logger.Debug(
+		"github.QueryDeployedPullRequests: found pull requests for deployed commits",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("commits", len(pullRequestsBySHA)),
+	)
+
+	return nil
+}
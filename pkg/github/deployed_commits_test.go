@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,8 +16,8 @@ import (
 	ghrest "github.com/google/go-github/v68/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/graphql"
-	"github.com/mozilla-services/rapid-release-model/pkg/github/internal/test"
 	"github.com/mozilla-services/rapid-release-model/pkg/github/rest"
+	"github.com/mozilla-services/rapid-release-model/pkg/internal/test"
 )
 
 func TestQueryDeployedCommits(t *testing.T) {
@@ -241,6 +244,348 @@ func TestQueryDeployedCommits(t *testing.T) {
 	}
 }
 
+func TestQueryDeploymentsWithCommits(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	graphQLClient := test.NewFakeGraphQLClient()
+	graphQLAPI := graphql.NewGitHubGraphQLAPI(graphQLClient, logger)
+	registerGraphQLresponses(t, graphQLClient)
+
+	restClient := test.NewFakeGitHubRESTClient()
+	restAPI := rest.NewGitHubRESTAPI(restClient, logger)
+	registerRESTresponses(t, restClient)
+
+	// REST client which fails to compare 3abc111..2abc111
+	failingRESTClient := test.NewFakeGitHubRESTClient()
+	failingRESTAPI := rest.NewGitHubRESTAPI(failingRESTClient, logger)
+	registerRESTresponses(t, failingRESTClient)
+	failingRESTClient.RegisterCommitComparison(
+		test.RESTCommitComparisonQueryKey{
+			RepoOwner: "hackebrot",
+			RepoName:  "turtle",
+			Base:      "3abc111ccccccccccc",
+			Head:      "2abc111bbbbbbbbbbb",
+			Page:      1,
+		},
+		&test.RESTCommitComparisonResponse{Err: errors.New("nope")},
+	)
+
+	// Deployed commit SHAs for each deployment by environment.
+	want := map[string][][]string{
+		"stage": {
+			{"1abc111aaaaaaaaaaa", "2abc111bbbbbbbbbbb"},
+			{"2abc111bbbbbbbbbbb", "5abc111yyyyyyyyyyy", "8abc222eeeeeeeeeee", "3abc111ccccccccccc"},
+			{"3abc111ccccccccccc"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		c           github.CommitsComparisonService
+		concurrency int
+		want        map[string][][]string
+		errContains string
+	}{
+		{name: "sequential", c: restAPI, concurrency: 0, want: want},
+		{name: "concurrency__1", c: restAPI, concurrency: 1, want: want},
+		{name: "concurrency__2", c: restAPI, concurrency: 2, want: want},
+		{name: "concurrency__8", c: restAPI, concurrency: 8, want: want},
+		{
+			name:        "error",
+			c:           failingRESTAPI,
+			concurrency: 4,
+			errContains: "error comparing commits for commits 3abc111ccccccccccc..2abc111bbbbbbbbbbb: failed to fetch commit comparison: nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := []string{"stage"}
+			opts := &github.DeploymentWithCommitsOptions{
+				Deployments: &github.DeploymentsOpts{Envs: &envs, Limit: 10},
+				Commits:     &github.CommitsOpts{Limit: 250},
+				Concurrency: tt.concurrency,
+			}
+
+			got, err := github.QueryDeploymentsWithCommits(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, graphQLAPI, tt.c, logger, opts)
+
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("QueryDeploymentsWithCommits() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("QueryDeploymentsWithCommits() returned unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, deployedSHAs(got)); diff != "" {
+				t.Errorf("QueryDeploymentsWithCommits() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQueryDeploymentsWithCommitsConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	d := &fakeDeploymentsService{}
+	for i := 20; i > 0; i-- {
+		d.deployments = append(d.deployments, github.Deployment{
+			LatestEnvironment: "prod",
+			CreatedAt:         time.Date(2022, time.May, i, 0, 0, 0, 0, time.UTC),
+			Commit:            &github.Commit{SHA: fmt.Sprintf("sha%02d", i)},
+		})
+	}
+
+	envs := []string{"prod"}
+	opts := &github.DeploymentWithCommitsOptions{
+		Deployments: &github.DeploymentsOpts{Envs: &envs, Limit: 20},
+		Commits:     &github.CommitsOpts{Limit: 250},
+		Concurrency: 3,
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		c := &fakeComparisonService{delay: 5 * time.Millisecond}
+
+		got, err := github.QueryDeploymentsWithCommits(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, d, c, logger, opts)
+		if err != nil {
+			t.Fatalf("QueryDeploymentsWithCommits() returned unexpected error: %v", err)
+		}
+
+		if c.maxInFlight > opts.Concurrency {
+			t.Errorf("QueryDeploymentsWithCommits() ran %d comparisons concurrently, want at most %d", c.maxInFlight, opts.Concurrency)
+		}
+
+		// Each deployment is followed by the deployment before it, regardless
+		// of the order in which comparisons completed.
+		for i, deployment := range got["prod"][:len(got["prod"])-1] {
+			wantBase := got["prod"][i+1].Commit.SHA
+			if gotBase := deployment.DeployedCommits[1].SHA; gotBase != wantBase {
+				t.Errorf("deployment %s has base %s, want %s", deployment.Commit.SHA, gotBase, wantBase)
+			}
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		c := &fakeComparisonService{delay: time.Minute, failHead: "sha19"}
+
+		done := make(chan error)
+		go func() {
+			_, err := github.QueryDeploymentsWithCommits(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, d, c, logger, opts)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), "error comparing commits for commits sha18..sha19: nope") {
+				t.Fatalf("QueryDeploymentsWithCommits() error = %v, want error for sha18..sha19", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("QueryDeploymentsWithCommits() did not cancel pending comparisons after an error")
+		}
+
+		if c.calls > opts.Concurrency {
+			t.Errorf("QueryDeploymentsWithCommits() started %d comparisons after an error, want at most %d", c.calls, opts.Concurrency)
+		}
+	})
+}
+
+func TestQueryDeploymentsWithCommitsBase(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	d := &fakeDeploymentsService{}
+	for i := 5; i > 0; i-- {
+		d.deployments = append(d.deployments, github.Deployment{
+			LatestEnvironment: "prod",
+			CreatedAt:         time.Date(2022, time.May, i, 0, 0, 0, 0, time.UTC),
+			Commit:            &github.Commit{SHA: fmt.Sprintf("sha%02d", i)},
+		})
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  map[string][][]string
+	}{
+		{
+			// The oldest deployment in the window is compared to the latest
+			// deployment before the window, which is not returned.
+			name:  "base",
+			since: time.Date(2022, time.May, 3, 0, 0, 0, 0, time.UTC),
+			want: map[string][][]string{
+				"prod": {{"sha05", "sha04"}, {"sha04", "sha03"}, {"sha03", "sha02"}},
+			},
+		},
+		{
+			// Without a deployment before the window, the oldest deployment
+			// only deploys its own commit.
+			name:  "no_base",
+			since: time.Date(2022, time.May, 1, 0, 0, 0, 0, time.UTC),
+			want: map[string][][]string{
+				"prod": {{"sha05", "sha04"}, {"sha04", "sha03"}, {"sha03", "sha02"}, {"sha02", "sha01"}, {"sha01"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := []string{"prod"}
+			opts := &github.DeploymentWithCommitsOptions{
+				Deployments: &github.DeploymentsOpts{Envs: &envs, Limit: 10, Window: &github.TimeWindow{Since: tt.since}},
+				Commits:     &github.CommitsOpts{Limit: 250},
+			}
+
+			got, err := github.QueryDeploymentsWithCommits(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, d, &fakeComparisonService{}, logger, opts)
+			if err != nil {
+				t.Fatalf("QueryDeploymentsWithCommits() returned unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, deployedSHAs(got)); diff != "" {
+				t.Errorf("QueryDeploymentsWithCommits() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQueryDeployedPullRequests(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	shared := &github.Commit{SHA: "1abc111"}
+
+	deployments := []*github.DeploymentWithCommits{
+		{DeployedCommits: []*github.Commit{{SHA: "3abc333"}, {SHA: "2abc222"}, shared}},
+		{DeployedCommits: []*github.Commit{shared}},
+	}
+
+	p := &fakeCommitPullRequestsService{prs: map[string][]github.PullRequest{
+		"1abc111": {{Number: 1}},
+		"3abc333": {{Number: 2}, {Number: 3}},
+	}}
+
+	if err := github.QueryDeployedPullRequests(ctx, repo, p, logger, deployments...); err != nil {
+		t.Fatalf("QueryDeployedPullRequests() returned unexpected error: %v", err)
+	}
+
+	// Commits are looked up once, in a single request.
+	if diff := cmp.Diff([][]string{{"3abc333", "2abc222", "1abc111"}}, p.requests); diff != "" {
+		t.Errorf("QueryDeployedPullRequests() requests mismatch (-want +got):\n%s", diff)
+	}
+
+	got := make(map[string][]int)
+	for _, d := range deployments {
+		for _, c := range d.DeployedCommits {
+			for _, pr := range c.PullRequests {
+				got[c.SHA] = append(got[c.SHA], pr.Number)
+			}
+		}
+	}
+
+	want := map[string][]int{
+		"1abc111": {1, 1},
+		"3abc333": {2, 3},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("QueryDeployedPullRequests() mismatch (-want +got):\n%s", diff)
+	}
+
+	p.err = errors.New("nope")
+	if err := github.QueryDeployedPullRequests(ctx, repo, p, logger, deployments...); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("QueryDeployedPullRequests() error = %v, want error from service", err)
+	}
+}
+
+// deployedSHAs returns the deployed commit SHAs for each deployment by
+// environment.
+func deployedSHAs(deploymentsByEnv map[string][]*github.DeploymentWithCommits) map[string][][]string {
+	shas := make(map[string][][]string)
+	for env, deployments := range deploymentsByEnv {
+		for _, d := range deployments {
+			var commits []string
+			for _, c := range d.DeployedCommits {
+				commits = append(commits, c.SHA)
+			}
+			shas[env] = append(shas[env], commits)
+		}
+	}
+	return shas
+}
+
+// fakeDeploymentsService returns the deployments of the given environments
+// within the window for every query, up to the limit. Deployments must be
+// sorted by time in descending order.
+type fakeDeploymentsService struct {
+	deployments []github.Deployment
+}
+
+func (f *fakeDeploymentsService) QueryDeployments(ctx context.Context, repo *github.Repo, envs *[]string, limit int, window *github.TimeWindow) ([]github.Deployment, error) {
+	var deployments []github.Deployment
+	for _, d := range f.deployments {
+		if len(deployments) == limit {
+			break
+		}
+		if slices.Contains(*envs, d.LatestEnvironment) && window.Contains(d.CreatedAt) {
+			deployments = append(deployments, d)
+		}
+	}
+	return deployments, nil
+}
+
+// fakeComparisonService returns the head and base commits for each comparison
+// after the given delay and records how many comparisons ran concurrently.
+// Comparisons for failHead fail immediately.
+type fakeComparisonService struct {
+	delay    time.Duration
+	failHead string
+
+	mu          sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeComparisonService) CompareCommits(ctx context.Context, repo *github.Repo, base string, head string, limit int) (*github.CommitsComparison, error) {
+	f.mu.Lock()
+	f.calls++
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	if head == f.failHead {
+		return nil, errors.New("nope")
+	}
+
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &github.CommitsComparison{
+		TotalCommits: 2,
+		Commits:      []*github.Commit{{SHA: head}, {SHA: base}},
+	}, nil
+}
+
 func registerGraphQLresponses(t *testing.T, c *test.FakeGraphQLClient) {
 	t.Helper()
 
@@ -293,18 +638,21 @@ func registerGraphQLresponses(t *testing.T, c *test.FakeGraphQLClient) {
 		}
 	}`
 
-	c.RegisterResponse(
-		test.GraphQLQueryKey{
-			QueryType: "*graphql.DeploymentQuery",
-			RepoOwner: "hackebrot",
-			RepoName:  "turtle",
-			Extra:     test.GraphQLQueryKeyExtra{Environments: "stage"},
-			EndCursor: "",
-		},
-		&test.GraphQLResponse{
-			Content: deploymentsJsonData1,
-		},
-	)
+	// DeploymentQuery and DeploymentsQuery share the same response format.
+	for _, queryType := range []string{"*graphql.DeploymentQuery", "*graphql.DeploymentsQuery"} {
+		c.RegisterResponse(
+			test.GraphQLQueryKey{
+				QueryType: queryType,
+				RepoOwner: "hackebrot",
+				RepoName:  "turtle",
+				Extra:     test.GraphQLQueryKeyExtra{Environments: "stage"},
+				EndCursor: "",
+			},
+			&test.GraphQLResponse{
+				Content: deploymentsJsonData1,
+			},
+		)
+	}
 
 	deploymentsJsonData2 := `{
 	    "Repository": {
@@ -339,18 +687,21 @@ func registerGraphQLresponses(t *testing.T, c *test.FakeGraphQLClient) {
 		}
 	}`
 
-	c.RegisterResponse(
-		test.GraphQLQueryKey{
-			QueryType: "*graphql.DeploymentQuery",
-			RepoOwner: "hackebrot",
-			RepoName:  "turtle",
-			Extra:     test.GraphQLQueryKeyExtra{Environments: "stage"},
-			EndCursor: "abc123",
-		},
-		&test.GraphQLResponse{
-			Content: deploymentsJsonData2,
-		},
-	)
+	// DeploymentQuery and DeploymentsQuery share the same response format.
+	for _, queryType := range []string{"*graphql.DeploymentQuery", "*graphql.DeploymentsQuery"} {
+		c.RegisterResponse(
+			test.GraphQLQueryKey{
+				QueryType: queryType,
+				RepoOwner: "hackebrot",
+				RepoName:  "turtle",
+				Extra:     test.GraphQLQueryKeyExtra{Environments: "stage"},
+				EndCursor: "abc123",
+			},
+			&test.GraphQLResponse{
+				Content: deploymentsJsonData2,
+			},
+		)
+	}
 
 	deploymentsJsonData3 := `{
 	    "Repository": {
@@ -523,3 +874,19 @@ func registerRESTresponses(t *testing.T, c *test.FakeGitHubRESTClient) {
 	)
 
 }
+
+// fakeCommitPullRequestsService returns canned pull requests by commit SHA and
+// records the SHAs of each request.
+type fakeCommitPullRequestsService struct {
+	prs      map[string][]github.PullRequest
+	err      error
+	requests [][]string
+}
+
+func (f *fakeCommitPullRequestsService) QueryCommitPullRequests(ctx context.Context, repo *github.Repo, shas []string) (map[string][]github.PullRequest, error) {
+	f.requests = append(f.requests, shas)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.prs, nil
+}
@@ -0,0 +1,76 @@
+package github
+
+import "sort"
+
+// failedState reports whether the state of a deployment or deployment status
+// marks it as failed.
+func failedState(state string) bool {
+	return state == "FAILURE" || state == "ERROR"
+}
+
+// Failed reports whether GitHub marked the deployment as failed.
+func (d *Deployment) Failed() bool {
+	return failedState(d.State)
+}
+
+// LogURL returns the most recent log URL of the status history.
+func (d *Deployment) LogURL() string {
+	var url string
+	for _, s := range d.Statuses {
+		if s.LogURL != "" {
+			url = s.LogURL
+		}
+	}
+	return url
+}
+
+// EnvironmentURL returns the most recent environment URL of the status
+// history.
+func (d *Deployment) EnvironmentURL() string {
+	var url string
+	for _, s := range d.Statuses {
+		if s.EnvironmentURL != "" {
+			url = s.EnvironmentURL
+		}
+	}
+	return url
+}
+
+// SetStatuses sets the status history of the deployment in ascending order
+// and derives its duration and number of failed attempts from it.
+func (d *Deployment) SetStatuses(statuses []DeploymentStatus) {
+	sorted := make([]DeploymentStatus, len(statuses))
+	copy(sorted, statuses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	if len(sorted) == 0 {
+		sorted = nil
+	}
+
+	d.Statuses = sorted
+	d.FailedAttempts = 0
+	d.DurationSeconds = nil
+
+	var finishedStatus *DeploymentStatus
+
+	for i, s := range sorted {
+		switch {
+		case s.State == "SUCCESS":
+			if finishedStatus == nil || finishedStatus.State != "SUCCESS" {
+				finishedStatus = &sorted[i]
+			}
+		case failedState(s.State):
+			d.FailedAttempts++
+			if finishedStatus == nil || finishedStatus.State != "SUCCESS" {
+				finishedStatus = &sorted[i]
+			}
+		}
+	}
+
+	if finishedStatus != nil {
+		seconds := finishedStatus.CreatedAt.Sub(d.CreatedAt).Seconds()
+		d.DurationSeconds = &seconds
+	}
+}
@@ -0,0 +1,91 @@
+package github_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestDeploymentSetStatuses(t *testing.T) {
+	createdAt := time.Date(2024, time.May, 6, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return createdAt.Add(time.Duration(minutes) * time.Minute)
+	}
+	seconds := func(s float64) *float64 { return &s }
+
+	tests := []struct {
+		name               string
+		states             []string
+		wantStates         []string
+		wantDuration       *float64
+		wantFailedAttempts int
+	}{{
+		name:         "success",
+		states:       []string{"SUCCESS", "IN_PROGRESS", "QUEUED"},
+		wantStates:   []string{"QUEUED", "IN_PROGRESS", "SUCCESS"},
+		wantDuration: seconds(120),
+	}, {
+		name:               "retried",
+		states:             []string{"INACTIVE", "SUCCESS", "IN_PROGRESS", "FAILURE", "QUEUED"},
+		wantStates:         []string{"QUEUED", "FAILURE", "IN_PROGRESS", "SUCCESS", "INACTIVE"},
+		wantDuration:       seconds(180),
+		wantFailedAttempts: 1,
+	}, {
+		name:               "failed",
+		states:             []string{"ERROR", "FAILURE", "QUEUED"},
+		wantStates:         []string{"QUEUED", "FAILURE", "ERROR"},
+		wantDuration:       seconds(120),
+		wantFailedAttempts: 2,
+	}, {
+		name:       "in progress",
+		states:     []string{"IN_PROGRESS", "QUEUED"},
+		wantStates: []string{"QUEUED", "IN_PROGRESS"},
+	}, {
+		name: "no statuses",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Statuses are returned newest first.
+			var statuses []github.DeploymentStatus
+			for i, state := range tt.states {
+				statuses = append(statuses, github.DeploymentStatus{State: state, CreatedAt: at(len(tt.states) - 1 - i)})
+			}
+
+			d := &github.Deployment{CreatedAt: createdAt}
+			d.SetStatuses(statuses)
+
+			var gotStates []string
+			for _, s := range d.Statuses {
+				gotStates = append(gotStates, s.State)
+			}
+
+			if diff := cmp.Diff(tt.wantStates, gotStates); diff != "" {
+				t.Errorf("SetStatuses() states mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantDuration, d.DurationSeconds); diff != "" {
+				t.Errorf("SetStatuses() duration mismatch (-want +got):\n%s", diff)
+			}
+			if d.FailedAttempts != tt.wantFailedAttempts {
+				t.Errorf("SetStatuses() failed attempts = %d, want %d", d.FailedAttempts, tt.wantFailedAttempts)
+			}
+		})
+	}
+}
+
+func TestDeploymentFailed(t *testing.T) {
+	for state, want := range map[string]bool{
+		"FAILURE":  true,
+		"ERROR":    true,
+		"ACTIVE":   false,
+		"INACTIVE": false,
+		"SUCCESS":  false,
+	} {
+		d := &github.Deployment{State: state}
+		if got := d.Failed(); got != want {
+			t.Errorf("Failed() for state %s = %v, want %v", state, got, want)
+		}
+	}
+}
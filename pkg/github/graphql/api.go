@@ -20,12 +20,14 @@ type API struct {
 // If API fails to satisfy any of these interfaces, the compiler will produce an error.
 // This approach enforces interface compliance without requiring runtime checks.
 var (
-	_ github.DeploymentsService   = (*API)(nil)
-	_ github.DeploymentService    = (*API)(nil)
-	_ github.PullRequestsService  = (*API)(nil)
-	_ github.ReleasesService      = (*API)(nil)
-	_ github.RefComparisonService = (*API)(nil)
-	_ github.HistoryService       = (*API)(nil)
+	_ github.DeploymentsService            = (*API)(nil)
+	_ github.DeploymentService             = (*API)(nil)
+	_ github.PullRequestsService           = (*API)(nil)
+	_ github.ReleasesService               = (*API)(nil)
+	_ github.RefComparisonService          = (*API)(nil)
+	_ github.HistoryService                = (*API)(nil)
+	_ github.AssociatedPullRequestsService = (*API)(nil)
+	_ github.CommitPullRequestsService     = (*API)(nil)
 )
 
 // Client is satisfied by the the githubv4.Client.
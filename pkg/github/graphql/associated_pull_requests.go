@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// CommitWithPullRequests represents a GitHub GraphQL API Commit along with the
+// pull requests it is associated with.
+// See https://docs.github.com/en/graphql/reference/objects#commit
+type CommitWithPullRequests struct {
+	Oid                    githubv4.GitObjectID
+	AssociatedPullRequests struct {
+		Nodes []PullRequest
+	} `graphql:"associatedPullRequests(first: 5)"`
+}
+
+// GraphQL query for the pull requests associated with the commits between two
+// refs
+type AssociatedPullRequestsQuery struct {
+	Repository struct {
+		Name  string
+		Owner struct {
+			Login string
+		}
+		Ref struct {
+			Compare struct {
+				Commits struct {
+					Nodes      []CommitWithPullRequests
+					TotalCount int
+					PageInfo   struct {
+						HasNextPage bool
+						EndCursor   string
+					}
+				} `graphql:"commits(first: $perPage, after: $endCursor)"`
+			} `graphql:"compare(headRef: $headRef)"`
+		} `graphql:"ref(qualifiedName: $baseRef)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// QueryAssociatedPullRequests fetches the merged pull requests associated with
+// up to limit commits between the base and head refs. Pull requests are sorted
+// by number. If there are no commits between the refs, for example because a
+// ref does not exist, it returns no pull requests.
+func (a *API) QueryAssociatedPullRequests(ctx context.Context, repo *github.Repo, base string, head string, limit int) ([]github.PullRequest, error) {
+	perPage := limit
+	if limit > 100 {
+		perPage = 100
+	}
+
+	queryVariables := map[string]interface{}{
+		"owner":     githubv4.String(repo.Owner),
+		"name":      githubv4.String(repo.Name),
+		"baseRef":   githubv4.String(base),
+		"headRef":   githubv4.String(head),
+		"perPage":   githubv4.Int(perPage),
+		"endCursor": (*githubv4.String)(nil), // When paginating forwards, the cursor to continue.
+	}
+
+	pullRequests := make(map[int]*github.PullRequest)
+	commits := 0
+
+Loop:
+	for {
+		var query AssociatedPullRequestsQuery
+
+		err := a.client.Query(ctx, &query, queryVariables)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range query.Repository.Ref.Compare.Commits.Nodes {
+			for _, p := range c.AssociatedPullRequests.Nodes {
+				// Commits are also associated with open pull requests from
+				// other branches which contain them.
+				if p.MergedAt.IsZero() {
+					continue
+				}
+				pullRequests[p.Number] = ConvertPullRequest(&p)
+			}
+
+			commits++
+			if commits == limit {
+				break Loop
+			}
+		}
+
+		if !query.Repository.Ref.Compare.Commits.PageInfo.HasNextPage {
+			break
+		}
+
+		queryVariables["endCursor"] = githubv4.String(query.Repository.Ref.Compare.Commits.PageInfo.EndCursor)
+	}
+
+	var result []github.PullRequest
+	for _, p := range pullRequests {
+		result = append(result, *p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Number < result[j].Number
+	})
+
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// commitPullRequestsBatchSize is the maximum number of commits looked up in a
+// single query.
+const commitPullRequestsBatchSize = 50
+
+// CommitPullRequestsObject represents a GitHub GraphQL API GitObject, which is
+// a Commit when looked up by the SHA of a commit.
+// See https://docs.github.com/en/graphql/reference/interfaces#gitobject
+type CommitPullRequestsObject struct {
+	Commit CommitWithPullRequests `graphql:"... on Commit"`
+}
+
+// NewCommitPullRequestsQuery returns a GraphQL query for the pull requests
+// associated with n commits. The GraphQL API cannot look up a list of commits
+// by SHA, so the query has a field `c<i>: object(oid: $oid<i>)` for each
+// commit. The fields of the query's Repository are of type
+// CommitPullRequestsObject.
+func NewCommitPullRequestsQuery(n int) interface{} {
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("C%d", i),
+			Type: reflect.TypeOf(CommitPullRequestsObject{}),
+			Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"c%d: object(oid: $oid%d)"`, i, i)),
+		}
+	}
+
+	query := reflect.StructOf([]reflect.StructField{{
+		Name: "Repository",
+		Type: reflect.StructOf(fields),
+		Tag:  `graphql:"repository(owner: $owner, name: $name)"`,
+	}})
+
+	return reflect.New(query).Interface()
+}
+
+// QueryCommitPullRequests fetches the merged pull requests associated with
+// each of the given commits. Commits are looked up in batches. The result maps
+// commit SHAs to pull requests sorted by number. Commits without merged pull
+// requests are not included in the result.
+func (a *API) QueryCommitPullRequests(ctx context.Context, repo *github.Repo, shas []string) (map[string][]github.PullRequest, error) {
+	pullRequests := make(map[string][]github.PullRequest)
+
+	for start := 0; start < len(shas); start += commitPullRequestsBatchSize {
+		end := start + commitPullRequestsBatchSize
+		if end > len(shas) {
+			end = len(shas)
+		}
+		batch := shas[start:end]
+
+		queryVariables := map[string]interface{}{
+			"owner": githubv4.String(repo.Owner),
+			"name":  githubv4.String(repo.Name),
+		}
+		for i, sha := range batch {
+			queryVariables[fmt.Sprintf("oid%d", i)] = githubv4.GitObjectID(sha)
+		}
+
+		query := NewCommitPullRequestsQuery(len(batch))
+
+		err := a.client.Query(ctx, query, queryVariables)
+		if err != nil {
+			return nil, err
+		}
+
+		repository := reflect.ValueOf(query).Elem().Field(0)
+
+		for i, sha := range batch {
+			object := repository.Field(i).Interface().(CommitPullRequestsObject)
+
+			for _, p := range object.Commit.AssociatedPullRequests.Nodes {
+				// Commits are also associated with open pull requests from
+				// other branches which contain them.
+				if p.MergedAt.IsZero() {
+					continue
+				}
+				pullRequests[sha] = append(pullRequests[sha], *ConvertPullRequest(&p))
+			}
+
+			sort.Slice(pullRequests[sha], func(i, j int) bool {
+				return pullRequests[sha][i].Number < pullRequests[sha][j].Number
+			})
+		}
+	}
+
+	return pullRequests, nil
+}
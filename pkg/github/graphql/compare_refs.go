@@ -8,7 +8,8 @@ import (
 	"github.com/shurcooL/githubv4"
 )
 
-type compareQuery struct {
+// GraphQL query for the commits between two refs
+type CompareQuery struct {
 	Repository struct {
 		Name  string
 		Owner struct {
@@ -48,7 +49,7 @@ func (a *API) QueryCompareRefs(ctx context.Context, repo *github.Repo, base stri
 Loop:
 	for {
 
-		var query compareQuery
+		var query CompareQuery
 
 		err := a.client.Query(ctx, &query, queryVariables)
 		if err != nil {
@@ -29,7 +29,7 @@ func ConvertCommit(c *Commit) *github.Commit {
 
 // Convert a GraphQL API Deployment to the unified Deployment model.
 func ConvertDeployment(d *Deployment) *github.Deployment {
-	return &github.Deployment{
+	deployment := &github.Deployment{
 		Description:         d.Description,
 		CreatedAt:           d.CreatedAt,
 		UpdatedAt:           d.UpdatedAt,
@@ -40,18 +40,60 @@ func ConvertDeployment(d *Deployment) *github.Deployment {
 		Ref:                 d.Ref.Name,
 		Commit:              ConvertCommit(&d.Commit),
 	}
+
+	var statuses []github.DeploymentStatus
+	for _, s := range d.Statuses.Nodes {
+		statuses = append(statuses, github.DeploymentStatus{
+			State:          string(s.State),
+			Description:    s.Description,
+			LogURL:         s.LogURL,
+			EnvironmentURL: s.EnvironmentURL,
+			CreatedAt:      s.CreatedAt,
+		})
+	}
+	deployment.SetStatuses(statuses)
+
+	return deployment
 }
 
 // Convert a GraphQL API Pull Request to the unified Pull Request model
 func ConvertPullRequest(p *PullRequest) *github.PullRequest {
-	return &github.PullRequest{
-		Number:    p.Number,
-		Title:     p.Title,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
-		ClosedAt:  p.ClosedAt,
-		MergedAt:  p.MergedAt,
+	var labels []string
+	for _, l := range p.Labels.Nodes {
+		labels = append(labels, l.Name)
+	}
+
+	pr := &github.PullRequest{
+		Number:       p.Number,
+		Title:        p.Title,
+		Author:       p.Author.Login,
+		BaseRef:      p.BaseRefName,
+		HeadRef:      p.HeadRefName,
+		Labels:       labels,
+		Additions:    p.Additions,
+		Deletions:    p.Deletions,
+		ChangedFiles: p.ChangedFiles,
+		Commits:      p.Commits.TotalCount,
+		Reviews:      p.Reviews.TotalCount,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+		ClosedAt:     p.ClosedAt,
+		MergedAt:     p.MergedAt,
+	}
+
+	if len(p.Commits.Nodes) > 0 {
+		pr.FirstCommitAt = p.Commits.Nodes[0].Commit.AuthoredDate
+	}
+
+	if len(p.Reviews.Nodes) > 0 {
+		pr.FirstReviewAt = p.Reviews.Nodes[0].SubmittedAt
 	}
+
+	if len(p.ApprovedReviews.Nodes) > 0 {
+		pr.ApprovedAt = p.ApprovedReviews.Nodes[0].SubmittedAt
+	}
+
+	return pr
 }
 
 // Convert a GraphQL API Release to the unified Release model
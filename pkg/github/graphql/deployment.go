@@ -63,6 +63,7 @@ func (a *API) QueryDeployment(ctx context.Context, repo *github.Repo, env string
 			if deployment == nil {
 				if string(d.Commit.Oid) == sha || string(d.Commit.AbbreviatedOid) == sha {
 					// Check if this is the deployment we're looking for
+					a.warnTruncatedStatuses("graphql.QueryDeployment", &d)
 					deployment = ConvertDeployment(&d)
 
 					a.logger.Debug(
@@ -78,6 +79,7 @@ func (a *API) QueryDeployment(ctx context.Context, repo *github.Repo, env string
 
 			if deployment != nil {
 				// The first deployment encountered after `deployment` is `prev`
+				a.warnTruncatedStatuses("graphql.QueryDeployment", &d)
 				prev = ConvertDeployment(&d)
 
 				a.logger.Debug(
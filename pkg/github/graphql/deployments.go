@@ -2,6 +2,7 @@ package graphql
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/shurcooL/githubv4"
@@ -23,8 +24,25 @@ type DeploymentsQuery struct {
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-// QueryDeployments fetches information about Deployments from the GitHub GraphQL API
-func (a *API) QueryDeployments(ctx context.Context, repo *github.Repo, envs *[]string, limit int) ([]github.Deployment, error) {
+// QueryDeployments fetches information about Deployments from the GitHub GraphQL API.
+// If a window is given, only Deployments that were created within it are returned.
+func (a *API) QueryDeployments(ctx context.Context, repo *github.Repo, envs *[]string, limit int, window *github.TimeWindow) ([]github.Deployment, error) {
+	var deployments []github.Deployment
+
+	err := a.StreamDeployments(ctx, repo, envs, limit, window, func(page []github.Deployment) error {
+		deployments = append(deployments, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// StreamDeployments is like QueryDeployments, but passes the Deployments of
+// each page to fn as soon as the page is fetched.
+func (a *API) StreamDeployments(ctx context.Context, repo *github.Repo, envs *[]string, limit int, window *github.TimeWindow, fn func([]github.Deployment) error) error {
 	// Values of `first` and `last` must be within 1-100. See `Node limit` in
 	// GitHub's GraphQL API documentation.
 	perPage := limit
@@ -47,30 +65,67 @@ func (a *API) QueryDeployments(ctx context.Context, repo *github.Repo, envs *[]s
 		"environments": environments,
 	}
 
-	var deployments []github.Deployment
+	count := 0
 
-Loop:
 	for {
 		var query DeploymentsQuery
 
 		err := a.client.Query(ctx, &query, queryVariables)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
+		var page []github.Deployment
+		done := !query.Repository.Deployments.PageInfo.HasNextPage
+
 		for _, d := range query.Repository.Deployments.Nodes {
-			deployments = append(deployments, *ConvertDeployment(&d))
-			if len(deployments) == limit {
-				break Loop
+			if window.IsBefore(d.CreatedAt) {
+				// Results are ordered by time in descending order, so all
+				// remaining results are outside of the window as well.
+				done = true
+				break
+			}
+			if !window.Contains(d.CreatedAt) {
+				continue
+			}
+			a.warnTruncatedStatuses("graphql.StreamDeployments", &d)
+			page = append(page, *ConvertDeployment(&d))
+			if count += 1; count == limit {
+				done = true
+				break
+			}
+		}
+
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
 			}
 		}
 
-		if !query.Repository.Deployments.PageInfo.HasNextPage {
-			break
+		if done {
+			return nil
 		}
 
 		queryVariables["endCursor"] = githubv4.String(query.Repository.Deployments.PageInfo.EndCursor)
 	}
+}
 
-	return deployments, nil
+// warnTruncatedStatuses logs a warning if the deployment has more statuses
+// than were fetched, in which case its duration and number of failed attempts
+// are derived from the most recent statuses only.
+func (a *API) warnTruncatedStatuses(caller string, d *Deployment) {
+	if d.Statuses.TotalCount <= len(d.Statuses.Nodes) {
+		return
+	}
+
+	a.logger.Warn(
+		caller+": deployment has more statuses than were fetched, duration and failed attempts may be wrong",
+		slog.Group("deployment",
+			slog.String("sha", string(d.Commit.Oid)),
+			slog.Time("createdAt", d.CreatedAt),
+			slog.String("env", d.LatestEnvironment),
+		),
+		slog.Int("statuses", d.Statuses.TotalCount),
+		slog.Int("fetched", len(d.Statuses.Nodes)),
+	)
 }
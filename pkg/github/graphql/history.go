@@ -8,7 +8,8 @@ import (
 	"github.com/shurcooL/githubv4"
 )
 
-type commitHistoryQuery struct {
+// GraphQL query for the history of a commit
+type CommitHistoryQuery struct {
 	Repository struct {
 		Object struct {
 			Commit struct {
@@ -42,7 +43,7 @@ func (a *API) QueryHistory(ctx context.Context, repo *github.Repo, head string,
 
 Loop:
 	for {
-		var query commitHistoryQuery
+		var query CommitHistoryQuery
 		err := a.client.Query(ctx, &query, queryVariables)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch commit history: %w", err)
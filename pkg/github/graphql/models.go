@@ -41,13 +41,64 @@ type Deployment struct {
 	Ref                 struct {
 		Name string
 	}
+	// Deployments rarely have more statuses than this. The total count is
+	// used to detect when the status history is cut off.
+	Statuses struct {
+		TotalCount int
+		Nodes      []DeploymentStatus
+	} `graphql:"statuses(first: 20)"`
+}
+
+// DeploymentStatus represents a GitHub GraphQL API Deployment Status.
+// See https://docs.github.com/en/graphql/reference/objects#deploymentstatus
+type DeploymentStatus struct {
+	State          githubv4.DeploymentStatusState
+	Description    string
+	LogURL         string `graphql:"logUrl"`
+	EnvironmentURL string `graphql:"environmentUrl"`
+	CreatedAt      time.Time
+}
+
+// PullRequestReview represents a GitHub GraphQL API Pull Request Review.
+// See https://docs.github.com/en/graphql/reference/objects#pullrequestreview
+type PullRequestReview struct {
+	SubmittedAt time.Time
 }
 
 // PullRequest represents a GitHub GraphQL API Pull Request.
 // See https://docs.github.com/en/graphql/reference/objects#pullrequest
 type PullRequest struct {
-	Number    int
-	Title     string
+	Number int
+	Title  string
+	Author struct {
+		Login string
+	}
+	BaseRefName string
+	HeadRefName string
+	Labels      struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Commits      struct {
+		TotalCount int
+		Nodes      []struct {
+			Commit struct {
+				AuthoredDate time.Time
+			}
+		}
+	} `graphql:"commits(first: 1)"`
+	// Pending reviews have not been submitted yet.
+	Reviews struct {
+		TotalCount int
+		Nodes      []PullRequestReview
+	} `graphql:"reviews(first: 1, states: [APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED])"`
+	ApprovedReviews struct {
+		Nodes []PullRequestReview
+	} `graphql:"approvedReviews: reviews(first: 1, states: [APPROVED])"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	ClosedAt  time.Time
@@ -2,6 +2,9 @@ package graphql
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mozilla-services/rapid-release-model/pkg/github"
 	"github.com/shurcooL/githubv4"
@@ -20,52 +23,167 @@ type PullRequestsQuery struct {
 				EndCursor   string
 			}
 			Nodes []PullRequest
-		} `graphql:"pullRequests(states: $states, first: $perPage, after: $endCursor, orderBy: $orderBy)"`
+		} `graphql:"pullRequests(states: $states, labels: $labels, baseRefName: $baseRefName, first: $perPage, after: $endCursor, orderBy: $orderBy)"`
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-// QueryPullRequests fetches information about merged PRs from the GitHub GraphQL API
-func (a *API) QueryPullRequests(ctx context.Context, repo *github.Repo, limit int) ([]github.PullRequest, error) {
+// QueryPullRequests fetches information about PRs from the GitHub GraphQL API.
+// The API cannot filter PRs by author or order them by merge time, so PRs are
+// filtered by author after fetching them, and PRs ordered by merge time are
+// fetched in order of last update and then sorted and truncated to the limit.
+// If a window is given, only PRs whose time of opts.OrderBy is within it are
+// returned.
+func (a *API) QueryPullRequests(ctx context.Context, repo *github.Repo, opts *github.PullRequestsOpts) ([]github.PullRequest, error) {
+	var pullRequests []github.PullRequest
+
+	err := a.StreamPullRequests(ctx, repo, opts, func(page []github.PullRequest) error {
+		pullRequests = append(pullRequests, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OrderBy == github.PullRequestOrderMerged {
+		sort.SliceStable(pullRequests, func(i, j int) bool {
+			return pullRequests[i].MergedAt.After(pullRequests[j].MergedAt)
+		})
+		if len(pullRequests) > opts.Limit {
+			pullRequests = pullRequests[:opts.Limit]
+		}
+	}
+
+	return pullRequests, nil
+}
+
+// StreamPullRequests is like QueryPullRequests, but passes the PRs of each
+// page to fn as soon as the page is fetched. PRs are passed in the order of
+// the query, so PRs ordered by merge time are neither sorted nor truncated to
+// the limit.
+func (a *API) StreamPullRequests(ctx context.Context, repo *github.Repo, opts *github.PullRequestsOpts, fn func([]github.PullRequest) error) error {
 	// Values of `first` and `last` must be within 1-100. See `Node limit` in
 	// GitHub's GraphQL API documentation.
-	perPage := limit
-	if limit > 100 {
+	perPage := opts.Limit
+	if opts.Limit > 100 {
 		perPage = 100
 	}
 
+	states := []githubv4.PullRequestState{githubv4.PullRequestStateMerged}
+	if len(opts.States) > 0 {
+		states = nil
+		for _, s := range opts.States {
+			states = append(states, githubv4.PullRequestState(s))
+		}
+	}
+
+	orderBy := githubv4.IssueOrder{Field: githubv4.IssueOrderFieldUpdatedAt, Direction: githubv4.OrderDirectionDesc}
+	if opts.OrderBy == github.PullRequestOrderCreated {
+		orderBy.Field = githubv4.IssueOrderFieldCreatedAt
+	}
+
 	queryVariables := map[string]interface{}{
-		"owner":     githubv4.String(repo.Owner),
-		"name":      githubv4.String(repo.Name),
-		"perPage":   githubv4.Int(perPage),
-		"endCursor": (*githubv4.String)(nil), // When paginating forwards, the cursor to continue.
-		"states":    []githubv4.PullRequestState{githubv4.PullRequestStateMerged},
-		"orderBy":   githubv4.IssueOrder{Field: githubv4.IssueOrderFieldUpdatedAt, Direction: githubv4.OrderDirectionDesc},
+		"owner":       githubv4.String(repo.Owner),
+		"name":        githubv4.String(repo.Name),
+		"perPage":     githubv4.Int(perPage),
+		"endCursor":   (*githubv4.String)(nil), // When paginating forwards, the cursor to continue.
+		"states":      states,
+		"orderBy":     orderBy,
+		"labels":      (*[]githubv4.String)(nil),
+		"baseRefName": (*githubv4.String)(nil),
 	}
 
-	var pullRequests []github.PullRequest
+	if len(opts.Labels) > 0 {
+		var labels []githubv4.String
+		for _, l := range opts.Labels {
+			labels = append(labels, githubv4.String(l))
+		}
+		queryVariables["labels"] = labels
+	}
+
+	if opts.BaseRef != "" {
+		queryVariables["baseRefName"] = githubv4.String(opts.BaseRef)
+	}
+
+	count := 0
+
+	// Merge times of the PRs merged last so far, newest first. PRs are merged
+	// before they are last updated, so once PRs were last updated before the
+	// oldest of opts.Limit merge times, no remaining PR is merged later.
+	var mergedAt []time.Time
 
-Loop:
 	for {
 		var query PullRequestsQuery
 
 		err := a.client.Query(ctx, &query, queryVariables)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
+		var page []github.PullRequest
+		done := !query.Repository.PullRequests.PageInfo.HasNextPage
+
 		for _, p := range query.Repository.PullRequests.Nodes {
-			pullRequests = append(pullRequests, *ConvertPullRequest(&p))
-			if len(pullRequests) == limit {
-				break Loop
+			pr := ConvertPullRequest(&p)
+
+			// PRs are merged before they are last updated, so PRs updated
+			// before the window were merged before the window as well.
+			orderedAt := pr.UpdatedAt
+			if opts.OrderBy == github.PullRequestOrderCreated {
+				orderedAt = pr.CreatedAt
+			}
+			if opts.Window.IsBefore(orderedAt) {
+				// Results are ordered by time in descending order, so all
+				// remaining results are outside of the window as well.
+				done = true
+				break
+			}
+			if opts.OrderBy == github.PullRequestOrderMerged && len(mergedAt) == opts.Limit && pr.UpdatedAt.Before(mergedAt[len(mergedAt)-1]) {
+				done = true
+				break
+			}
+			if !opts.Window.Contains(opts.Time(pr)) {
+				continue
+			}
+			if opts.Author != "" && !strings.EqualFold(pr.Author, opts.Author) {
+				continue
+			}
+			page = append(page, *pr)
+			if opts.OrderBy == github.PullRequestOrderMerged {
+				mergedAt = insertNewest(mergedAt, pr.MergedAt, opts.Limit)
+				continue
+			}
+			if count += 1; count == opts.Limit {
+				done = true
+				break
+			}
+		}
+
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
 			}
 		}
 
-		if !query.Repository.PullRequests.PageInfo.HasNextPage {
-			break
+		if done {
+			return nil
 		}
 
 		queryVariables["endCursor"] = githubv4.String(query.Repository.PullRequests.PageInfo.EndCursor)
 	}
+}
 
-	return pullRequests, nil
+// insertNewest inserts t into times, which are sorted newest first, and keeps
+// at most limit times.
+func insertNewest(times []time.Time, t time.Time, limit int) []time.Time {
+	i := sort.Search(len(times), func(i int) bool { return times[i].Before(t) })
+	if i >= limit {
+		return times
+	}
+	times = append(times, time.Time{})
+	copy(times[i+1:], times[i:])
+	times[i] = t
+	if len(times) > limit {
+		times = times[:limit]
+	}
+	return times
 }
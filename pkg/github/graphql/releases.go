@@ -23,8 +23,25 @@ type ReleasesQuery struct {
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-// QueryReleases fetches information about merged PRs from the GitHub GraphQL API
-func (a *API) QueryReleases(ctx context.Context, repo *github.Repo, limit int) ([]github.Release, error) {
+// QueryReleases fetches information about Releases from the GitHub GraphQL API.
+// If a window is given, only Releases that were created within it are returned.
+func (a *API) QueryReleases(ctx context.Context, repo *github.Repo, limit int, window *github.TimeWindow) ([]github.Release, error) {
+	var releases []github.Release
+
+	err := a.StreamReleases(ctx, repo, limit, window, func(page []github.Release) error {
+		releases = append(releases, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// StreamReleases is like QueryReleases, but passes the Releases of each page
+// to fn as soon as the page is fetched.
+func (a *API) StreamReleases(ctx context.Context, repo *github.Repo, limit int, window *github.TimeWindow, fn func([]github.Release) error) error {
 	// Values of `first` and `last` must be within 1-100. See `Node limit` in
 	// GitHub's GraphQL API documentation.
 	perPage := limit
@@ -40,30 +57,46 @@ func (a *API) QueryReleases(ctx context.Context, repo *github.Repo, limit int) (
 		"orderBy":   githubv4.ReleaseOrder{Field: githubv4.ReleaseOrderFieldCreatedAt, Direction: githubv4.OrderDirectionDesc},
 	}
 
-	var releases []github.Release
+	count := 0
 
-Loop:
 	for {
 		var query ReleasesQuery
 
 		err := a.client.Query(ctx, &query, queryVariables)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
+		var page []github.Release
+		done := !query.Repository.Releases.PageInfo.HasNextPage
+
 		for _, r := range query.Repository.Releases.Nodes {
-			releases = append(releases, *ConvertRelease(&r))
-			if len(releases) == limit {
-				break Loop
+			if window.IsBefore(r.CreatedAt) {
+				// Results are ordered by time in descending order, so all
+				// remaining results are outside of the window as well.
+				done = true
+				break
+			}
+			if !window.Contains(r.CreatedAt) {
+				continue
+			}
+			page = append(page, *ConvertRelease(&r))
+			if count += 1; count == limit {
+				done = true
+				break
+			}
+		}
+
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
 			}
 		}
 
-		if !query.Repository.Releases.PageInfo.HasNextPage {
-			break
+		if done {
+			return nil
 		}
 
 		queryVariables["endCursor"] = githubv4.String(query.Repository.Releases.PageInfo.EndCursor)
 	}
-
-	return releases, nil
 }
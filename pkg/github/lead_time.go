@@ -0,0 +1,193 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+// CommitLeadTime represents a commit along with the deployment that first
+// shipped it to an environment.
+type CommitLeadTime struct {
+	Env        string
+	Commit     *Commit     `csv:"-"`
+	Deployment *Deployment `csv:"-"`
+
+	// Columns for the commit and the deployment which shipped it
+	_ struct{} `csv:"abbreviatedCommitSHA,key=Commit.AbbreviatedSHA"`
+	_ struct{} `csv:"commitSHA,key=Commit.SHA"`
+	_ struct{} `csv:"authoredDate,key=Commit.AuthoredDate"`
+	_ struct{} `csv:"committedDate,key=Commit.CommittedDate"`
+	_ struct{} `csv:"deploymentCommitSHA,key=Deployment.Commit.SHA"`
+	_ struct{} `csv:"deploymentCreatedAt,key=Deployment.CreatedAt"`
+	_ struct{} `csv:"deploymentState,key=Deployment.State"`
+
+	// Time from authoring the commit to its first deployment, in hours
+	LeadTimeHours float64
+}
+
+// LeadTimeSummary holds lead time percentiles for an environment.
+type LeadTimeSummary struct {
+	Env      string
+	Commits  int
+	P50Hours float64
+	P75Hours float64
+	P90Hours float64
+}
+
+// LeadTimeReport holds the lead time for each deployed commit and summary
+// percentiles for each environment.
+type LeadTimeReport struct {
+	Commits []*CommitLeadTime  `csv:",rows"`
+	Summary []*LeadTimeSummary `csv:"-"`
+}
+
+// Percentile returns the p-th percentile (0-100) of the given durations using
+// linear interpolation between the closest ranks. It returns zero if there are
+// no durations.
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	weight := rank - float64(lower)
+	return sorted[lower] + time.Duration(weight*float64(sorted[upper]-sorted[lower]))
+}
+
+// SuccessfulDeployments returns the successful deployments among the given
+// ones, which must belong to a single environment, sorted by creation time in
+// ascending order. Commits are compared to the previous deployment whether it
+// failed or not, so the commits of failed deployments are added to the next
+// successful deployment, which is the one that shipped them. Commits of failed
+// deployments without a later successful deployment are left out.
+func SuccessfulDeployments(deployments []*DeploymentWithCommits) []*DeploymentWithCommits {
+	sorted := make([]*DeploymentWithCommits, len(deployments))
+	copy(sorted, deployments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	var successful []*DeploymentWithCommits
+	var pending []*Commit
+
+	for _, d := range sorted {
+		if d.Failed() {
+			pending = append(pending, d.DeployedCommits...)
+			continue
+		}
+
+		if len(pending) == 0 {
+			successful = append(successful, d)
+			continue
+		}
+
+		successful = append(successful, &DeploymentWithCommits{
+			Deployment:      d.Deployment,
+			DeployedCommits: append(pending, d.DeployedCommits...),
+		})
+		pending = nil
+	}
+
+	return successful
+}
+
+// ComputeLeadTimes joins each deployed commit with the earliest successful
+// deployment that shipped it in each environment. Failed deployments did not
+// ship their commits and are skipped. Commits are sorted by environment and by
+// deployment time in descending order.
+func ComputeLeadTimes(deploymentsByEnv map[string][]*DeploymentWithCommits) *LeadTimeReport {
+	report := &LeadTimeReport{}
+
+	var envs []string
+	for env := range deploymentsByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	for _, env := range envs {
+		firstDeployments := make(map[string]*CommitLeadTime)
+
+		for _, d := range SuccessfulDeployments(deploymentsByEnv[env]) {
+			for _, commit := range d.DeployedCommits {
+				if first, ok := firstDeployments[commit.SHA]; ok && !d.CreatedAt.Before(first.Deployment.CreatedAt) {
+					continue
+				}
+				firstDeployments[commit.SHA] = &CommitLeadTime{
+					Env:           env,
+					Commit:        commit,
+					Deployment:    d.Deployment,
+					LeadTimeHours: d.CreatedAt.Sub(commit.AuthoredDate).Hours(),
+				}
+			}
+		}
+
+		var commits []*CommitLeadTime
+		var leadTimes []time.Duration
+
+		for _, c := range firstDeployments {
+			commits = append(commits, c)
+			leadTimes = append(leadTimes, c.Deployment.CreatedAt.Sub(c.Commit.AuthoredDate))
+		}
+
+		sort.Slice(commits, func(i, j int) bool {
+			if !commits[i].Deployment.CreatedAt.Equal(commits[j].Deployment.CreatedAt) {
+				return commits[i].Deployment.CreatedAt.After(commits[j].Deployment.CreatedAt)
+			}
+			if !commits[i].Commit.AuthoredDate.Equal(commits[j].Commit.AuthoredDate) {
+				return commits[i].Commit.AuthoredDate.After(commits[j].Commit.AuthoredDate)
+			}
+			return commits[i].Commit.SHA < commits[j].Commit.SHA
+		})
+
+		report.Commits = append(report.Commits, commits...)
+		report.Summary = append(report.Summary, &LeadTimeSummary{
+			Env:      env,
+			Commits:  len(commits),
+			P50Hours: Percentile(leadTimes, 50).Hours(),
+			P75Hours: Percentile(leadTimes, 75).Hours(),
+			P90Hours: Percentile(leadTimes, 90).Hours(),
+		})
+	}
+
+	return report
+}
+
+// QueryLeadTimes fetches deployments with their commits and computes the lead
+// time for each deployed commit.
+func QueryLeadTimes(
+	ctx context.Context,
+	repo *Repo,
+	d DeploymentsService, c CommitsComparisonService,
+	logger *slog.Logger,
+	opts *DeploymentWithCommitsOptions,
+) (*LeadTimeReport, error) {
+	deploymentsByEnv, err := QueryDeploymentsWithCommits(ctx, repo, d, c, logger, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error querying deployments with commits: %w", err)
+	}
+
+	report := ComputeLeadTimes(deploymentsByEnv)
+
+	logger.Debug(
+		"github.QueryLeadTimes: computed lead times",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("count", len(report.Commits)),
+	)
+
+	return report, nil
+}
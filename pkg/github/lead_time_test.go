@@ -0,0 +1,98 @@
+package github_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{4 * time.Hour, time.Hour, 3 * time.Hour, 2 * time.Hour}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{name: "empty", durations: nil, p: 50, want: 0},
+		{name: "single", durations: []time.Duration{time.Minute}, p: 90, want: time.Minute},
+		{name: "p0", durations: durations, p: 0, want: time.Hour},
+		{name: "p50", durations: durations, p: 50, want: 150 * time.Minute},
+		{name: "p75", durations: durations, p: 75, want: 195 * time.Minute},
+		{name: "p100", durations: durations, p: 100, want: 4 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := github.Percentile(tt.durations, tt.p); got != tt.want {
+				t.Errorf("Percentile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeLeadTimes(t *testing.T) {
+	commit := &github.Commit{SHA: "1abc111aaaaaaaaaaa", AuthoredDate: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)}
+
+	first := &github.Deployment{CreatedAt: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC), Commit: commit}
+	redeploy := &github.Deployment{CreatedAt: time.Date(2024, time.May, 2, 12, 0, 0, 0, time.UTC), Commit: commit}
+
+	report := github.ComputeLeadTimes(map[string][]*github.DeploymentWithCommits{
+		"prod": {
+			{Deployment: redeploy, DeployedCommits: []*github.Commit{commit}},
+			{Deployment: first, DeployedCommits: []*github.Commit{commit}},
+		},
+	})
+
+	if len(report.Commits) != 1 {
+		t.Fatalf("ComputeLeadTimes() returned %d commits, want 1", len(report.Commits))
+	}
+
+	if got := report.Commits[0].Deployment; got != first {
+		t.Errorf("ComputeLeadTimes() joined commit with deployment at %v, want %v", got.CreatedAt, first.CreatedAt)
+	}
+
+	if got := report.Commits[0].LeadTimeHours; got != 2 {
+		t.Errorf("ComputeLeadTimes() lead time = %v, want 2", got)
+	}
+
+	if got := report.Summary[0].P90Hours; got != 2 {
+		t.Errorf("ComputeLeadTimes() p90 = %v, want 2", got)
+	}
+}
+
+func TestComputeLeadTimesFailed(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2024, time.May, day, 12, 0, 0, 0, time.UTC) }
+
+	fix := &github.Commit{SHA: "1abc111aaaaaaaaaaa", AuthoredDate: at(1)}
+	feature := &github.Commit{SHA: "2abc111aaaaaaaaaaa", AuthoredDate: at(4)}
+
+	// The retry of the failed deployment compares the same commit, so it
+	// deploys no commits of its own. The last deployment failed and has not
+	// been retried yet.
+	failed := &github.Deployment{CreatedAt: at(2), Commit: fix, State: "FAILURE"}
+	retry := &github.Deployment{CreatedAt: at(3), Commit: fix, State: "ACTIVE"}
+	broken := &github.Deployment{CreatedAt: at(5), Commit: feature, State: "ERROR"}
+
+	report := github.ComputeLeadTimes(map[string][]*github.DeploymentWithCommits{
+		"prod": {
+			{Deployment: broken, DeployedCommits: []*github.Commit{feature}},
+			{Deployment: retry},
+			{Deployment: failed, DeployedCommits: []*github.Commit{fix}},
+		},
+	})
+
+	if len(report.Commits) != 1 {
+		t.Fatalf("ComputeLeadTimes() returned %d commits, want 1", len(report.Commits))
+	}
+
+	if got := report.Commits[0].Deployment; got != retry {
+		t.Errorf("ComputeLeadTimes() joined commit with deployment at %v, want %v", got.CreatedAt, retry.CreatedAt)
+	}
+
+	if got := report.Commits[0].LeadTimeHours; got != 48 {
+		t.Errorf("ComputeLeadTimes() lead time = %v, want 48", got)
+	}
+}
@@ -1,6 +1,8 @@
 package github
 
 import (
+	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -22,14 +24,28 @@ type Commit struct {
 	SHA            string
 	AuthoredDate   time.Time
 	CommittedDate  time.Time
-	Message        string
-	Parents        []*CommitParent
+	Message        string          `csv:",firstline"`
+	Parents        []*CommitParent `csv:",key=SHA"`
+	_              struct{}        `csv:"isMerge,key=IsMerge"`
+
+	// Merged pull requests associated with the commit. Only set when pull
+	// requests were requested for the commit.
+	PullRequests []PullRequest `json:",omitempty" csv:"-"`
+}
+
+// IsMerge reports whether the commit is a merge commit.
+func (c *Commit) IsMerge() bool {
+	return len(c.Parents) > 1
 }
 
 // Represents a Git Commit Comparison
 type CommitsComparison struct {
-	TotalCommits int
-	Commits      []*Commit
+	TotalCommits int       `csv:"-"`
+	Commits      []*Commit `csv:",rows"`
+
+	// Number of commits in the comparison, which may exceed the number of
+	// rows
+	_ struct{} `csv:"totalCommits,key=TotalCommits"`
 }
 
 // Unified GitHub Deployment Model (used for both REST & GraphQL API)
@@ -41,24 +57,147 @@ type Deployment struct {
 	LatestEnvironment   string
 	Task                string
 	State               string
-	Ref                 string
-	Commit              *Commit
+	Ref                 string   `csv:"-"`
+	_                   struct{} `csv:"abbreviatedCommitSHA,key=Commit.AbbreviatedSHA"`
+	Commit              *Commit  `csv:"commitSHA,key=SHA"`
+
+	// Status history of the deployment, oldest first
+	Statuses []DeploymentStatus `csv:",key=State"`
+
+	// Time from creating the deployment to its first successful status, or
+	// to its last failed status if it never succeeded, in seconds. Nil if the
+	// deployment has not finished.
+	DurationSeconds *float64 `csv:",prec=0"`
+
+	// Number of failed or errored statuses
+	FailedAttempts int
+
+	// Most recent URLs of the status history
+	_ struct{} `csv:"logURL,key=LogURL"`
+	_ struct{} `csv:"environmentURL,key=EnvironmentURL"`
+}
+
+// Unified GitHub Deployment Status Model (used for both REST & GraphQL API)
+type DeploymentStatus struct {
+	State          string
+	Description    string
+	LogURL         string
+	EnvironmentURL string
+	CreatedAt      time.Time
 }
 
 // DeploymentWithCommits represents a deployment along with its associated deployed commits.
 type DeploymentWithCommits struct {
 	*Deployment
-	DeployedCommits []*Commit
+	DeployedCommits []*Commit `csv:",key=SHA"`
+
+	// Numbers of the pull requests associated with the deployed commits
+	_ struct{} `csv:"prs,key=PullRequestNumbers"`
+}
+
+// CSVColumns returns the CSV columns of deployments with commits, which leave
+// out the status history of the deployment.
+func (*DeploymentWithCommits) CSVColumns() []string {
+	return []string{
+		"description", "createdAt", "updatedAt", "originalEnvironment",
+		"latestEnvironment", "task", "state", "abbreviatedCommitSHA", "commitSHA",
+		"deployedCommits", "prs",
+	}
+}
+
+// PullRequestNumbers returns the sorted numbers of the pull requests
+// associated with the deployed commits.
+func (d *DeploymentWithCommits) PullRequestNumbers() []int {
+	var numbers []int
+
+	seen := make(map[int]bool)
+	for _, commit := range d.DeployedCommits {
+		for _, pr := range commit.PullRequests {
+			if !seen[pr.Number] {
+				seen[pr.Number] = true
+				numbers = append(numbers, pr.Number)
+			}
+		}
+	}
+	sort.Ints(numbers)
+
+	return numbers
 }
 
 // Unified GitHub PullRequest Model (used for both REST & GraphQL API)
 type PullRequest struct {
-	Number    int
-	Title     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	ClosedAt  time.Time
-	MergedAt  time.Time
+	Number       int
+	Title        string
+	Author       string
+	BaseRef      string
+	HeadRef      string
+	Labels       []string
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Commits      int
+	Reviews      int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ClosedAt     time.Time
+	MergedAt     time.Time
+
+	// Time the first commit of the PR was authored, which may be before the
+	// PR was created
+	FirstCommitAt time.Time
+
+	// Times the first review and the first approving review were submitted
+	FirstReviewAt time.Time
+	ApprovedAt    time.Time
+}
+
+// CSVColumns returns the CSV columns of pull requests in the order of earlier
+// exports, which had columns for the timestamps right after the title.
+func (*PullRequest) CSVColumns() []string {
+	return []string{
+		"number", "title", "createdAt", "updatedAt", "closedAt", "mergedAt",
+		"author", "baseRef", "headRef", "labels",
+		"additions", "deletions", "changedFiles", "commits",
+		"firstCommitAt", "firstReviewAt", "approvedAt", "reviews",
+	}
+}
+
+// MarshalJSON encodes the pull request as JSON. Times which are not set, such
+// as the merge time of an open pull request or the review times of a pull
+// request without reviews, are left out.
+func (p PullRequest) MarshalJSON() ([]byte, error) {
+	type pullRequest PullRequest
+
+	// Fields of the outer struct take precedence over the embedded fields of
+	// the same name. The times are the last fields of PullRequest, so the
+	// order of the fields is the same.
+	return json.Marshal(&struct {
+		*pullRequest
+		CreatedAt     *time.Time `json:",omitempty"`
+		UpdatedAt     *time.Time `json:",omitempty"`
+		ClosedAt      *time.Time `json:",omitempty"`
+		MergedAt      *time.Time `json:",omitempty"`
+		FirstCommitAt *time.Time `json:",omitempty"`
+		FirstReviewAt *time.Time `json:",omitempty"`
+		ApprovedAt    *time.Time `json:",omitempty"`
+	}{
+		pullRequest:   (*pullRequest)(&p),
+		CreatedAt:     nonZeroTime(p.CreatedAt),
+		UpdatedAt:     nonZeroTime(p.UpdatedAt),
+		ClosedAt:      nonZeroTime(p.ClosedAt),
+		MergedAt:      nonZeroTime(p.MergedAt),
+		FirstCommitAt: nonZeroTime(p.FirstCommitAt),
+		FirstReviewAt: nonZeroTime(p.FirstReviewAt),
+		ApprovedAt:    nonZeroTime(p.ApprovedAt),
+	})
+}
+
+// nonZeroTime returns a pointer to t, or nil if t is the zero time.
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
 // Unified GitHub Release Model (used for both REST & GraphQL API)
@@ -0,0 +1,216 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// DefaultPromotionEnvs is the default order in which commits are promoted
+// across environments.
+var DefaultPromotionEnvs = []string{"dev", "stage", "prod"}
+
+// PromotionStage represents the first deployment of a commit to an
+// environment.
+type PromotionStage struct {
+	Env string
+
+	// First deployment of the commit to the environment. Nil if the commit was
+	// never deployed to the environment.
+	Deployment *Deployment `csv:"-"`
+	_          struct{}    `csv:"deploymentCreatedAt,key=Deployment.CreatedAt"`
+	_          struct{}    `csv:"deploymentState,key=Deployment.State"`
+
+	// Time from the first deployment to the closest preceding environment which
+	// the commit was deployed to, in hours. Nil if the commit was not deployed
+	// to the environment or to any preceding environment. Negative if the
+	// commit was deployed to the environment first.
+	DelayHours *float64
+}
+
+// CommitPromotion holds the first deployment of a commit to each environment
+// in the order of promotion.
+type CommitPromotion struct {
+	Commit *Commit `csv:"-"`
+
+	// Columns for the commit, which are repeated on the row of each stage
+	_ struct{} `csv:"abbreviatedCommitSHA,key=Commit.AbbreviatedSHA"`
+	_ struct{} `csv:"commitSHA,key=Commit.SHA"`
+	_ struct{} `csv:"latestEnv,key=LatestEnv"`
+
+	Stages []*PromotionStage `csv:",rows"`
+
+	// Last environment in the order of promotion which the commit was
+	// deployed to
+	LatestEnv string `csv:"-"`
+}
+
+// PromotionSummary holds promotion delay percentiles for an environment.
+// Commits is the number of commits deployed to the environment and Promoted
+// the number of these commits which were deployed to a preceding environment.
+type PromotionSummary struct {
+	Env      string
+	Commits  int
+	Promoted int
+	P50Hours float64
+	P75Hours float64
+	P90Hours float64
+}
+
+// PromotionReport holds the promotion of each deployed commit and summary
+// percentiles for each environment.
+type PromotionReport struct {
+	Commits []*CommitPromotion  `csv:",rows"`
+	Summary []*PromotionSummary `csv:"-"`
+}
+
+// firstDeployedAt returns the time of the earliest deployment of the commit.
+func (c *CommitPromotion) firstDeployedAt() time.Time {
+	var first time.Time
+	for _, s := range c.Stages {
+		if s.Deployment != nil && (first.IsZero() || s.Deployment.CreatedAt.Before(first)) {
+			first = s.Deployment.CreatedAt
+		}
+	}
+	return first
+}
+
+// ComputePromotions joins the deployments of each commit across the given
+// environments, which are in the order of promotion. Deployments to other
+// environments and failed deployments are ignored. Commits are sorted by the
+// time of their first deployment in descending order.
+func ComputePromotions(deployments []Deployment, envs []string) *PromotionReport {
+	report := &PromotionReport{}
+
+	envIndex := make(map[string]int)
+	for i, env := range envs {
+		envIndex[env] = i
+	}
+
+	promotions := make(map[string]*CommitPromotion)
+
+	for i := range deployments {
+		d := &deployments[i]
+		if d.Commit == nil || d.Failed() {
+			continue
+		}
+
+		stageIndex, ok := envIndex[d.LatestEnvironment]
+		if !ok {
+			continue
+		}
+
+		p, ok := promotions[d.Commit.SHA]
+		if !ok {
+			p = &CommitPromotion{Commit: d.Commit}
+			for _, env := range envs {
+				p.Stages = append(p.Stages, &PromotionStage{Env: env})
+			}
+			promotions[d.Commit.SHA] = p
+		}
+
+		stage := p.Stages[stageIndex]
+		if stage.Deployment == nil || d.CreatedAt.Before(stage.Deployment.CreatedAt) {
+			stage.Deployment = d
+		}
+	}
+
+	delaysByEnv := make([][]time.Duration, len(envs))
+	deployedByEnv := make([]int, len(envs))
+
+	for _, p := range promotions {
+		var previous *PromotionStage
+
+		for i, stage := range p.Stages {
+			if stage.Deployment == nil {
+				continue
+			}
+
+			deployedByEnv[i]++
+			p.LatestEnv = stage.Env
+
+			if previous != nil {
+				delay := stage.Deployment.CreatedAt.Sub(previous.Deployment.CreatedAt)
+				stage.DelayHours = hours(delay)
+				delaysByEnv[i] = append(delaysByEnv[i], delay)
+			}
+
+			previous = stage
+		}
+
+		report.Commits = append(report.Commits, p)
+	}
+
+	sort.Slice(report.Commits, func(i, j int) bool {
+		fi, fj := report.Commits[i].firstDeployedAt(), report.Commits[j].firstDeployedAt()
+		if !fi.Equal(fj) {
+			return fi.After(fj)
+		}
+		return report.Commits[i].Commit.SHA < report.Commits[j].Commit.SHA
+	})
+
+	// The first environment is never promoted to.
+	for i := 1; i < len(envs); i++ {
+		report.Summary = append(report.Summary, &PromotionSummary{
+			Env:      envs[i],
+			Commits:  deployedByEnv[i],
+			Promoted: len(delaysByEnv[i]),
+			P50Hours: Percentile(delaysByEnv[i], 50).Hours(),
+			P75Hours: Percentile(delaysByEnv[i], 75).Hours(),
+			P90Hours: Percentile(delaysByEnv[i], 90).Hours(),
+		})
+	}
+
+	return report
+}
+
+// Options for the QueryPromotions function
+type PromotionOptions struct {
+	// Environments in the order of promotion
+	Envs []string
+
+	// Maximum number of deployments to fetch for each environment
+	Limit  int
+	Window *TimeWindow
+}
+
+// QueryPromotions fetches deployments to the given environments and computes
+// the promotion of each deployed commit across them. Deployments are fetched
+// for each environment separately, so that environments with many deployments
+// do not crowd out the deployments of the others. Environments are deployed to
+// at different rates though, so the fetched deployments of each environment
+// may reach back to a different time. Commits deployed before the oldest
+// fetched deployment of an environment appear to have skipped it, unless the
+// window holds fewer deployments than the limit for each environment.
+func QueryPromotions(
+	ctx context.Context,
+	repo *Repo,
+	d DeploymentsService,
+	logger *slog.Logger,
+	opts *PromotionOptions,
+) (*PromotionReport, error) {
+	var deployments []Deployment
+
+	for _, env := range opts.Envs {
+		envs := []string{env}
+
+		envDeployments, err := d.QueryDeployments(ctx, repo, &envs, opts.Limit, opts.Window)
+		if err != nil {
+			return nil, fmt.Errorf("error querying deployments for %s: %w", env, err)
+		}
+
+		deployments = append(deployments, envDeployments...)
+	}
+
+	report := ComputePromotions(deployments, opts.Envs)
+
+	logger.Debug(
+		"github.QueryPromotions: computed promotions",
+		slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+		slog.Int("count", len(report.Commits)),
+	)
+
+	return report, nil
+}
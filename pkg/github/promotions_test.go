@@ -0,0 +1,135 @@
+package github_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+func TestComputePromotions(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.May, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	deployment := func(env, sha string, createdAt time.Time, state string) github.Deployment {
+		return github.Deployment{
+			LatestEnvironment: env,
+			CreatedAt:         createdAt,
+			State:             state,
+			Commit:            &github.Commit{SHA: sha},
+		}
+	}
+
+	deployments := []github.Deployment{
+		// Promoted to all environments, deployed to stage twice
+		deployment("dev", "aaa", at(1, 10), "INACTIVE"),
+		deployment("stage", "aaa", at(1, 12), "INACTIVE"),
+		deployment("stage", "aaa", at(2, 9), "INACTIVE"),
+		deployment("prod", "aaa", at(2, 12), "ACTIVE"),
+		// Skipped stage
+		deployment("dev", "bbb", at(3, 10), "INACTIVE"),
+		deployment("prod", "bbb", at(3, 16), "ACTIVE"),
+		// Never promoted, failed to deploy to stage
+		deployment("dev", "ccc", at(4, 10), "ACTIVE"),
+		deployment("stage", "ccc", at(4, 11), "FAILURE"),
+		// Deployed to an environment which is not part of the promotion
+		deployment("preview", "ddd", at(5, 10), "ACTIVE"),
+	}
+
+	report := github.ComputePromotions(deployments, []string{"dev", "stage", "prod"})
+
+	hours := func(h float64) *float64 { return &h }
+
+	type stage struct {
+		Env        string
+		DeployedAt time.Time
+		DelayHours *float64
+	}
+
+	type promotion struct {
+		SHA       string
+		LatestEnv string
+		Stages    []stage
+	}
+
+	var got []promotion
+	for _, c := range report.Commits {
+		p := promotion{SHA: c.Commit.SHA, LatestEnv: c.LatestEnv}
+		for _, s := range c.Stages {
+			var deployedAt time.Time
+			if s.Deployment != nil {
+				deployedAt = s.Deployment.CreatedAt
+			}
+			p.Stages = append(p.Stages, stage{s.Env, deployedAt, s.DelayHours})
+		}
+		got = append(got, p)
+	}
+
+	want := []promotion{{
+		SHA:       "ccc",
+		LatestEnv: "dev",
+		Stages:    []stage{{Env: "dev", DeployedAt: at(4, 10)}, {Env: "stage"}, {Env: "prod"}},
+	}, {
+		SHA:       "bbb",
+		LatestEnv: "prod",
+		Stages:    []stage{{Env: "dev", DeployedAt: at(3, 10)}, {Env: "stage"}, {Env: "prod", DeployedAt: at(3, 16), DelayHours: hours(6)}},
+	}, {
+		SHA:       "aaa",
+		LatestEnv: "prod",
+		Stages:    []stage{{Env: "dev", DeployedAt: at(1, 10)}, {Env: "stage", DeployedAt: at(1, 12), DelayHours: hours(2)}, {Env: "prod", DeployedAt: at(2, 12), DelayHours: hours(24)}},
+	}}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ComputePromotions() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantSummary := []*github.PromotionSummary{
+		{Env: "stage", Commits: 1, Promoted: 1, P50Hours: 2, P75Hours: 2, P90Hours: 2},
+		{Env: "prod", Commits: 2, Promoted: 2, P50Hours: 15, P75Hours: 19.5, P90Hours: 22.2},
+	}
+
+	if diff := cmp.Diff(wantSummary, report.Summary); diff != "" {
+		t.Errorf("ComputePromotions() summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryPromotions(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))
+
+	// Commits are deployed to dev more often than to prod, so a limit across
+	// both environments would only return deployments to dev.
+	d := &fakeDeploymentsService{}
+	for i := 10; i > 0; i-- {
+		env := "dev"
+		if i%5 == 0 {
+			env = "prod"
+		}
+		d.deployments = append(d.deployments, github.Deployment{
+			LatestEnvironment: env,
+			CreatedAt:         time.Date(2024, time.May, i, 0, 0, 0, 0, time.UTC),
+			Commit:            &github.Commit{SHA: fmt.Sprintf("sha%02d", i)},
+		})
+	}
+
+	report, err := github.QueryPromotions(ctx, &github.Repo{Owner: "hackebrot", Name: "turtle"}, d, logger, &github.PromotionOptions{
+		Envs:  []string{"dev", "prod"},
+		Limit: 2,
+	})
+	if err != nil {
+		t.Fatalf("QueryPromotions() returned unexpected error: %v", err)
+	}
+
+	want := []*github.PromotionSummary{{Env: "prod", Commits: 2}}
+
+	if diff := cmp.Diff(want, report.Summary); diff != "" {
+		t.Errorf("QueryPromotions() summary mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PullRequestState is the state of a pull request. Closed pull requests were
+// closed without being merged.
+type PullRequestState string
+
+const (
+	PullRequestStateOpen   PullRequestState = "OPEN"
+	PullRequestStateClosed PullRequestState = "CLOSED"
+	PullRequestStateMerged PullRequestState = "MERGED"
+)
+
+// ParsePullRequestState parses a case-insensitive pull request state.
+func ParsePullRequestState(s string) (PullRequestState, error) {
+	switch state := PullRequestState(strings.ToUpper(s)); state {
+	case PullRequestStateOpen, PullRequestStateClosed, PullRequestStateMerged:
+		return state, nil
+	}
+	return "", fmt.Errorf("invalid pull request state %q, must be one of open, closed, merged", s)
+}
+
+// PullRequestOrder is the time by which pull requests are ordered.
+type PullRequestOrder string
+
+const (
+	PullRequestOrderCreated PullRequestOrder = "created"
+	PullRequestOrderUpdated PullRequestOrder = "updated"
+	PullRequestOrderMerged  PullRequestOrder = "merged"
+)
+
+// ParsePullRequestOrder parses a case-insensitive pull request order.
+func ParsePullRequestOrder(s string) (PullRequestOrder, error) {
+	switch order := PullRequestOrder(strings.ToLower(s)); order {
+	case PullRequestOrderCreated, PullRequestOrderUpdated, PullRequestOrderMerged:
+		return order, nil
+	}
+	return "", fmt.Errorf("invalid pull request order %q, must be one of created, updated, merged", s)
+}
+
+// Options for the PullRequestsService
+type PullRequestsOpts struct {
+	Limit int
+
+	// Only return pull requests whose time of OrderBy is within the window
+	Window *TimeWindow
+
+	// States defaults to merged pull requests
+	States []PullRequestState
+
+	// Only return pull requests into the given base branch
+	BaseRef string
+
+	// Only return pull requests with any of the given labels
+	Labels []string
+
+	// Only return pull requests opened by the user with the given login
+	Author string
+
+	// OrderBy defaults to the time pull requests were last updated. Ordering
+	// by merge time requires merged pull requests only.
+	OrderBy PullRequestOrder
+}
+
+// Time returns the time of the pull request by which results are ordered and
+// filtered.
+func (o *PullRequestsOpts) Time(pr *PullRequest) time.Time {
+	switch o.OrderBy {
+	case PullRequestOrderCreated:
+		return pr.CreatedAt
+	case PullRequestOrderMerged:
+		return pr.MergedAt
+	default:
+		return pr.UpdatedAt
+	}
+}
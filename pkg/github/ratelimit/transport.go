@@ -0,0 +1,316 @@
+// Package ratelimit provides an http.RoundTripper which handles GitHub primary
+// and secondary rate limits for both the REST and the GraphQL API.
+//
+// See https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api
+// and https://docs.github.com/en/graphql/overview/rate-limits-and-node-limits-for-the-graphql-api
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for the Transport
+const (
+	DefaultMaxRetries = 5
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+	DefaultMaxWait    = 15 * time.Minute
+)
+
+// Transport is an http.RoundTripper which retries requests that GitHub
+// rejected because of a rate limit. It waits until the time from the
+// Retry-After or X-RateLimit-Reset response headers or, if neither is set,
+// for an exponential backoff with jitter.
+type Transport struct {
+	Base   http.RoundTripper
+	Logger *slog.Logger
+
+	// Maximum number of retries for a single request
+	MaxRetries int
+
+	// Bounds for the exponential backoff if GitHub does not tell us how long
+	// to wait for
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Maximum time to wait for a single retry. Responses which ask us to wait
+	// longer than that are returned to the caller.
+	MaxWait time.Duration
+
+	// Overridden in tests
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+// NewTransport returns a new Transport with default settings, which sends
+// requests using base. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, logger *slog.Logger) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Transport{
+		Base:       base,
+		Logger:     logger,
+		MaxRetries: DefaultMaxRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		MaxWait:    DefaultMaxWait,
+		now:        time.Now,
+		sleep:      sleep,
+		jitter:     jitter,
+	}
+}
+
+// RoundTrip sends the request and retries it if GitHub responds with a rate
+// limit error.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, getBody, err := bufferBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			body, err := getBody()
+			if err != nil {
+				return nil, fmt.Errorf("error rewinding request body: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		t.logQuota(req, resp)
+
+		limited, err := isRateLimited(req, resp)
+		if err != nil {
+			return nil, err
+		}
+
+		if !limited {
+			return resp, nil
+		}
+
+		wait := t.waitFor(resp, attempt)
+
+		if attempt >= t.MaxRetries || wait > t.MaxWait {
+			t.Logger.Warn(
+				"ratelimit: giving up on rate limited request",
+				slog.String("url", req.URL.String()),
+				slog.Int("status", resp.StatusCode),
+				slog.Int("attempt", attempt+1),
+				slog.Duration("wait", wait),
+			)
+			return resp, nil
+		}
+
+		t.Logger.Warn(
+			"ratelimit: rate limited, waiting before retrying request",
+			slog.String("url", req.URL.String()),
+			slog.Int("status", resp.StatusCode),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("wait", wait),
+		)
+
+		// Drain the body so that the connection can be reused.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// logQuota logs the remaining quota from the X-RateLimit-* response headers.
+func (t *Transport) logQuota(req *http.Request, resp *http.Response) {
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+
+	attrs := []any{
+		slog.String("url", req.URL.String()),
+		slog.String("resource", resp.Header.Get("X-RateLimit-Resource")),
+		slog.String("limit", resp.Header.Get("X-RateLimit-Limit")),
+		slog.String("remaining", remaining),
+		slog.String("used", resp.Header.Get("X-RateLimit-Used")),
+	}
+
+	if reset, ok := parseReset(resp.Header); ok {
+		attrs = append(attrs, slog.Time("reset", reset))
+	}
+
+	t.Logger.Debug("ratelimit: quota", attrs...)
+}
+
+// waitFor returns how long to wait before retrying the request. It prefers
+// the Retry-After header, then the X-RateLimit-Reset header if no quota is
+// remaining, and falls back to an exponential backoff with jitter.
+func (t *Transport) waitFor(resp *http.Response, attempt int) time.Duration {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if seconds, err := strconv.Atoi(s); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, ok := parseReset(resp.Header); ok {
+			// Add a second to account for clock skew.
+			if wait := reset.Sub(t.now()) + time.Second; wait > 0 {
+				return wait
+			}
+			return 0
+		}
+	}
+
+	backoff := t.MinBackoff << attempt
+	if backoff <= 0 || backoff > t.MaxBackoff {
+		backoff = t.MaxBackoff
+	}
+
+	return t.jitter(backoff)
+}
+
+// isRateLimited reports whether GitHub rejected the request because of a rate
+// limit. For GraphQL requests, it reads the response body to look for
+// RATE_LIMITED errors and replaces it with a copy.
+func isRateLimited(req *http.Request, resp *http.Response) (bool, error) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true, nil
+	case http.StatusForbidden:
+		if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return true, nil
+		}
+		body, err := peekBody(resp)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Contains(bytes.ToLower(body), []byte("rate limit")), nil
+	case http.StatusOK:
+		if !strings.HasSuffix(req.URL.Path, "/graphql") {
+			return false, nil
+		}
+		body, err := peekBody(resp)
+		if err != nil {
+			return false, err
+		}
+		return hasRateLimitedError(body), nil
+	default:
+		return false, nil
+	}
+}
+
+// hasRateLimitedError reports whether the GraphQL response contains an error
+// of type RATE_LIMITED.
+func hasRateLimitedError(body []byte) bool {
+	var payload struct {
+		Errors []struct {
+			Type string `json:"type"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return false
+	}
+
+	for _, e := range payload.Errors {
+		if e.Type == "RATE_LIMITED" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// peekBody reads the response body and replaces it with a copy, so that it
+// can be read again by the caller.
+func peekBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// bufferBody returns a func which returns a new copy of the request body for
+// retries. If the request does not support GetBody, it buffers the body in
+// memory and returns a copy of the request which reads from the buffer.
+func bufferBody(req *http.Request) (*http.Request, func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, func() (io.ReadCloser, error) { return http.NoBody, nil }, nil
+	}
+
+	if req.GetBody != nil {
+		return req, req.GetBody, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading request body: %w", err)
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	req = req.Clone(req.Context())
+	req.Body, _ = getBody()
+	req.GetBody = getBody
+
+	return req, getBody, nil
+}
+
+// parseReset parses the X-RateLimit-Reset header, which is in UTC epoch
+// seconds.
+func parseReset(h http.Header) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0).UTC(), true
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// jitter returns a random duration between half of d and d.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,282 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// response is a canned response of the fake GitHub server.
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// fakeGitHub is an httptest.Server stand-in for GitHub, which returns the
+// given responses in order and records the bodies of the requests it served.
+type fakeGitHub struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []response
+	bodies    []string
+}
+
+func newFakeGitHub(t *testing.T, responses ...response) *fakeGitHub {
+	t.Helper()
+
+	f := &fakeGitHub{responses: responses}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		f.bodies = append(f.bodies, string(body))
+
+		if len(f.responses) == 0 {
+			t.Errorf("unexpected request %d to %s", len(f.bodies), r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := f.responses[0]
+		f.responses = f.responses[1:]
+
+		for k, v := range resp.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+		io.WriteString(w, resp.body)
+	}))
+
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func TestTransport(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+
+	ok := response{
+		status:  http.StatusOK,
+		headers: map[string]string{"X-RateLimit-Remaining": "4999", "X-RateLimit-Limit": "5000"},
+		body:    `{"data": {}}`,
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		responses  []response
+		maxRetries int
+		wantStatus int
+		wantBody   string
+		wantWaits  []time.Duration
+		wantLog    string
+	}{
+		{
+			name:       "ok",
+			path:       "/graphql",
+			responses:  []response{ok},
+			wantStatus: http.StatusOK,
+			wantBody:   ok.body,
+			wantLog:    "msg=\"ratelimit: quota\"",
+		},
+		{
+			name: "primary__rate_limit",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{
+					status:  http.StatusForbidden,
+					headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+					body:    `{"message": "API rate limit exceeded"}`,
+				},
+				ok,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   ok.body,
+			wantWaits:  []time.Duration{31 * time.Second},
+			wantLog:    "msg=\"ratelimit: rate limited, waiting before retrying request\"",
+		},
+		{
+			name: "retry_after",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "3"}},
+				ok,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   ok.body,
+			wantWaits:  []time.Duration{3 * time.Second},
+		},
+		{
+			name: "secondary__rate_limit__backoff",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`},
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`},
+				ok,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   ok.body,
+			wantWaits:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name: "graphql__rate_limited",
+			path: "/graphql",
+			responses: []response{
+				{
+					status:  http.StatusOK,
+					headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+					body:    `{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`,
+				},
+				ok,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   ok.body,
+			wantWaits:  []time.Duration{31 * time.Second},
+		},
+		{
+			name: "graphql__other__error",
+			path: "/graphql",
+			responses: []response{
+				{status: http.StatusOK, body: `{"errors": [{"type": "NOT_FOUND"}]}`},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"errors": [{"type": "NOT_FOUND"}]}`,
+		},
+		{
+			name: "forbidden",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message": "Resource not accessible by integration"}`},
+			},
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"message": "Resource not accessible by integration"}`,
+		},
+		{
+			name: "max_retries",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{status: http.StatusTooManyRequests, body: "1"},
+				{status: http.StatusTooManyRequests, body: "2"},
+				{status: http.StatusTooManyRequests, body: "3"},
+			},
+			maxRetries: 2,
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   "3",
+			wantWaits:  []time.Duration{time.Second, 2 * time.Second},
+			wantLog:    "msg=\"ratelimit: giving up on rate limited request\"",
+		},
+		{
+			name: "max_wait",
+			path: "/repos/hackebrot/turtle/compare/a...b",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "3600"}, body: "later"},
+			},
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   "later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeGitHub(t, tt.responses...)
+
+			logbuf := new(bytes.Buffer)
+			logger := slog.New(slog.NewTextHandler(logbuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			var waits []time.Duration
+
+			transport := NewTransport(nil, logger)
+			transport.now = func() time.Time { return now }
+			transport.jitter = func(d time.Duration) time.Duration { return d }
+			transport.sleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			if tt.maxRetries > 0 {
+				transport.MaxRetries = tt.maxRetries
+			}
+
+			client := &http.Client{Transport: transport}
+
+			reqBody := `{"query": "query { viewer { login } }"}`
+			resp, err := client.Post(server.URL+tt.path, "application/json", strings.NewReader(reqBody))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("error reading response body: %v", err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+
+			if diff := cmp.Diff(tt.wantWaits, waits); diff != "" {
+				t.Errorf("waits mismatch (-want +got):\n%s", diff)
+			}
+
+			// Every attempt must send the full request body.
+			for i, b := range server.bodies {
+				if b != reqBody {
+					t.Errorf("request %d body = %q, want %q", i+1, b, reqBody)
+				}
+			}
+
+			if tt.wantLog != "" && !strings.Contains(logbuf.String(), tt.wantLog) {
+				t.Errorf("log does not contain %q:\n%s", tt.wantLog, logbuf.String())
+			}
+		})
+	}
+}
+
+func TestTransportContextCanceled(t *testing.T) {
+	server := newFakeGitHub(t, response{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transport := NewTransport(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleep(ctx, d)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos/hackebrot/turtle", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = (&http.Client{Transport: transport}).Do(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if got := jitter(10 * time.Second); got < 5*time.Second || got > 10*time.Second {
+			t.Fatalf("jitter(10s) = %s, want between 5s and 10s", got)
+		}
+	}
+}
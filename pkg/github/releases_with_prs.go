@@ -1,21 +1,25 @@
 package github
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"regexp"
 	"strconv"
+	"time"
 )
 
-// ReleaseWithPRs embeds a GitHub Release object with an added slice of PR
-// numbers, which we have parsed from its auto-generated release notes.
+// ReleaseWithPRs embeds a GitHub Release object with an added slice of the
+// PRs which were shipped in the release.
 type ReleaseWithPRs struct {
 	*Release
-	PRs []int
+	PRs []PullRequest `csv:"prs,key=Number"`
 }
 
 // NewReleaseWithPrs creates a new ReleaseWithPRs by parsing PR numbers from
-// auto-generated Release Descriptions.
+// auto-generated Release Descriptions. Only the Number of each PR is set.
 func NewReleaseWithPRs(r *Release) *ReleaseWithPRs {
-	var prs []int
+	var prs []PullRequest
 
 	// Pattern for auto-generated release notes. For more information see:
 	// https://docs.github.com/en/repositories/releasing-projects-on-github/automatically-generated-release-notes
@@ -28,10 +32,105 @@ func NewReleaseWithPRs(r *Release) *ReleaseWithPRs {
 				if err != nil {
 					continue
 				}
-				prs = append(prs, n)
+				prs = append(prs, PullRequest{Number: n})
 			}
 		}
 	}
 
 	return &ReleaseWithPRs{r, prs}
 }
+
+// Options for the QueryReleasesWithPRs function
+type ReleasesWithPRsOptions struct {
+	Limit  int
+	Window *TimeWindow
+
+	// Maximum number of commits to look up PRs for per release
+	CommitLimit int
+}
+
+// QueryReleasesWithPRs fetches releases and resolves the PRs shipped in each
+// release from the commits between its tag and the tag of the previous
+// release, which may be before the time window. It falls back to parsing PR numbers from the release notes for the
+// oldest release, draft releases and releases without associated PRs.
+func QueryReleasesWithPRs(
+	ctx context.Context,
+	repo *Repo,
+	r ReleasesService, p AssociatedPullRequestsService,
+	logger *slog.Logger,
+	opts *ReleasesWithPRsOptions,
+) ([]ReleaseWithPRs, error) {
+	// Fetch one more release than requested to compare the oldest one with.
+	releases, err := r.QueryReleases(ctx, repo, opts.Limit+1, opts.Window)
+	if err != nil {
+		return nil, fmt.Errorf("error querying releases: %w", err)
+	}
+
+	count := min(len(releases), opts.Limit)
+
+	// The release before the oldest one may be before the start of the window,
+	// so query it without the start.
+	if n := len(releases); n > 0 && n <= opts.Limit && opts.Window != nil && !opts.Window.Since.IsZero() {
+		// Both ends of the window are inclusive, so end the window just before
+		// the oldest release.
+		before := &TimeWindow{Until: releases[n-1].CreatedAt.Add(-time.Nanosecond)}
+
+		previous, err := r.QueryReleases(ctx, repo, 1, before)
+		if err != nil {
+			return nil, fmt.Errorf("error querying release before %s: %w", releases[n-1].TagName, err)
+		}
+
+		releases = append(releases, previous...)
+	}
+
+	var releasesWithPRs []ReleaseWithPRs
+
+	for i := 0; i < count; i++ {
+		release := releases[i] // Create a copy to avoid referencing the same loop variable memory.
+
+		previous := previousRelease(releases, i)
+
+		if previous != nil && !release.IsDraft {
+			base, head := "refs/tags/"+previous.TagName, "refs/tags/"+release.TagName
+
+			prs, err := p.QueryAssociatedPullRequests(ctx, repo, base, head, opts.CommitLimit)
+			if err != nil {
+				return nil, fmt.Errorf("error querying PRs for release %s: %w", release.TagName, err)
+			}
+
+			logger.Debug(
+				"github.QueryReleasesWithPRs: found PRs for commits between releases",
+				slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+				slog.String("base", previous.TagName),
+				slog.String("head", release.TagName),
+				slog.Int("count", len(prs)),
+			)
+
+			if len(prs) > 0 {
+				releasesWithPRs = append(releasesWithPRs, ReleaseWithPRs{&release, prs})
+				continue
+			}
+		}
+
+		logger.Debug(
+			"github.QueryReleasesWithPRs: parsing PRs from release notes",
+			slog.String("repo", fmt.Sprintf("%s/%s", repo.Owner, repo.Name)),
+			slog.String("release", release.TagName),
+		)
+
+		releasesWithPRs = append(releasesWithPRs, *NewReleaseWithPRs(&release))
+	}
+
+	return releasesWithPRs, nil
+}
+
+// previousRelease returns the next older release which is not a draft, or nil
+// if there is none. Releases are expected in descending order.
+func previousRelease(releases []Release, i int) *Release {
+	for j := i + 1; j < len(releases); j++ {
+		if !releases[j].IsDraft {
+			return &releases[j]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package github_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mozilla-services/rapid-release-model/pkg/github"
+)
+
+// fakeReleasesService returns the releases within the window, up to the
+// limit. Releases must be sorted by time in descending order.
+type fakeReleasesService struct {
+	releases []github.Release
+}
+
+func (f *fakeReleasesService) QueryReleases(ctx context.Context, repo *github.Repo, limit int, window *github.TimeWindow) ([]github.Release, error) {
+	var releases []github.Release
+	for _, r := range f.releases {
+		if len(releases) == limit {
+			break
+		}
+		if window.Contains(r.CreatedAt) {
+			releases = append(releases, r)
+		}
+	}
+	return releases, nil
+}
+
+// fakeAssociatedPullRequestsService returns PRs by "base...head".
+type fakeAssociatedPullRequestsService struct {
+	prs      map[string][]github.PullRequest
+	err      error
+	compared []string
+}
+
+func (f *fakeAssociatedPullRequestsService) QueryAssociatedPullRequests(ctx context.Context, repo *github.Repo, base string, head string, limit int) ([]github.PullRequest, error) {
+	key := base + "..." + head
+	f.compared = append(f.compared, key)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.prs[key], nil
+}
+
+func TestQueryReleasesWithPRs(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	releases := &fakeReleasesService{releases: []github.Release{
+		{TagName: "v4", Description: "Hand-written notes"},
+		{TagName: "v3-draft", IsDraft: true, Description: "* Draft by @hackebrot in https://github.com/hackebrot/turtle/pull/9"},
+		{TagName: "v2", Description: "* Squash by @hackebrot in https://github.com/hackebrot/turtle/pull/5"},
+		{TagName: "v1", Description: "* Init by @hackebrot in https://github.com/hackebrot/turtle/pull/1"},
+	}}
+
+	prs := &fakeAssociatedPullRequestsService{prs: map[string][]github.PullRequest{
+		"refs/tags/v2...refs/tags/v4": {{Number: 7, Title: "Add shell"}, {Number: 8, Title: "Feed turtle"}},
+	}}
+
+	got, err := github.QueryReleasesWithPRs(ctx, repo, releases, prs, logger, &github.ReleasesWithPRsOptions{Limit: 3, CommitLimit: 100})
+	if err != nil {
+		t.Fatalf("QueryReleasesWithPRs() returned unexpected error: %v", err)
+	}
+
+	numbers := make(map[string][]int)
+	for _, r := range got {
+		for _, pr := range r.PRs {
+			numbers[r.TagName] = append(numbers[r.TagName], pr.Number)
+		}
+	}
+
+	want := map[string][]int{
+		"v4":       {7, 8}, // Resolved via API, skipping the draft release
+		"v3-draft": {9},    // Drafts have no tag to compare
+		"v2":       {5},    // No associated PRs, parsed from release notes
+	}
+
+	if diff := cmp.Diff(want, numbers); diff != "" {
+		t.Errorf("QueryReleasesWithPRs() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got[0].PRs[0].Title != "Add shell" {
+		t.Errorf("QueryReleasesWithPRs() did not return full PRs, got %+v", got[0].PRs[0])
+	}
+
+	wantCompared := []string{"refs/tags/v2...refs/tags/v4", "refs/tags/v1...refs/tags/v2"}
+	if diff := cmp.Diff(wantCompared, prs.compared); diff != "" {
+		t.Errorf("QueryReleasesWithPRs() comparisons mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryReleasesWithPRsWindow(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	releases := &fakeReleasesService{releases: []github.Release{
+		{TagName: "v3", CreatedAt: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{TagName: "v2", CreatedAt: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{TagName: "v1", CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+	prs := &fakeAssociatedPullRequestsService{}
+
+	window := &github.TimeWindow{Since: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)}
+
+	got, err := github.QueryReleasesWithPRs(ctx, repo, releases, prs, logger, &github.ReleasesWithPRsOptions{Limit: 5, Window: window, CommitLimit: 100})
+	if err != nil {
+		t.Fatalf("QueryReleasesWithPRs() returned unexpected error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("QueryReleasesWithPRs() returned %d releases, want 2", len(got))
+	}
+
+	// The oldest release in the window is compared to the release before the
+	// window, which is not returned.
+	wantCompared := []string{"refs/tags/v2...refs/tags/v3", "refs/tags/v1...refs/tags/v2"}
+	if diff := cmp.Diff(wantCompared, prs.compared); diff != "" {
+		t.Errorf("QueryReleasesWithPRs() comparisons mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryReleasesWithPRsError(t *testing.T) {
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))
+	repo := &github.Repo{Owner: "hackebrot", Name: "turtle"}
+
+	releases := &fakeReleasesService{releases: []github.Release{{TagName: "v2"}, {TagName: "v1"}}}
+	prs := &fakeAssociatedPullRequestsService{err: errors.New("boom")}
+
+	_, err := github.QueryReleasesWithPRs(ctx, repo, releases, prs, logger, &github.ReleasesWithPRsOptions{Limit: 1, CommitLimit: 100})
+	if err == nil || !strings.Contains(err.Error(), "error querying PRs for release v2: boom") {
+		t.Fatalf("QueryReleasesWithPRs() error = %v, want error for release v2", err)
+	}
+}
@@ -0,0 +1,29 @@
+package github
+
+import "time"
+
+// TimeWindow limits query results to the time from Since to Until (both
+// inclusive). A zero Since or Until leaves that end of the window unbounded.
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Contains reports whether t is within the window. A nil window contains any
+// time.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	return !w.IsBefore(t) && (w.Until.IsZero() || !t.After(w.Until))
+}
+
+// IsBefore reports whether t is before the start of the window. Services use
+// this to stop paginating results, which are ordered by time in descending
+// order, as soon as they fall outside of the window.
+func (w *TimeWindow) IsBefore(t time.Time) bool {
+	if w == nil || w.Since.IsZero() {
+		return false
+	}
+	return t.Before(w.Since)
+}